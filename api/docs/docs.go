@@ -14,7 +14,2358 @@ const docTemplate = `{
     },
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
-    "paths": {}
+    "paths": {
+        "/api-keys": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get every API key, including revoked ones, oldest first. Keys are shown by their prefix only.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List API keys",
+                "responses": {
+                    "200": {
+                        "description": "API keys retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/subscription-aggregator_internal_models.APIKey"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Caller is not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not get API keys",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Issues a key for service-to-service access with the given scopes: subscriptions:read, subscriptions:write, reports:read or admin, which grants everything. The key is only returned in this response; only a hash of it is stored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Issue an API key",
+                "parameters": [
+                    {
+                        "description": "Key name and scopes",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.APIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "API key issued successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.IssuedAPIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or data",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller is not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not issue API key",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes a key so that it no longer authenticates. Revoking a revoked key does nothing.",
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "API key revoked successfully"
+                    },
+                    "400": {
+                        "description": "Invalid API key ID",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller is not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not revoke API key",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api-keys/{id}/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the secret of an active key, keeping its ID, name and scopes. The old secret stops working at once; the new one is only returned in this response.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Rotate an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "API key rotated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.IssuedAPIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid API key ID",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Caller is not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found or revoked",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not rotate API key",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/fx-rates": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get every loaded exchange rate.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List exchange rates",
+                "responses": {
+                    "200": {
+                        "description": "Rates retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/subscription-aggregator_internal_models.FXRate"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Could not get rates",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates or replaces exchange rates. A rate is the price of one unit of base_currency in quote_currency and is valid from its month until a newer rate is loaded. Months must be in \"MM-YYYY\" format.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Load exchange rates",
+                "parameters": [
+                    {
+                        "description": "Exchange rates",
+                        "name": "rates",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/subscription-aggregator_internal_models.FXRateRequest"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rates saved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/subscription-aggregator_internal_models.FXRate"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or data",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save rates",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/monthly": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns one row per calendar month between from and to (both inclusive) with the cost of every service charged in that month. Dates must be in \"MM-YYYY\" format.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Monthly cost breakdown",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "First month of the report (MM-YYYY)",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last month of the report (MM-YYYY)",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 4217 currency to convert every charge to",
+                        "name": "target_currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Report built successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.MonthlyReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not build report",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/services": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get every catalog service, ordered by name.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List services",
+                "responses": {
+                    "200": {
+                        "description": "Services retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/subscription-aggregator_internal_models.Service"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Could not get services",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Adds a service to the catalog. Subscriptions created or updated later whose service_name matches the name or an alias, ignoring case and extra spaces, are linked to it and stored under its name.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create a service",
+                "parameters": [
+                    {
+                        "description": "Service data",
+                        "name": "service",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.ServiceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Service created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Service"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the new service"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or data",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Name or alias is already used by another service",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save service",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/services/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a catalog service with its aliases.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get a service by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Service ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Service found successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Service"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid service ID",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Service not found",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not get service",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces a catalog service and its aliases. Subscriptions already linked to it keep the name they were stored under until they are updated.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update a service",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Service ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated service data",
+                        "name": "service",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.ServiceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Service updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Service"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or data",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Service not found",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Name or alias is already used by another service",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not update service",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes a catalog service. Its subscriptions keep their service name and lose the link to the service.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete a service",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Service ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid service ID",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Service not found",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not delete service",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a page of subscription records for a specific user, or for every user when all_users is set. Dates must be in \"MM-YYYY\" format.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List subscriptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID, required unless all_users is set",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "List subscriptions of every user",
+                        "name": "all_users",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also list soft-deleted subscriptions; admins only",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only subscriptions active in this month (MM-YYYY)",
+                        "name": "active_at",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum price",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum price",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest start date (MM-YYYY)",
+                        "name": "start_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest start date (MM-YYYY)",
+                        "name": "start_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest end date (MM-YYYY)",
+                        "name": "end_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest end date (MM-YYYY)",
+                        "name": "end_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field: start_date, price or service_name, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (1-500, default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Subscriptions retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.SubscriptionPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not get list subscriptions",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new user subscription. Dates must be in \"MM-YYYY\" format.\nWith an Idempotency-Key header a retry of the same request within the key's TTL returns the original response instead of creating another subscription.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create a new subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client-generated key, at most 255 characters",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Subscription data",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.SubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Subscription created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Subscription"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the new subscription"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or data",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Subscription already exists or a request with the same Idempotency-Key is in progress",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key was used with a different request",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save subscription",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Streams the subscriptions of a user, or of every user when all_users is set, as CSV in the format accepted by the import. Takes the filters and sort order of the listing endpoint. A service_name or category starting with =, +, - or @ is prefixed with an apostrophe so spreadsheets do not run it as a formula.",
+                "produces": [
+                    "text/csv"
+                ],
+                "summary": "Export subscriptions to CSV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID, required unless all_users is set",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Export subscriptions of every user",
+                        "name": "all_users",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also export soft-deleted subscriptions; admins only",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field: start_date, price or service_name, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format, only csv is supported",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not export subscriptions",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/import": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a subscription for every row of a CSV file with a header row. The columns service_name, price, user_id and start_date are required; end_date, billing_period, billing_interval, currency and category are optional and other columns, such as the id of an export, are ignored. Dates must be in \"MM-YYYY\" format.\nThe import is all or nothing: with any invalid row nothing is created. With dry_run the rows are only validated and the report lists the invalid ones.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Import subscriptions from CSV",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file, at most 10000 rows",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only validate the rows",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rows imported or validated",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.ImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid file or rows",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not import subscriptions",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/total-cost": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Calculates the total cost of a user's subscriptions for a given period, optionally filtered by one or more service names. The period dates must be in \"MM-YYYY\" format.\nWithout target_currency and group_by the prices are summed as stored and a bare number is returned; that fails with 400 when the charges are in more than one currency. With target_currency every month's charges are converted at that month's rate and the rates used are reported. With group_by the response also lists the total of every group.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Calculate total subscription cost",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Service name or catalog alias, case-insensitive; may be repeated",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date of the period (MM-YYYY)",
+                        "name": "period_start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the period (MM-YYYY), exclusive",
+                        "name": "period_end",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 4217 currency to convert the total to",
+                        "name": "target_currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Group totals by service, month, year or category",
+                        "name": "group_by",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Total cost calculated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.TotalCost"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not calculate total cost",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a user's subscription record by its unique ID.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get a subscription by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also return a soft-deleted subscription; admins only",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached copy",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Subscription found successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Subscription"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the subscription"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Subscription has not changed"
+                    },
+                    "400": {
+                        "description": "Invalid subscription ID",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not get subscription",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update an existing subscription record by its unique ID. With If-Match the update is only applied when the subscription still has that ETag.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update an existing subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag returned by the last read",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Updated subscription data",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.SubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Subscription updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Subscription"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the subscription"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Subscription was modified by someone else",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not update subscription",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Soft-deletes a user's subscription record by its unique ID. The subscription can be restored until the retention window passes and it is purged.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid subscription ID",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not delete subscription",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Changes only the fields present in an RFC 7396 merge patch; null removes a field, so \"end_date\": null makes the subscription open-ended; null for service_name, price, user_id or start_date is rejected. The merged subscription is validated like a full update. With If-Match the patch is only applied when the subscription still has that ETag.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Patch a subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag returned by the last read",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Merge patch with the fields to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.SubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Subscription patched successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Subscription"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the subscription"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid patch or merged data",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Subscription was modified by someone else",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Patch is not JSON",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not patch subscription",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get every recorded change of a subscription, oldest first, including changes made before it was deleted.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get subscription history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "History retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/subscription-aggregator_internal_models.SubscriptionEvent"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid subscription ID",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription has no history",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not get subscription history",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Restores a soft-deleted subscription that has not been purged yet.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Restore a deleted subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid subscription ID",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Subscription is not deleted",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not restore subscription",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/subscriptions:batch": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Runs up to 1000 create, update and delete operations in order and reports the outcome of each with the status code the single-item endpoint would have answered.\nIn atomic mode (the default) a failing operation rolls back the whole batch and the other operations report 424. In best_effort mode every operation is applied or fails on its own.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Apply a batch of subscription changes",
+                "parameters": [
+                    {
+                        "description": "Operations to apply",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.BatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Outcome of every operation",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not apply batch",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get every user, oldest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List users",
+                "responses": {
+                    "200": {
+                        "description": "Users retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/subscription-aggregator_internal_models.User"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Could not get users",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a user that subscriptions can belong to. The ID is generated unless the request carries one, for users known to another system.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create a user",
+                "parameters": [
+                    {
+                        "description": "User data",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.UserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.User"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the new user"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or data",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "ID or email is already used by another user",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not save user",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a user by its unique ID.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get a user by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User found successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not get user",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the profile of a user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated user data",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.UserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or data",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Email is already used by another user",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not update user",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Permanently deletes a user together with all of their subscriptions, including soft-deleted ones. The audit history of the subscriptions is kept and gets a deleted event for every subscription that was not deleted yet.",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not delete user",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{user_id}/renewals.ics": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns an iCalendar (RFC 5545) feed with an all-day event for every charge of the user's subscriptions from today until the horizon. Event UIDs are derived from the subscription ID and the charge date, so calendar apps update events in place when the feed is refreshed.",
+                "produces": [
+                    "text/calendar"
+                ],
+                "summary": "Renewal calendar",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Horizon in months (1-60, default 12)",
+                        "name": "months",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Add a reminder this many days before each charge (0-30)",
+                        "name": "reminder_days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "iCalendar feed",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found, or the path does not end in .ics",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not build calendar",
+                        "schema": {
+                            "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "subscription-aggregator_internal_models.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "rotated_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.APIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.BatchOperationRequest": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ]
+                },
+                "subscription": {
+                    "$ref": "#/definitions/subscription-aggregator_internal_models.SubscriptionRequest"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.BatchRequest": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "description": "Mode is \"atomic\" (the default), where either every operation is applied\nor none is, or \"best_effort\", where each operation stands on its own.",
+                    "type": "string",
+                    "enum": [
+                        "atomic",
+                        "best_effort"
+                    ]
+                },
+                "operations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscription-aggregator_internal_models.BatchOperationRequest"
+                    }
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscription-aggregator_internal_models.BatchResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.BatchResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/subscription-aggregator_internal_models.Problem"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "op": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "subscription": {
+                    "$ref": "#/definitions/subscription-aggregator_internal_models.Subscription"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.CostGroup": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "month": {
+                    "type": "string"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "total_cost": {
+                    "type": "integer"
+                },
+                "year": {
+                    "type": "integer"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.CurrencyTotal": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "currency": {
+                    "type": "string"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.FXRate": {
+            "type": "object",
+            "properties": {
+                "base_currency": {
+                    "type": "string"
+                },
+                "month": {
+                    "type": "string"
+                },
+                "quote_currency": {
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "number"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.FXRateRequest": {
+            "type": "object",
+            "properties": {
+                "base_currency": {
+                    "type": "string"
+                },
+                "month": {
+                    "type": "string"
+                },
+                "quote_currency": {
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "number"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.ImportReport": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscription-aggregator_internal_models.ImportRowError"
+                    }
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "integer"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.ImportRowError": {
+            "type": "object",
+            "properties": {
+                "invalid_params": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscription-aggregator_internal_models.InvalidParam"
+                    }
+                },
+                "line": {
+                    "type": "integer"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.InvalidParam": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.IssuedAPIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "rotated_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.MonthlyReport": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "months": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscription-aggregator_internal_models.ReportMonth"
+                    }
+                },
+                "rates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscription-aggregator_internal_models.FXRate"
+                    }
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string"
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "invalid_params": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscription-aggregator_internal_models.InvalidParam"
+                    }
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.ReportMonth": {
+            "type": "object",
+            "properties": {
+                "month": {
+                    "type": "string"
+                },
+                "services": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscription-aggregator_internal_models.ServiceCost"
+                    }
+                },
+                "totals": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscription-aggregator_internal_models.CurrencyTotal"
+                    }
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.Service": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "category": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "default_price": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "website": {
+                    "type": "string"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.ServiceCost": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "service_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.ServiceRequest": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "category": {
+                    "type": "string"
+                },
+                "default_price": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "website": {
+                    "type": "string"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.Subscription": {
+            "type": "object",
+            "properties": {
+                "billing_interval": {
+                    "type": "integer"
+                },
+                "billing_period": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "service_id": {
+                    "type": "string"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.SubscriptionEvent": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "after": {
+                    "type": "object"
+                },
+                "before": {
+                    "type": "object"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.SubscriptionPage": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscription-aggregator_internal_models.Subscription"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.SubscriptionRequest": {
+            "type": "object",
+            "properties": {
+                "billing_interval": {
+                    "type": "integer"
+                },
+                "billing_period": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.TotalCost": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscription-aggregator_internal_models.CostGroup"
+                    }
+                },
+                "rates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/subscription-aggregator_internal_models.FXRate"
+                    }
+                },
+                "total_cost": {
+                    "type": "integer"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.User": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "default_currency": {
+                    "type": "string"
+                },
+                "display_name": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "subscription-aggregator_internal_models.UserRequest": {
+            "type": "object",
+            "properties": {
+                "default_currency": {
+                    "type": "string"
+                },
+                "display_name": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID is optional on create, for users whose ID comes from another system.",
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "JWT as \"Bearer \u003ctoken\u003e\" or API key as \"ApiKey \u003ckey\u003e\"; required when AUTH_JWKS_FILE or AUTH_API_KEYS is set.",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
+    }
 }`
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"subscription-aggregator/internal/auth"
+	"subscription-aggregator/internal/config"
+	"subscription-aggregator/internal/db/postgres"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/utils"
+)
+
+const apiKeysUsage = "usage: subscription-aggregator api-keys issue [-tenant <id>] <name> <scope>..."
+
+// runAPIKeys handles the "api-keys" subcommand, which issues the first admin
+// key before anyone can call the API. The key is printed to stdout.
+func runAPIKeys(ctx context.Context, args []string, cfg *config.Config, log *slog.Logger) error {
+	if len(args) == 0 || args[0] != "issue" {
+		return errors.New(apiKeysUsage)
+	}
+
+	ctx, args, err := tenantArgs(ctx, "api-keys issue", args[1:])
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 2 {
+		return errors.New(apiKeysUsage)
+	}
+
+	key, err := utils.MapAPIKeyRequest(models.APIKeyRequest{Name: args[0], Scopes: args[1:]})
+	if err != nil {
+		return err
+	}
+
+	secret, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return err
+	}
+
+	key.Prefix = prefix
+
+	storage, err := postgres.New(ctx, cfg, log)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	if err := storage.CreateAPIKey(ctx, key, hash); err != nil {
+		return err
+	}
+
+	fmt.Println(secret)
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"subscription-aggregator/internal/config"
+	"subscription-aggregator/internal/db/postgres"
+	"subscription-aggregator/internal/fx"
+)
+
+const fxRatesUsage = "usage: subscription-aggregator fx-rates import [-tenant <id>] <file.csv>"
+
+// runFXRates handles the "fx-rates" subcommand.
+func runFXRates(ctx context.Context, args []string, cfg *config.Config, log *slog.Logger) error {
+	if len(args) == 0 || args[0] != "import" {
+		return errors.New(fxRatesUsage)
+	}
+
+	ctx, args, err := tenantArgs(ctx, "fx-rates import", args[1:])
+	if err != nil {
+		return err
+	}
+
+	if len(args) != 1 {
+		return errors.New(fxRatesUsage)
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rates, err := fx.ParseCSV(file)
+	if err != nil {
+		return err
+	}
+
+	storage, err := postgres.New(ctx, cfg, log)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	return storage.SaveRates(ctx, rates)
+}
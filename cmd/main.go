@@ -2,71 +2,186 @@ package main
 
 import (
 	"context"
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	httpSwagger "github.com/swaggo/http-swagger"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	_ "subscription-aggregator/api/docs"
+	"subscription-aggregator/internal/auth"
 	"subscription-aggregator/internal/config"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/db/memory"
 	"subscription-aggregator/internal/db/postgres"
 	"subscription-aggregator/internal/handlers"
 	"subscription-aggregator/internal/logger"
+	"subscription-aggregator/internal/metrics"
+	"subscription-aggregator/internal/purger"
+	"subscription-aggregator/internal/tracing"
 )
 
 // @title Subscription Aggregator API
 // @version 1.0
 // @description REST service for aggregating data about users' online subscriptions
 // @host localhost:8080
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT as "Bearer <token>" or API key as "ApiKey <key>"; required when AUTH_JWKS_FILE or AUTH_API_KEYS is set.
 func main() {
 	log := logger.NewLogger()
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Error("Error loading config: ", err)
+		log.Error("Error loading config", "error", err)
 		os.Exit(1)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	storage, err := postgres.New(ctx, cfg, log)
+	if len(os.Args) > 1 && os.Args[1] == "fx-rates" {
+		if err := runFXRates(ctx, os.Args[2:], cfg, log); err != nil {
+			log.Error("Error importing fx rates", "error", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "api-keys" {
+		if err := runAPIKeys(ctx, os.Args[2:], cfg, log); err != nil {
+			log.Error("Error managing api keys", "error", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], cfg, log); err != nil {
+			log.Error("Error running migrations", "error", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg, log)
 	if err != nil {
-		log.Error("Error creating storage: ", err)
+		log.Error("Error setting up tracing", "error", err)
 		os.Exit(1)
 	}
 
-	defer storage.Close(ctx)
+	defer shutdownTracing(context.Background())
+
+	appMetrics := metrics.New(log)
+
+	storage, closeStorage, err := newStorage(ctx, cfg, appMetrics, log)
+	if err != nil {
+		log.Error("Error creating storage", "error", err)
+		os.Exit(1)
+	}
 
-	subscriptionHandler := handlers.NewSubscriptionsHandler(storage, log)
+	defer closeStorage()
 
-	router := chi.NewRouter()
+	appMetrics.MustRegister(metrics.NewRevenueCollector(storage))
 
-	router.Use(middleware.RequestID)
-	router.Use(middleware.Logger)
-	router.Use(middleware.Recoverer)
-	router.Use(middleware.URLFormat)
+	if cfg.PurgeRetention > 0 || cfg.IdempotencyTTL > 0 {
+		go purger.New(storage, cfg.PurgeRetention, cfg.IdempotencyTTL, cfg.PurgeInterval, log).Run(ctx)
+	}
 
-	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("Service start"))
-	})
+	authenticate, err := newAuthenticator(cfg, storage, log)
+	if err != nil {
+		log.Error("Error loading auth keys", "error", err)
+		os.Exit(1)
+	}
 
-	router.Get("/swagger/*", httpSwagger.Handler(
-		httpSwagger.URL("http://localhost:8080/swagger/doc.json"),
-	))
+	router := newRouter(storage, cfg.IdempotencyTTL, appMetrics, authenticate, log)
 
-	router.Route("/subscriptions", func(r chi.Router) {
-		r.Post("/", subscriptionHandler.CreateSubscription)
-		r.Delete("/{id}", subscriptionHandler.DeleteSubscription)
-		r.Get("/{id}", subscriptionHandler.GetSubscriptionByID)
-		r.Get("/", subscriptionHandler.ListSubscriptionsByUserID)
-		r.Put("/{id}", subscriptionHandler.UpdateSubscription)
-		r.Get("/total-cost", subscriptionHandler.SumTotalCostSubscriptions)
-	})
+	if cfg.MetricsAddr != "" {
+		go serveMetrics(cfg.MetricsAddr, appMetrics, log)
+	}
 
 	log.Info("Service start on port :8080")
 	if err := http.ListenAndServe(":8080", router); err != nil {
-		log.Error("Error starting server: ", err)
+		log.Error("Error starting server", "error", err)
+		os.Exit(1)
+	}
+}
+
+// serveMetrics serves /metrics on a listener of its own, outside the API and
+// its authentication.
+func serveMetrics(addr string, m *metrics.Metrics, log *slog.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+
+	log.Info("Metrics served on " + addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Error("Error starting metrics server", "error", err)
 		os.Exit(1)
 	}
 }
+
+// newStorage opens the configured backend. A postgres backend also reports
+// its connection pool and the timing of every storage method.
+func newStorage(ctx context.Context, cfg *config.Config, m *metrics.Metrics, log *slog.Logger) (db.Storage, func(), error) {
+	switch cfg.StorageType {
+	case config.StorageMemory:
+		return memory.New(log), func() {}, nil
+	case config.StoragePostgres:
+		if cfg.MigrateOnStartup {
+			if err := migrateUp(cfg, log); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		storage, err := postgres.New(ctx, cfg, log)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		m.MustRegister(metrics.NewPoolCollector(storage.Stat))
+
+		return metrics.InstrumentStorage(storage, m), storage.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage type %q", cfg.StorageType)
+	}
+}
+
+// newAuthenticator returns the middleware that checks bearer tokens and API
+// keys, or one that lets every request through when neither is configured.
+func newAuthenticator(cfg *config.Config, apiKeys db.APIKeyStorage, log *slog.Logger) (func(http.Handler) http.Handler, error) {
+	if cfg.AuthJWKSFile == "" && !cfg.AuthAPIKeys {
+		log.Warn("Authentication is disabled, set AUTH_JWKS_FILE or AUTH_API_KEYS to enable it")
+		return func(next http.Handler) http.Handler { return next }, nil
+	}
+
+	var verifier *auth.Verifier
+	if cfg.AuthJWKSFile != "" {
+		keys, err := auth.LoadKeySet(cfg.AuthJWKSFile)
+		if err != nil {
+			return nil, err
+		}
+
+		verifier = auth.NewVerifier(keys, cfg.AuthIssuer, cfg.AuthAudience, cfg.AuthLeeway)
+		log.Info("Bearer token authentication enabled", "keys", len(keys))
+	}
+
+	if !cfg.AuthAPIKeys {
+		apiKeys = nil
+	} else {
+		log.Info("API key authentication enabled")
+	}
+
+	return handlers.Authenticate(verifier, apiKeys, log), nil
+}
+
+func migrateUp(cfg *config.Config, log *slog.Logger) error {
+	migrator, err := postgres.NewMigrator(cfg, log)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	return migrator.Up()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"subscription-aggregator/internal/config"
+	"subscription-aggregator/internal/db/postgres"
+)
+
+const migrateUsage = "usage: subscription-aggregator migrate up|down [N]|status|version"
+
+// runMigrate handles the "migrate" subcommand.
+func runMigrate(args []string, cfg *config.Config, log *slog.Logger) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	migrator, err := postgres.NewMigrator(cfg, log)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	switch args[0] {
+	case "up":
+		return migrator.Up()
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+
+		return migrator.Down(steps)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied"
+			}
+
+			fmt.Printf("%06d %-8s %s\n", status.Version, state, status.Name)
+		}
+
+		return nil
+	case "version":
+		version, dirty, ok, err := migrator.Version()
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			fmt.Println("no migrations applied")
+			return nil
+		}
+
+		if dirty {
+			fmt.Printf("%d (dirty)\n", version)
+			return nil
+		}
+
+		fmt.Println(version)
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	httpSwagger "github.com/swaggo/http-swagger"
+	"log/slog"
+	"net/http"
+	"subscription-aggregator/internal/audit"
+	"subscription-aggregator/internal/auth"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/handlers"
+	"subscription-aggregator/internal/metrics"
+	"subscription-aggregator/internal/tracing"
+	"time"
+)
+
+// newRouter wires the handlers of the API to storage. authenticate guards
+// every route but / and /swagger.
+func newRouter(storage db.Storage, idempotencyTTL time.Duration, appMetrics *metrics.Metrics, authenticate func(http.Handler) http.Handler, log *slog.Logger) http.Handler {
+	subscriptionHandler := handlers.NewSubscriptionsHandler(storage, storage, storage, storage, idempotencyTTL, log)
+	fxRatesHandler := handlers.NewFXRatesHandler(storage, log)
+	reportsHandler := handlers.NewReportsHandler(storage, storage, log)
+	renewalsHandler := handlers.NewRenewalsHandler(storage, log)
+	servicesHandler := handlers.NewServicesHandler(storage, log)
+	usersHandler := handlers.NewUsersHandler(storage, log)
+	apiKeysHandler := handlers.NewAPIKeysHandler(storage, log)
+
+	router := chi.NewRouter()
+
+	router.Use(middleware.RequestID)
+	router.Use(tracing.Middleware)
+	router.Use(appMetrics.Middleware)
+	router.Use(audit.Middleware)
+	router.Use(middleware.Logger)
+	router.Use(middleware.Recoverer)
+	router.Use(middleware.URLFormat)
+
+	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Service start"))
+	})
+
+	router.Get("/swagger/*", httpSwagger.Handler(
+		httpSwagger.URL("http://localhost:8080/swagger/doc.json"),
+	))
+
+	// API keys only reach the routes their scopes allow; user tokens pass
+	// every scope check.
+	read := handlers.RequireScope(auth.ScopeSubscriptionsRead)
+	write := handlers.RequireScope(auth.ScopeSubscriptionsWrite)
+	reports := handlers.RequireScope(auth.ScopeReportsRead)
+
+	router.Group(func(api chi.Router) {
+		api.Use(authenticate)
+		api.Use(handlers.ResolveTenant)
+
+		api.With(write).Post("/subscriptions:batch", subscriptionHandler.BatchSubscriptions)
+
+		api.Route("/subscriptions", func(r chi.Router) {
+			r.With(write).Post("/", subscriptionHandler.CreateSubscription)
+			r.With(write).Delete("/{id}", subscriptionHandler.DeleteSubscription)
+			r.With(read).Get("/{id}", subscriptionHandler.GetSubscriptionByID)
+			r.With(read).Get("/{id}/history", subscriptionHandler.GetSubscriptionHistory)
+			r.With(write).Post("/{id}/restore", subscriptionHandler.RestoreSubscription)
+			r.With(read).Get("/", subscriptionHandler.ListSubscriptionsByUserID)
+			r.With(write).Put("/{id}", subscriptionHandler.UpdateSubscription)
+			r.With(write).Patch("/{id}", subscriptionHandler.PatchSubscription)
+			r.With(reports).Get("/total-cost", subscriptionHandler.SumTotalCostSubscriptions)
+			r.With(write).Post("/import", subscriptionHandler.ImportSubscriptions)
+			r.With(read).Get("/export", subscriptionHandler.ExportSubscriptions)
+		})
+
+		api.Route("/services", func(r chi.Router) {
+			r.With(handlers.RequireAdmin).Post("/", servicesHandler.CreateService)
+			r.With(read).Get("/", servicesHandler.ListServices)
+			r.With(read).Get("/{id}", servicesHandler.GetService)
+			r.With(handlers.RequireAdmin).Put("/{id}", servicesHandler.UpdateService)
+			r.With(handlers.RequireAdmin).Delete("/{id}", servicesHandler.DeleteService)
+		})
+
+		api.Route("/users", func(r chi.Router) {
+			r.With(write).Post("/", usersHandler.CreateUser)
+			r.With(handlers.RequireAdmin).Get("/", usersHandler.ListUsers)
+			r.With(read).Get("/{user_id}", usersHandler.GetUser)
+			r.With(write).Put("/{user_id}", usersHandler.UpdateUser)
+			r.With(write).Delete("/{user_id}", usersHandler.DeleteUser)
+			r.With(reports).Get("/{user_id}/renewals", renewalsHandler.RenewalsCalendar)
+		})
+
+		api.Route("/reports", func(r chi.Router) {
+			r.With(reports).Get("/monthly", reportsHandler.MonthlyReport)
+		})
+
+		api.Route("/fx-rates", func(r chi.Router) {
+			r.With(read).Get("/", fxRatesHandler.ListRates)
+			r.With(handlers.RequireAdmin).Put("/", fxRatesHandler.SaveRates)
+		})
+
+		api.Route("/api-keys", func(r chi.Router) {
+			r.Use(handlers.RequireAdmin)
+
+			r.Post("/", apiKeysHandler.IssueAPIKey)
+			r.Get("/", apiKeysHandler.ListAPIKeys)
+			r.Delete("/{id}", apiKeysHandler.RevokeAPIKey)
+			r.Post("/{id}/rotate", apiKeysHandler.RotateAPIKey)
+		})
+	})
+
+	return router
+}
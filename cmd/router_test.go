@@ -0,0 +1,828 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"subscription-aggregator/internal/auth"
+	"subscription-aggregator/internal/config"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/db/memory"
+	"subscription-aggregator/internal/metrics"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
+	"testing"
+	"time"
+)
+
+var testLog = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// newTestServer serves the API on top of storage.
+func newTestServer(t *testing.T, storage db.Storage, authenticate func(http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(newRouter(storage, time.Hour, metrics.New(testLog), authenticate, testLog))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func noAuthentication(next http.Handler) http.Handler {
+	return next
+}
+
+type testResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func call(t *testing.T, server *httptest.Server, method, path string, header http.Header, body any) testResponse {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, server.URL+path, reader)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: read body: %v", method, path, err)
+	}
+
+	return testResponse{status: resp.StatusCode, header: resp.Header, body: data}
+}
+
+func tenantHeader(id string) http.Header {
+	return http.Header{tenant.Header: {id}}
+}
+
+// createTestSubscription creates a user with a Netflix subscription from
+// 07-2025 on and returns their IDs.
+func createTestSubscription(t *testing.T, server *httptest.Server, header http.Header) (userID string, subID string) {
+	t.Helper()
+
+	resp := call(t, server, http.MethodPost, "/users", header, map[string]any{"display_name": "Test"})
+	if resp.status != http.StatusCreated {
+		t.Fatalf("create user: got status %d: %s", resp.status, resp.body)
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(resp.body, &created); err != nil {
+		t.Fatalf("decode user: %v", err)
+	}
+	userID = created.ID
+
+	resp = call(t, server, http.MethodPost, "/subscriptions", header, map[string]any{
+		"service_name": "Netflix",
+		"price":        400,
+		"user_id":      userID,
+		"start_date":   "07-2025",
+	})
+	if resp.status != http.StatusCreated {
+		t.Fatalf("create subscription: got status %d: %s", resp.status, resp.body)
+	}
+
+	if err := json.Unmarshal(resp.body, &created); err != nil {
+		t.Fatalf("decode subscription: %v", err)
+	}
+
+	return userID, created.ID
+}
+
+// checkTenantIsolation creates a user and a subscription as owner and checks
+// that other can neither read, change nor list them.
+func checkTenantIsolation(t *testing.T, server *httptest.Server, owner, other http.Header) {
+	t.Helper()
+
+	user, sub := createTestSubscription(t, server, owner)
+
+	tests := []struct {
+		method string
+		path   string
+		body   any
+		status int
+		want   string
+	}{
+		{method: http.MethodGet, path: "/subscriptions/" + sub},
+		{method: http.MethodGet, path: "/subscriptions/" + sub + "/history"},
+		{method: http.MethodPatch, path: "/subscriptions/" + sub, body: map[string]any{"price": 1}},
+		{method: http.MethodDelete, path: "/subscriptions/" + sub},
+		{method: http.MethodGet, path: "/users/" + user},
+		{method: http.MethodGet, path: "/subscriptions?user_id=" + user},
+		{method: http.MethodGet, path: "/subscriptions/total-cost?period_start=01-2025&period_end=01-2026&user_id=" + user, status: http.StatusOK, want: "0"},
+		{method: http.MethodGet, path: "/subscriptions/export?user_id=" + user},
+	}
+
+	for _, tt := range tests {
+		if tt.status == 0 {
+			tt.status = http.StatusNotFound
+		}
+
+		resp := call(t, server, tt.method, tt.path, other, tt.body)
+		if resp.status != tt.status {
+			t.Errorf("%s %s from another tenant: got status %d, want %d: %s", tt.method, tt.path, resp.status, tt.status, resp.body)
+			continue
+		}
+
+		if tt.want != "" && strings.TrimSpace(string(resp.body)) != tt.want {
+			t.Errorf("%s %s from another tenant: got %s, want %s", tt.method, tt.path, resp.body, tt.want)
+		}
+	}
+
+	resp := call(t, server, http.MethodGet, "/subscriptions?all_users=true", other, nil)
+	if resp.status != http.StatusOK {
+		t.Fatalf("list all users from another tenant: got status %d: %s", resp.status, resp.body)
+	}
+
+	if bytes.Contains(resp.body, []byte(sub)) {
+		t.Errorf("list all users from another tenant: got %s, want no subscription %s", resp.body, sub)
+	}
+
+	resp = call(t, server, http.MethodGet, "/subscriptions/"+sub, owner, nil)
+	if resp.status != http.StatusOK {
+		t.Errorf("get subscription from its own tenant: got status %d: %s", resp.status, resp.body)
+	}
+
+	if !bytes.Contains(resp.body, []byte(`"price":400`)) {
+		t.Errorf("get subscription from its own tenant: got %s, want it unchanged", resp.body)
+	}
+}
+
+// testTenantIsolation checks tenant isolation on storage for tenants named by
+// the X-Tenant-ID header and for tenants named by the tokens of admins, who
+// may act for every user, so that only the tenant keeps them apart.
+func testTenantIsolation(t *testing.T, storage db.Storage) {
+	t.Helper()
+
+	newTenant := func(name string) string {
+		return name + "-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:16]
+	}
+
+	server := newTestServer(t, storage, noAuthentication)
+	checkTenantIsolation(t, server, tenantHeader(newTenant("acme")), tenantHeader(newTenant("globex")))
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	keySet, err := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "oct", "kid": "test", "alg": "HS256", "k": base64.RawURLEncoding.EncodeToString(secret)},
+	}})
+	if err != nil {
+		t.Fatalf("marshal key set: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, keySet, 0o600); err != nil {
+		t.Fatalf("write key set: %v", err)
+	}
+
+	authenticate, err := newAuthenticator(&config.Config{AuthJWKSFile: path, AuthLeeway: time.Minute}, storage, testLog)
+	if err != nil {
+		t.Fatalf("create authenticator: %v", err)
+	}
+
+	bearer := func(tenantID string) http.Header {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub":       uuid.NewString(),
+			"tenant_id": tenantID,
+			"roles":     []string{auth.AdminRole},
+			"exp":       time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = "test"
+
+		signed, err := token.SignedString(secret)
+		if err != nil {
+			t.Fatalf("sign token: %v", err)
+		}
+
+		return http.Header{"Authorization": {"Bearer " + signed}}
+	}
+
+	acme := newTenant("acme")
+	owner, other := bearer(acme), bearer(newTenant("globex"))
+
+	server = newTestServer(t, storage, authenticate)
+	checkTenantIsolation(t, server, owner, other)
+
+	// A token cannot be pointed at another tenant with the header.
+	header := other.Clone()
+	header.Set(tenant.Header, acme)
+
+	resp := call(t, server, http.MethodGet, "/subscriptions?all_users=true", header, nil)
+	if resp.status != http.StatusForbidden {
+		t.Errorf("token for tenant %s: got status %d, want %d: %s", acme, resp.status, http.StatusForbidden, resp.body)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	server := newTestServer(t, memory.New(testLog), noAuthentication)
+	header := tenantHeader("acme")
+
+	user, sub := createTestSubscription(t, server, header)
+	path := "/subscriptions/" + sub
+
+	resp := call(t, server, http.MethodGet, path, header, nil)
+
+	tag := resp.header.Get("ETag")
+	if resp.status != http.StatusOK || tag == "" {
+		t.Fatalf("get: got status %d and ETag %q: %s", resp.status, tag, resp.body)
+	}
+
+	withHeader := func(name, value string) http.Header {
+		h := header.Clone()
+		h.Set(name, value)
+		return h
+	}
+
+	body := map[string]any{"service_name": "Netflix", "price": 500, "user_id": user, "start_date": "07-2025"}
+
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+		body   any
+		status int
+	}{
+		{name: "cached copy", method: http.MethodGet, header: withHeader("If-None-Match", tag), status: http.StatusNotModified},
+		{name: "weak cached copy", method: http.MethodGet, header: withHeader("If-None-Match", "W/"+tag), status: http.StatusNotModified},
+		{name: "other copy", method: http.MethodGet, header: withHeader("If-None-Match", `"0"`), status: http.StatusOK},
+		{name: "stale update", method: http.MethodPut, header: withHeader("If-Match", `"0"`), body: body, status: http.StatusPreconditionFailed},
+		{name: "stale patch", method: http.MethodPatch, header: withHeader("If-Match", `"0"`), body: map[string]any{"price": 600}, status: http.StatusPreconditionFailed},
+		{name: "current update", method: http.MethodPut, header: withHeader("If-Match", tag), body: body, status: http.StatusOK},
+		// The update above changed the version, so the tag is stale now.
+		{name: "update with the old tag", method: http.MethodPut, header: withHeader("If-Match", tag), body: body, status: http.StatusPreconditionFailed},
+		{name: "old cached copy", method: http.MethodGet, header: withHeader("If-None-Match", tag), status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		resp := call(t, server, tt.method, path, tt.header, tt.body)
+		if resp.status != tt.status {
+			t.Errorf("%s: got status %d, want %d: %s", tt.name, resp.status, tt.status, resp.body)
+		}
+
+		if resp.status == http.StatusNotModified && len(resp.body) != 0 {
+			t.Errorf("%s: got body %s on 304", tt.name, resp.body)
+		}
+	}
+
+	resp = call(t, server, http.MethodGet, path, header, nil)
+	if got := resp.header.Get("ETag"); got == tag || !bytes.Contains(resp.body, []byte(`"price":500`)) {
+		t.Errorf("after the update: got ETag %s and %s, want a new ETag and price 500", got, resp.body)
+	}
+}
+
+func TestPatchSubscription(t *testing.T) {
+	server := newTestServer(t, memory.New(testLog), noAuthentication)
+	header := tenantHeader("acme")
+
+	_, sub := createTestSubscription(t, server, header)
+	path := "/subscriptions/" + sub
+
+	tests := []struct {
+		patch  any
+		status int
+		want   []string
+		absent string
+	}{
+		{patch: map[string]any{"end_date": "12-2025"}, status: http.StatusOK, want: []string{`"price":400`, `"end_date":"2025-12-01T00:00:00Z"`}},
+		{patch: map[string]any{"price": 500}, status: http.StatusOK, want: []string{`"price":500`, `"end_date":"2025-12-01T00:00:00Z"`}},
+		{patch: map[string]any{"end_date": nil}, status: http.StatusOK, want: []string{`"price":500`}, absent: `"end_date"`},
+		{patch: map[string]any{"price": nil}, status: http.StatusBadRequest},
+		{patch: map[string]any{"price": -1}, status: http.StatusBadRequest},
+		{patch: map[string]any{"end_date": "01-2025"}, status: http.StatusBadRequest},
+		{patch: []int{1}, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		resp := call(t, server, http.MethodPatch, path, header, tt.patch)
+		if resp.status != tt.status {
+			t.Errorf("%v: got status %d, want %d: %s", tt.patch, resp.status, tt.status, resp.body)
+			continue
+		}
+
+		for _, want := range tt.want {
+			if !bytes.Contains(resp.body, []byte(want)) {
+				t.Errorf("%v: got %s, want %s", tt.patch, resp.body, want)
+			}
+		}
+
+		if tt.absent != "" && bytes.Contains(resp.body, []byte(tt.absent)) {
+			t.Errorf("%v: got %s, want no %s", tt.patch, resp.body, tt.absent)
+		}
+	}
+
+	// Failed patches leave the subscription as the last good one made it.
+	resp := call(t, server, http.MethodGet, path, header, nil)
+	if !bytes.Contains(resp.body, []byte(`"price":500`)) || bytes.Contains(resp.body, []byte(`"end_date"`)) {
+		t.Errorf("after the patches: got %s", resp.body)
+	}
+}
+
+func TestCreateAndUpdateReturnTheStoredSubscription(t *testing.T) {
+	server := newTestServer(t, memory.New(testLog), noAuthentication)
+	header := tenantHeader("acme")
+
+	user, _ := createTestSubscription(t, server, header)
+
+	type stored struct {
+		ID        string    `json:"id"`
+		Price     int       `json:"price"`
+		Version   int       `json:"version"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+
+	decode := func(name string, resp testResponse) stored {
+		t.Helper()
+
+		var sub stored
+		if err := json.Unmarshal(resp.body, &sub); err != nil {
+			t.Fatalf("%s: decode: %v", name, err)
+		}
+
+		return sub
+	}
+
+	body := map[string]any{"service_name": "Okko", "price": 300, "user_id": user, "start_date": "07-2025"}
+
+	resp := call(t, server, http.MethodPost, "/subscriptions", header, body)
+	if resp.status != http.StatusCreated {
+		t.Fatalf("create: got status %d: %s", resp.status, resp.body)
+	}
+
+	created := decode("create", resp)
+	if uuid.Validate(created.ID) != nil || created.Version != 1 || created.CreatedAt.IsZero() || !created.UpdatedAt.Equal(created.CreatedAt) {
+		t.Errorf("create: got %+v, want a new ID, version 1 and both timestamps", created)
+	}
+
+	if location := resp.header.Get("Location"); location != "/subscriptions/"+created.ID {
+		t.Errorf("create: got Location %q, want /subscriptions/%s", location, created.ID)
+	}
+
+	if resp := call(t, server, http.MethodGet, resp.header.Get("Location"), header, nil); resp.status != http.StatusOK || decode("get", resp) != created {
+		t.Errorf("get the created subscription: got status %d: %s", resp.status, resp.body)
+	}
+
+	body["price"] = 350
+
+	resp = call(t, server, http.MethodPut, "/subscriptions/"+created.ID, header, body)
+	if resp.status != http.StatusOK {
+		t.Fatalf("update: got status %d: %s", resp.status, resp.body)
+	}
+
+	updated := decode("update", resp)
+	if updated.ID != created.ID || updated.Price != 350 || updated.Version != 2 || !updated.CreatedAt.Equal(created.CreatedAt) || updated.UpdatedAt.Before(created.UpdatedAt) {
+		t.Errorf("update: got %+v after %+v", updated, created)
+	}
+
+	if resp := call(t, server, http.MethodGet, "/subscriptions/"+created.ID, header, nil); decode("get", resp) != updated {
+		t.Errorf("get the updated subscription: got %s, want %+v", resp.body, updated)
+	}
+}
+
+func withIdempotencyKey(header http.Header, key string) http.Header {
+	header = header.Clone()
+	header.Set("Idempotency-Key", key)
+	return header
+}
+
+func TestIdempotentCreate(t *testing.T) {
+	server := newTestServer(t, memory.New(testLog), noAuthentication)
+	header := tenantHeader("acme")
+
+	user, _ := createTestSubscription(t, server, header)
+
+	body := map[string]any{"service_name": "Okko", "price": 300, "user_id": user, "start_date": "07-2025"}
+
+	first := call(t, server, http.MethodPost, "/subscriptions", withIdempotencyKey(header, "create-1"), body)
+	if first.status != http.StatusCreated {
+		t.Fatalf("create: got status %d: %s", first.status, first.body)
+	}
+
+	unknownUser := map[string]any{"service_name": "Okko", "price": 300, "user_id": uuid.NewString(), "start_date": "07-2025"}
+	changed := map[string]any{"service_name": "Okko", "price": 301, "user_id": user, "start_date": "07-2025"}
+
+	tests := []struct {
+		name     string
+		key      string
+		body     any
+		status   int
+		replayed bool
+	}{
+		{name: "retry", key: "create-1", body: body, status: http.StatusCreated, replayed: true},
+		{name: "retry with another payload", key: "create-1", body: changed, status: http.StatusUnprocessableEntity},
+		{name: "failed request", key: "create-2", body: unknownUser, status: http.StatusBadRequest},
+		// The failed request released its key, so it can be reused.
+		{name: "retry of the failed request", key: "create-2", body: changed, status: http.StatusCreated},
+		{name: "key too long", key: strings.Repeat("k", 256), body: body, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		resp := call(t, server, http.MethodPost, "/subscriptions", withIdempotencyKey(header, tt.key), tt.body)
+		if resp.status != tt.status {
+			t.Errorf("%s: got status %d, want %d: %s", tt.name, resp.status, tt.status, resp.body)
+			continue
+		}
+
+		if replayed := resp.header.Get("Idempotent-Replayed") == "true"; replayed != tt.replayed {
+			t.Errorf("%s: got replayed %v, want %v", tt.name, replayed, tt.replayed)
+		}
+
+		if tt.replayed {
+			if !bytes.Equal(resp.body, first.body) || resp.header.Get("Location") != first.header.Get("Location") {
+				t.Errorf("%s: got %s at %s, want %s at %s", tt.name, resp.body, resp.header.Get("Location"), first.body, first.header.Get("Location"))
+			}
+		}
+	}
+
+	// The same key in another tenant is another key.
+	other := withIdempotencyKey(tenantHeader("globex"), "create-1")
+	if resp := call(t, server, http.MethodPost, "/subscriptions", other, body); resp.status != http.StatusBadRequest {
+		t.Errorf("key of another tenant: got status %d, want %d for a user it does not have: %s", resp.status, http.StatusBadRequest, resp.body)
+	}
+
+	resp := call(t, server, http.MethodGet, "/subscriptions?service_name=Okko&user_id="+user, header, nil)
+	var page struct {
+		Items []any `json:"items"`
+	}
+	if err := json.Unmarshal(resp.body, &page); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+
+	if len(page.Items) != 2 {
+		t.Errorf("got %d Okko subscriptions, want 2: %s", len(page.Items), resp.body)
+	}
+}
+
+// importCSV posts data as the file of a CSV import.
+func importCSV(t *testing.T, server *httptest.Server, header http.Header, query string, file string) testResponse {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	part, err := form.CreateFormFile("file", "subscriptions.csv")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+
+	if _, err := io.WriteString(part, file); err != nil {
+		t.Fatalf("write form file: %v", err)
+	}
+
+	if err := form.Close(); err != nil {
+		t.Fatalf("close form: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/subscriptions/import"+query, &body)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("import: read body: %v", err)
+	}
+
+	return testResponse{status: resp.StatusCode, header: resp.Header, body: data}
+}
+
+func TestImportExportRoundTrip(t *testing.T) {
+	server := newTestServer(t, memory.New(testLog), noAuthentication)
+	header := tenantHeader("acme")
+
+	user, _ := createTestSubscription(t, server, header)
+
+	export := func() [][]string {
+		t.Helper()
+
+		resp := call(t, server, http.MethodGet, "/subscriptions/export?sort=price&user_id="+user, header, nil)
+		if resp.status != http.StatusOK {
+			t.Fatalf("export: got status %d: %s", resp.status, resp.body)
+		}
+
+		records, err := csv.NewReader(bytes.NewReader(resp.body)).ReadAll()
+		if err != nil {
+			t.Fatalf("export: parse: %v", err)
+		}
+
+		return records
+	}
+
+	exported := export()
+	if len(exported) != 2 {
+		t.Fatalf("export: got %d lines, want a header and one row", len(exported))
+	}
+
+	valid := "service_name,price,user_id,start_date,end_date\n=Okko,300," + user + ",08-2025,\n"
+	invalid := valid + "Kion,-1," + user + ",13-2025,\n"
+
+	decodeReport := func(resp testResponse) models.ImportReport {
+		t.Helper()
+
+		var report models.ImportReport
+		if err := json.Unmarshal(resp.body, &report); err != nil {
+			t.Fatalf("decode report: %v", err)
+		}
+
+		return report
+	}
+
+	resp := importCSV(t, server, header, "?dry_run=true", invalid)
+	report := decodeReport(resp)
+	if resp.status != http.StatusOK || report.Rows != 2 || report.Imported != 0 || len(report.Errors) != 1 || report.Errors[0].Line != 3 {
+		t.Errorf("dry run: got status %d: %s", resp.status, resp.body)
+	}
+
+	if resp := importCSV(t, server, header, "", invalid); resp.status != http.StatusBadRequest || !bytes.Contains(resp.body, []byte("line[3].price")) {
+		t.Errorf("invalid import: got status %d: %s", resp.status, resp.body)
+	}
+
+	if got := export(); len(got) != 2 {
+		t.Fatalf("after the failed imports: got %d lines, want 2", len(got))
+	}
+
+	resp = importCSV(t, server, header, "", valid)
+	if report := decodeReport(resp); resp.status != http.StatusOK || report.Imported != 1 {
+		t.Fatalf("import: got status %d: %s", resp.status, resp.body)
+	}
+
+	// The export can be imported again; its id column is ignored.
+	var again bytes.Buffer
+	if err := csv.NewWriter(&again).WriteAll(exported); err != nil {
+		t.Fatalf("write export: %v", err)
+	}
+
+	resp = importCSV(t, server, header, "", again.String())
+	if report := decodeReport(resp); resp.status != http.StatusOK || report.Imported != 1 {
+		t.Fatalf("import the export: got status %d: %s", resp.status, resp.body)
+	}
+
+	records := export()
+	if len(records) != 4 {
+		t.Fatalf("after the imports: got %d lines, want a header and 3 rows", len(records))
+	}
+
+	// The formula-like service name comes back escaped.
+	if records[1][1] != "'=Okko" {
+		t.Errorf("cheapest row: got service %q, want '=Okko", records[1][1])
+	}
+
+	if records[2][0] == records[3][0] || records[2][1] != "Netflix" || records[3][1] != "Netflix" {
+		t.Errorf("re-imported rows: got %v and %v, want two Netflix rows with their own IDs", records[2], records[3])
+	}
+}
+
+func TestRenewalsCalendarIsStable(t *testing.T) {
+	server := newTestServer(t, memory.New(testLog), noAuthentication)
+	header := tenantHeader("acme")
+
+	user, sub := createTestSubscription(t, server, header)
+	path := "/users/" + user + "/renewals.ics?months=3&reminder_days=2"
+
+	first := call(t, server, http.MethodGet, path, header, nil)
+	if first.status != http.StatusOK {
+		t.Fatalf("calendar: got status %d: %s", first.status, first.body)
+	}
+
+	if got := first.header.Get("Content-Type"); got != "text/calendar; charset=utf-8" {
+		t.Errorf("calendar: got Content-Type %q", got)
+	}
+
+	// Refreshing the feed must give calendar apps the same events.
+	if again := call(t, server, http.MethodGet, path, header, nil); !bytes.Equal(again.body, first.body) {
+		t.Errorf("calendar changed between requests:\n%s\n%s", first.body, again.body)
+	}
+
+	var uids []string
+	for _, line := range strings.Split(string(first.body), "\r\n") {
+		if uid, ok := strings.CutPrefix(line, "UID:"); ok {
+			uids = append(uids, uid)
+		}
+	}
+
+	if len(uids) < 3 {
+		t.Fatalf("calendar: got %d events over 3 months, want at least 3", len(uids))
+	}
+
+	seen := make(map[string]bool)
+	for _, uid := range uids {
+		if !strings.HasPrefix(uid, sub+"-") || !strings.HasSuffix(uid, "@subscription-aggregator") || seen[uid] {
+			t.Errorf("calendar: unexpected or repeated UID %s", uid)
+		}
+		seen[uid] = true
+	}
+
+	if got := strings.Count(string(first.body), "TRIGGER:-P2D\r\n"); got != len(uids) {
+		t.Errorf("calendar: got %d reminders for %d events", got, len(uids))
+	}
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{path: "/users/" + user + "/renewals", status: http.StatusNotFound},
+		{path: "/users/" + user + "/renewals.ics?months=61", status: http.StatusBadRequest},
+		{path: "/users/" + user + "/renewals.ics?reminder_days=31", status: http.StatusBadRequest},
+		{path: "/users/not-a-uuid/renewals.ics", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		if resp := call(t, server, http.MethodGet, tt.path, header, nil); resp.status != tt.status {
+			t.Errorf("%s: got status %d, want %d: %s", tt.path, resp.status, tt.status, resp.body)
+		}
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	storage := memory.New(testLog)
+
+	_, sub := createTestSubscription(t, newTestServer(t, storage, noAuthentication), tenantHeader("acme"))
+
+	authenticate, err := newAuthenticator(&config.Config{AuthAPIKeys: true}, storage, testLog)
+	if err != nil {
+		t.Fatalf("create authenticator: %v", err)
+	}
+
+	server := newTestServer(t, storage, authenticate)
+
+	apiKey := func(key string) http.Header {
+		return http.Header{"Authorization": {"ApiKey " + key}}
+	}
+
+	// The first admin key comes from the command line, the others from it.
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	ctx := tenant.WithID(context.Background(), "acme")
+	if err := storage.CreateAPIKey(ctx, &models.APIKey{ID: uuid.NewString(), Name: "admin", Prefix: prefix, Scopes: []string{auth.ScopeAdmin}}, hash); err != nil {
+		t.Fatalf("create admin key: %v", err)
+	}
+	admin := apiKey(key)
+
+	issue := func(scopes ...string) (models.IssuedAPIKey, http.Header) {
+		t.Helper()
+
+		resp := call(t, server, http.MethodPost, "/api-keys", admin, map[string]any{"name": strings.Join(scopes, " "), "scopes": scopes})
+		if resp.status != http.StatusCreated {
+			t.Fatalf("issue %v: got status %d: %s", scopes, resp.status, resp.body)
+		}
+
+		var issued models.IssuedAPIKey
+		if err := json.Unmarshal(resp.body, &issued); err != nil {
+			t.Fatalf("decode key: %v", err)
+		}
+
+		return issued, apiKey(issued.Key)
+	}
+
+	_, read := issue(auth.ScopeSubscriptionsRead)
+	_, write := issue(auth.ScopeSubscriptionsWrite)
+	_, reports := issue(auth.ScopeReportsRead)
+
+	var user struct {
+		UserID string `json:"user_id"`
+	}
+	resp := call(t, server, http.MethodGet, "/subscriptions/"+sub, read, nil)
+	if err := json.Unmarshal(resp.body, &user); err != nil {
+		t.Fatalf("decode subscription: %v", err)
+	}
+
+	get := "/subscriptions/" + sub
+	totalCost := "/subscriptions/total-cost?period_start=07-2025&period_end=08-2025&user_id=" + user.UserID
+	report := "/reports/monthly?from=07-2025&to=08-2025&user_id=" + user.UserID
+
+	tests := []struct {
+		name   string
+		header http.Header
+		method string
+		path   string
+		body   any
+		status int
+	}{
+		{name: "read", header: read, method: http.MethodGet, path: get, status: http.StatusOK},
+		{name: "read", header: read, method: http.MethodGet, path: "/subscriptions?all_users=true", status: http.StatusOK},
+		{name: "read", header: read, method: http.MethodPatch, path: get, body: map[string]any{"price": 401}, status: http.StatusForbidden},
+		{name: "read", header: read, method: http.MethodGet, path: totalCost, status: http.StatusForbidden},
+		{name: "read", header: read, method: http.MethodGet, path: "/api-keys", status: http.StatusForbidden},
+		{name: "write", header: write, method: http.MethodPatch, path: get, body: map[string]any{"price": 401}, status: http.StatusOK},
+		{name: "write", header: write, method: http.MethodGet, path: get, status: http.StatusForbidden},
+		{name: "write", header: write, method: http.MethodPut, path: "/fx-rates", body: []any{}, status: http.StatusForbidden},
+		{name: "reports", header: reports, method: http.MethodGet, path: totalCost, status: http.StatusOK},
+		{name: "reports", header: reports, method: http.MethodGet, path: report, status: http.StatusOK},
+		{name: "reports", header: reports, method: http.MethodGet, path: get, status: http.StatusForbidden},
+		{name: "admin", header: admin, method: http.MethodGet, path: get, status: http.StatusOK},
+		{name: "admin", header: admin, method: http.MethodGet, path: "/api-keys", status: http.StatusOK},
+		{name: "no key", header: http.Header{}, method: http.MethodGet, path: get, status: http.StatusUnauthorized},
+		{name: "malformed key", header: apiKey("garbage"), method: http.MethodGet, path: get, status: http.StatusUnauthorized},
+		{name: "unknown key", header: apiKey("sa_" + strings.Repeat("x", 43)), method: http.MethodGet, path: get, status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		resp := call(t, server, tt.method, tt.path, tt.header, tt.body)
+		if resp.status != tt.status {
+			t.Errorf("%s: %s %s: got status %d, want %d: %s", tt.name, tt.method, tt.path, resp.status, tt.status, resp.body)
+		}
+	}
+
+	// A rotated key stops working at once and a revoked one for good.
+	rotated, old := issue(auth.ScopeSubscriptionsRead)
+
+	resp = call(t, server, http.MethodPost, "/api-keys/"+rotated.ID+"/rotate", admin, nil)
+	if resp.status != http.StatusOK {
+		t.Fatalf("rotate: got status %d: %s", resp.status, resp.body)
+	}
+
+	var fresh models.IssuedAPIKey
+	if err := json.Unmarshal(resp.body, &fresh); err != nil {
+		t.Fatalf("decode rotated key: %v", err)
+	}
+
+	if resp := call(t, server, http.MethodGet, get, old, nil); resp.status != http.StatusUnauthorized {
+		t.Errorf("key before rotation: got status %d, want %d", resp.status, http.StatusUnauthorized)
+	}
+
+	if resp := call(t, server, http.MethodGet, get, apiKey(fresh.Key), nil); resp.status != http.StatusOK {
+		t.Errorf("key after rotation: got status %d, want %d: %s", resp.status, http.StatusOK, resp.body)
+	}
+
+	if resp := call(t, server, http.MethodDelete, "/api-keys/"+rotated.ID, admin, nil); resp.status != http.StatusNoContent {
+		t.Fatalf("revoke: got status %d: %s", resp.status, resp.body)
+	}
+
+	if resp := call(t, server, http.MethodGet, get, apiKey(fresh.Key), nil); resp.status != http.StatusUnauthorized {
+		t.Errorf("revoked key: got status %d, want %d", resp.status, http.StatusUnauthorized)
+	}
+}
+
+func TestTenantIsolationMemory(t *testing.T) {
+	testTenantIsolation(t, memory.New(testLog))
+}
+
+func TestTenantIsolationPostgres(t *testing.T) {
+	if os.Getenv("POSTGRES_HOST") == "" {
+		t.Skip("POSTGRES_HOST is not set")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	cfg.MigrateOnStartup = true
+
+	storage, closeStorage, err := newStorage(context.Background(), cfg, metrics.New(testLog), testLog)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(closeStorage)
+
+	testTenantIsolation(t, storage)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"subscription-aggregator/internal/tenant"
+)
+
+// tenantArgs reads the -tenant option that subcommands accept before their
+// arguments. It returns ctx scoped to the tenant, the default one unless
+// the option is given, and the remaining arguments.
+func tenantArgs(ctx context.Context, name string, args []string) (context.Context, []string, error) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	id := flags.String("tenant", tenant.Default, "tenant to act for")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tenant.Validate(*id); err != nil {
+		return nil, nil, fmt.Errorf("invalid tenant %q: %w", *id, err)
+	}
+
+	return tenant.WithID(ctx, *id), flags.Args(), nil
+}
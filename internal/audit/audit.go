@@ -0,0 +1,60 @@
+// Package audit carries the who and why of a request down to the storage
+// layer, which records them next to every subscription mutation.
+package audit
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+)
+
+const (
+	ActionCreated  = "created"
+	ActionUpdated  = "updated"
+	ActionDeleted  = "deleted"
+	ActionRestored = "restored"
+)
+
+// AnonymousActor is recorded when a request carries no identity.
+const AnonymousActor = "anonymous"
+
+type contextKey int
+
+const (
+	actorKey contextKey = iota
+	requestIDKey
+)
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey).(string); ok && actor != "" {
+		return actor
+	}
+
+	return AnonymousActor
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// Middleware copies the chi request ID and the X-Actor header into the
+// request context. It must run after middleware.RequestID.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithRequestID(r.Context(), middleware.GetReqID(r.Context()))
+		if actor := r.Header.Get("X-Actor"); actor != "" {
+			ctx = WithActor(ctx, actor)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		actor string
+		want  string
+	}{
+		{actor: "support", want: "support"},
+		{want: AnonymousActor},
+	}
+
+	for _, tt := range tests {
+		var actor, requestID string
+		handler := middleware.RequestID(Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actor, requestID = Actor(r.Context()), RequestID(r.Context())
+		})))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(middleware.RequestIDHeader, "req-1")
+		if tt.actor != "" {
+			req.Header.Set("X-Actor", tt.actor)
+		}
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if actor != tt.want {
+			t.Errorf("X-Actor %q: got actor %q, want %q", tt.actor, actor, tt.want)
+		}
+
+		if requestID != "req-1" {
+			t.Errorf("X-Actor %q: got request ID %q, want req-1", tt.actor, requestID)
+		}
+	}
+}
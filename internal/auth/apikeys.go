@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"subscription-aggregator/internal/models"
+)
+
+// Scopes of API keys. ScopeAdmin includes every other scope.
+const (
+	ScopeSubscriptionsRead  = "subscriptions:read"
+	ScopeSubscriptionsWrite = "subscriptions:write"
+	ScopeReportsRead        = "reports:read"
+	ScopeAdmin              = "admin"
+)
+
+var scopes = []string{ScopeSubscriptionsRead, ScopeSubscriptionsWrite, ScopeReportsRead, ScopeAdmin}
+
+func IsScope(scope string) bool {
+	return slices.Contains(scopes, scope)
+}
+
+const (
+	apiKeyPrefix = "sa_"
+	// apiKeyDisplayLength is how much of a key is kept in clear text to
+	// tell keys apart.
+	apiKeyDisplayLength = 10
+)
+
+// NewAPIKey generates a random API key and returns it with the prefix that
+// identifies it and the hash that is stored instead of the key.
+func NewAPIKey() (key string, prefix string, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	return key, key[:apiKeyDisplayLength], HashAPIKey(key), nil
+}
+
+// HashAPIKey hashes a key for lookup. The keys are random 256-bit secrets,
+// so a plain SHA-256 is enough to make a leaked table useless.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// LooksLikeAPIKey tells a key apart from garbage before any lookup.
+func LooksLikeAPIKey(key string) bool {
+	return strings.HasPrefix(key, apiKeyPrefix) && len(key) > apiKeyDisplayLength
+}
+
+// APIKeyPrincipal is the caller authenticated by an API key.
+func APIKeyPrincipal(key *models.APIKey) *Principal {
+	return &Principal{
+		Subject: "api-key:" + key.ID,
+		Admin:   slices.Contains(key.Scopes, ScopeAdmin),
+		Service: true,
+		Scopes:  key.Scopes,
+		Tenant:  key.TenantID,
+	}
+}
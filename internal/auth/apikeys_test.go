@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"strings"
+	"subscription-aggregator/internal/models"
+	"testing"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	key, prefix, hash, err := NewAPIKey()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	if !LooksLikeAPIKey(key) || !strings.HasPrefix(key, prefix) || len(prefix) != apiKeyDisplayLength {
+		t.Errorf("got key %q with prefix %q", key, prefix)
+	}
+
+	if hash != HashAPIKey(key) || strings.Contains(hash, key) {
+		t.Errorf("got hash %q for key %q", hash, key)
+	}
+
+	if other, _, _, _ := NewAPIKey(); other == key {
+		t.Errorf("generated the same key twice")
+	}
+
+	for _, garbage := range []string{"", "sa_", "sa_short", "xx_0123456789abcdef"} {
+		if LooksLikeAPIKey(garbage) {
+			t.Errorf("%q looks like an api key", garbage)
+		}
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	key := func(scopes ...string) *Principal {
+		return APIKeyPrincipal(&models.APIKey{ID: "1", Scopes: scopes})
+	}
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		scope     string
+		want      bool
+	}{
+		{name: "user", principal: &Principal{Subject: "user"}, scope: ScopeReportsRead, want: true},
+		{name: "read key", principal: key(ScopeSubscriptionsRead), scope: ScopeSubscriptionsRead, want: true},
+		{name: "read key", principal: key(ScopeSubscriptionsRead), scope: ScopeSubscriptionsWrite},
+		{name: "read key", principal: key(ScopeSubscriptionsRead), scope: ScopeReportsRead},
+		{name: "read and reports key", principal: key(ScopeSubscriptionsRead, ScopeReportsRead), scope: ScopeReportsRead, want: true},
+		{name: "admin key", principal: key(ScopeAdmin), scope: ScopeSubscriptionsWrite, want: true},
+		{name: "key without scopes", principal: key(), scope: ScopeSubscriptionsRead},
+	}
+
+	for _, tt := range tests {
+		if got := tt.principal.HasScope(tt.scope); got != tt.want {
+			t.Errorf("%s: %s: got %v, want %v", tt.name, tt.scope, got, tt.want)
+		}
+	}
+
+	if !key(ScopeAdmin).Admin || key(ScopeSubscriptionsWrite).Admin {
+		t.Errorf("only keys with the admin scope are admins")
+	}
+}
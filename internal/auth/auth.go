@@ -0,0 +1,75 @@
+// Package auth verifies the bearer tokens of requests and carries the
+// authenticated caller to the handlers.
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// AdminRole is the token role that lets a caller act on every user's data.
+const AdminRole = "admin"
+
+var (
+	// ErrUnauthenticated means the request carries no valid credentials.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden means the caller may not act on the requested data.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Principal is the authenticated caller. Subject is the ID of the user the
+// caller acts as.
+type Principal struct {
+	Subject string
+	Admin   bool
+	// Service is set for API keys, which act for every user but only within
+	// their Scopes.
+	Service bool
+	Scopes  []string
+	// Tenant is the organization whose data the caller may reach.
+	Tenant string
+}
+
+// HasScope reports whether the principal may call routes that need scope.
+// Tokens of users are limited by ownership instead and have every scope.
+func (p *Principal) HasScope(scope string) bool {
+	if !p.Service {
+		return true
+	}
+
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+type contextKey int
+
+const principalKey contextKey = iota
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// FromContext returns the caller of the request, or nil when authentication
+// is turned off.
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey).(*Principal)
+	return principal
+}
+
+// CanActFor reports whether the caller may touch the data of userID. Every
+// caller may when authentication is turned off.
+func CanActFor(ctx context.Context, userID string) bool {
+	return ActsForAllUsers(ctx) || FromContext(ctx).Subject == userID
+}
+
+// ActsForAllUsers reports whether the caller may touch every user's data.
+func ActsForAllUsers(ctx context.Context) bool {
+	principal := FromContext(ctx)
+	return principal == nil || principal.Admin || principal.Service
+}
+
+// IsAdmin reports whether the caller may use the admin endpoints.
+func IsAdmin(ctx context.Context) bool {
+	principal := FromContext(ctx)
+	return principal == nil || principal.Admin
+}
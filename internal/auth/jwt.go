@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"cmp"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"slices"
+	"subscription-aggregator/internal/tenant"
+	"time"
+)
+
+const (
+	algHS256 = "HS256"
+	algRS256 = "RS256"
+)
+
+// Key is one verification key of a key set: an HMAC secret for HS256 or an
+// RSA public key for RS256.
+type Key struct {
+	ID        string
+	Algorithm string
+	material  jwt.VerificationKey
+}
+
+// jwk is the JSON Web Key (RFC 7517) form of a Key.
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	K         string `json:"k"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+}
+
+// LoadKeySet reads a JSON Web Key Set file with "oct" keys for HS256 and
+// "RSA" keys for RS256. Keys meant for anything but signatures are skipped.
+func LoadKeySet(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key set: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse key set: %w", err)
+	}
+
+	var keys []Key
+	for i, raw := range set.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+
+		key, err := parseKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("key %d of key set: %w", i, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("key set %s has no signing keys", path)
+	}
+
+	return keys, nil
+}
+
+func parseKey(raw jwk) (Key, error) {
+	key := Key{ID: raw.KeyID}
+
+	switch raw.KeyType {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(raw.K)
+		if err != nil || len(secret) < 32 {
+			return key, fmt.Errorf("oct key must have a base64url secret of at least 32 bytes")
+		}
+		key.Algorithm = algHS256
+		key.material = secret
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(raw.N)
+		if err != nil || len(n) < 256 {
+			return key, fmt.Errorf("RSA key must have a base64url modulus of at least 2048 bits")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(raw.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return key, fmt.Errorf("RSA key has an invalid exponent")
+		}
+		key.Algorithm = algRS256
+		key.material = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	default:
+		return key, fmt.Errorf("unsupported key type %q", raw.KeyType)
+	}
+
+	if raw.Algorithm != "" && raw.Algorithm != key.Algorithm {
+		return key, fmt.Errorf("%s key cannot be used with %s", raw.KeyType, raw.Algorithm)
+	}
+
+	return key, nil
+}
+
+// Verifier checks the signature and claims of JWTs.
+type Verifier struct {
+	keys     []Key
+	issuer   string
+	audience string
+	leeway   time.Duration
+}
+
+// NewVerifier creates a verifier; an empty issuer or audience is not
+// checked. leeway is the clock skew allowed for exp and nbf.
+func NewVerifier(keys []Key, issuer string, audience string, leeway time.Duration) *Verifier {
+	return &Verifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		leeway:   leeway,
+	}
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Roles    []string `json:"roles"`
+	TenantID string   `json:"tenant_id"`
+}
+
+// Verify returns the principal of a compact JWT signed with HS256 or RS256
+// by a key of the set. Every failure is an ErrUnauthenticated.
+func (v *Verifier) Verify(token string, now time.Time) (*Principal, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{algHS256, algRS256}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(v.leeway),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	}
+
+	if v.issuer != "" {
+		options = append(options, jwt.WithIssuer(v.issuer))
+	}
+
+	if v.audience != "" {
+		options = append(options, jwt.WithAudience(v.audience))
+	}
+
+	var body claims
+	if _, err := jwt.NewParser(options...).ParseWithClaims(token, &body, v.keyFor); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+
+	if body.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrUnauthenticated)
+	}
+
+	if body.TenantID != "" {
+		if err := tenant.Validate(body.TenantID); err != nil {
+			return nil, fmt.Errorf("%w: token has an invalid tenant_id: %w", ErrUnauthenticated, err)
+		}
+	}
+
+	return &Principal{
+		Subject: body.Subject,
+		Admin:   slices.Contains(body.Roles, AdminRole),
+		Tenant:  cmp.Or(body.TenantID, tenant.Default),
+	}, nil
+}
+
+// keyFor returns the keys of the algorithm named in the header; the kid, when
+// present, picks a single key. The parser has already refused any algorithm
+// but HS256 and RS256.
+func (v *Verifier) keyFor(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	var set jwt.VerificationKeySet
+	for _, key := range v.keys {
+		if key.Algorithm == token.Method.Alg() && (kid == "" || key.ID == kid) {
+			set.Keys = append(set.Keys, key.material)
+		}
+	}
+
+	if len(set.Keys) == 0 {
+		return nil, fmt.Errorf("no %s key with kid %q", token.Method.Alg(), kid)
+	}
+
+	return set, nil
+}
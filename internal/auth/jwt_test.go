@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "subscription-aggregator"
+	testLeeway   = time.Minute
+	testHMACKid  = "hmac-1"
+	testRSAKid   = "rsa-1"
+)
+
+var testNow = time.Date(2025, time.July, 1, 12, 0, 0, 0, time.UTC)
+
+type testKeys struct {
+	secret     []byte
+	privateKey *rsa.PrivateKey
+	verifier   *Verifier
+}
+
+// newTestKeys writes a key set with one HS256 and one RS256 key and loads it
+// back, so the tests cover LoadKeySet as well.
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+
+	set := map[string]any{"keys": []map[string]string{
+		{"kty": "oct", "kid": testHMACKid, "alg": algHS256, "use": "sig", "k": base64.RawURLEncoding.EncodeToString(secret)},
+		{
+			"kty": "RSA",
+			"kid": testRSAKid,
+			"alg": algRS256,
+			"n":   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+		},
+	}}
+
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("marshal key set: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write key set: %v", err)
+	}
+
+	keys, err := LoadKeySet(path)
+	if err != nil {
+		t.Fatalf("load key set: %v", err)
+	}
+
+	return &testKeys{
+		secret:     secret,
+		privateKey: privateKey,
+		verifier:   NewVerifier(keys, testIssuer, testAudience, testLeeway),
+	}
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		"iss": testIssuer,
+		"aud": testAudience,
+		"exp": testNow.Add(time.Hour).Unix(),
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+
+	return signed
+}
+
+func TestVerify(t *testing.T) {
+	keys := newTestKeys(t)
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&keys.privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+
+	hs256 := func(claims jwt.MapClaims) string {
+		return sign(t, jwt.SigningMethodHS256, testHMACKid, keys.secret, claims)
+	}
+
+	with := func(changes map[string]any) jwt.MapClaims {
+		claims := validClaims()
+		for name, value := range changes {
+			if value == nil {
+				delete(claims, name)
+				continue
+			}
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tamper := func(token string, part int) string {
+		parts := strings.Split(token, ".")
+		if part == 1 {
+			claims := with(map[string]any{"roles": []string{AdminRole}})
+			payload, _ := json.Marshal(claims)
+			parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+		} else {
+			signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+			signature[0] ^= 0xff
+			parts[2] = base64.RawURLEncoding.EncodeToString(signature)
+		}
+		return strings.Join(parts, ".")
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		valid  bool
+		admin  bool
+		tenant string
+	}{
+		{name: "HS256", token: hs256(validClaims()), valid: true, tenant: "default"},
+		{name: "RS256", token: sign(t, jwt.SigningMethodRS256, testRSAKid, keys.privateKey, validClaims()), valid: true, tenant: "default"},
+		{name: "no kid", token: sign(t, jwt.SigningMethodHS256, "", keys.secret, validClaims()), valid: true, tenant: "default"},
+		{name: "admin role", token: hs256(with(map[string]any{"roles": []string{AdminRole}})), valid: true, admin: true, tenant: "default"},
+		{name: "tenant", token: hs256(with(map[string]any{"tenant_id": "acme"})), valid: true, tenant: "acme"},
+
+		{name: "alg none", token: sign(t, jwt.SigningMethodNone, testHMACKid, jwt.UnsafeAllowNoneSignatureType, validClaims())},
+		{name: "HS256 signed with the RSA public key", token: sign(t, jwt.SigningMethodHS256, testRSAKid, publicKeyDER, validClaims())},
+		{name: "HS256 signed with the RSA public key, no kid", token: sign(t, jwt.SigningMethodHS256, "", publicKeyDER, validClaims())},
+		{name: "HS512", token: sign(t, jwt.SigningMethodHS512, testHMACKid, keys.secret, validClaims())},
+
+		{name: "unknown kid", token: sign(t, jwt.SigningMethodHS256, "hmac-2", keys.secret, validClaims())},
+		{name: "kid of a key of another algorithm", token: sign(t, jwt.SigningMethodRS256, testHMACKid, keys.privateKey, validClaims())},
+
+		{name: "expired within leeway", token: hs256(with(map[string]any{"exp": testNow.Add(-30 * time.Second).Unix()})), valid: true, tenant: "default"},
+		{name: "expired beyond leeway", token: hs256(with(map[string]any{"exp": testNow.Add(-2 * time.Minute).Unix()}))},
+		{name: "missing exp", token: hs256(with(map[string]any{"exp": nil}))},
+		{name: "nbf within leeway", token: hs256(with(map[string]any{"nbf": testNow.Add(30 * time.Second).Unix()})), valid: true, tenant: "default"},
+		{name: "nbf beyond leeway", token: hs256(with(map[string]any{"nbf": testNow.Add(2 * time.Minute).Unix()}))},
+
+		{name: "aud array", token: hs256(with(map[string]any{"aud": []string{"other", testAudience}})), valid: true, tenant: "default"},
+		{name: "wrong aud", token: hs256(with(map[string]any{"aud": "other"}))},
+		{name: "wrong aud array", token: hs256(with(map[string]any{"aud": []string{"other", "another"}}))},
+		{name: "missing aud", token: hs256(with(map[string]any{"aud": nil}))},
+		{name: "wrong iss", token: hs256(with(map[string]any{"iss": "https://evil.example.com"}))},
+		{name: "missing sub", token: hs256(with(map[string]any{"sub": nil}))},
+
+		{name: "tampered payload", token: tamper(hs256(validClaims()), 1)},
+		{name: "tampered signature", token: tamper(hs256(validClaims()), 2)},
+		{name: "tampered RS256 signature", token: tamper(sign(t, jwt.SigningMethodRS256, testRSAKid, keys.privateKey, validClaims()), 2)},
+		{name: "malformed", token: "not.a.token"},
+
+		{name: "invalid tenant_id", token: hs256(with(map[string]any{"tenant_id": "Acme Corp"}))},
+		{name: "tenant_id with a path", token: hs256(with(map[string]any{"tenant_id": "../acme"}))},
+	}
+
+	for _, tt := range tests {
+		principal, err := keys.verifier.Verify(tt.token, testNow)
+		if !tt.valid {
+			if !errors.Is(err, ErrUnauthenticated) {
+				t.Errorf("%s: got principal %+v and error %v, want %v", tt.name, principal, err, ErrUnauthenticated)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+
+		if principal.Admin != tt.admin || principal.Tenant != tt.tenant {
+			t.Errorf("%s: got principal %+v, want admin %v and tenant %q", tt.name, principal, tt.admin, tt.tenant)
+		}
+	}
+}
+
+func TestLoadKeySetRejectsWeakKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  map[string]string
+	}{
+		{name: "short secret", key: map[string]string{"kty": "oct", "k": base64.RawURLEncoding.EncodeToString([]byte("short"))}},
+		{name: "small modulus", key: map[string]string{"kty": "RSA", "n": base64.RawURLEncoding.EncodeToString(make([]byte, 128)), "e": "AQAB"}},
+		{name: "algorithm of another key type", key: map[string]string{"kty": "oct", "alg": algRS256, "k": base64.RawURLEncoding.EncodeToString(make([]byte, 32))}},
+		{name: "unsupported key type", key: map[string]string{"kty": "EC"}},
+	}
+
+	for _, tt := range tests {
+		data, _ := json.Marshal(map[string]any{"keys": []map[string]string{tt.key}})
+
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatalf("write key set: %v", err)
+		}
+
+		if _, err := LoadKeySet(path); err == nil {
+			t.Errorf("%s: key set was accepted", tt.name)
+		}
+	}
+}
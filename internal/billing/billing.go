@@ -0,0 +1,86 @@
+// Package billing computes the dates on which a subscription is charged.
+package billing
+
+import (
+	"subscription-aggregator/internal/models"
+	"time"
+)
+
+// MaxInterval is the largest number of billing periods between two charges,
+// ten years of monthly billing. It keeps the charge dates computable.
+const MaxInterval = 120
+
+// IsValidPeriod reports whether period is one of the supported billing periods.
+func IsValidPeriod(period string) bool {
+	switch period {
+	case models.BillingWeekly, models.BillingMonthly, models.BillingQuarterly, models.BillingYearly:
+		return true
+	}
+
+	return false
+}
+
+// ChargeDate returns the n-th charge date of a subscription, counting the
+// start date as charge zero. Each date is computed from the start date rather
+// than from the previous charge, so month lengths never make the anchor drift.
+func ChargeDate(sub *models.Subscription, n int) time.Time {
+	interval := sub.BillingInterval
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch sub.BillingPeriod {
+	case models.BillingWeekly:
+		return sub.StartDate.AddDate(0, 0, 7*interval*n)
+	case models.BillingQuarterly:
+		return sub.StartDate.AddDate(0, 3*interval*n, 0)
+	case models.BillingYearly:
+		return sub.StartDate.AddDate(interval*n, 0, 0)
+	default:
+		return sub.StartDate.AddDate(0, interval*n, 0)
+	}
+}
+
+// ChargeDates returns the charge dates of a subscription that fall into
+// [from, to). The subscription's end date is exclusive as well.
+func ChargeDates(sub *models.Subscription, from time.Time, to time.Time) []time.Time {
+	if sub.EndDate != nil && sub.EndDate.Before(to) {
+		to = *sub.EndDate
+	}
+
+	var dates []time.Time
+	for n := 0; ; n++ {
+		date := ChargeDate(sub, n)
+		if !date.Before(to) {
+			break
+		}
+
+		if !date.Before(from) {
+			dates = append(dates, date)
+		}
+	}
+
+	return dates
+}
+
+// MonthlyAmount spreads the price of a subscription evenly over the months of
+// its billing period, counting 52 weeks in a year.
+func MonthlyAmount(sub *models.Subscription) float64 {
+	interval := sub.BillingInterval
+	if interval < 1 {
+		interval = 1
+	}
+
+	price := float64(sub.Price) / float64(interval)
+
+	switch sub.BillingPeriod {
+	case models.BillingWeekly:
+		return price * 52 / 12
+	case models.BillingQuarterly:
+		return price / 3
+	case models.BillingYearly:
+		return price / 12
+	default:
+		return price
+	}
+}
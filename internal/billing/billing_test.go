@@ -0,0 +1,150 @@
+package billing
+
+import (
+	"math"
+	"subscription-aggregator/internal/models"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestChargeDates(t *testing.T) {
+	end := date(2025, time.October, 1)
+
+	tests := []struct {
+		name     string
+		period   string
+		interval int
+		end      *time.Time
+		from     time.Time
+		to       time.Time
+		want     []time.Time
+	}{
+		{
+			name: "monthly", period: models.BillingMonthly, interval: 1,
+			from: date(2025, time.January, 1), to: date(2025, time.April, 1),
+			want: []time.Time{date(2025, time.January, 1), date(2025, time.February, 1), date(2025, time.March, 1)},
+		},
+		{
+			name: "no interval counts as one", period: models.BillingMonthly,
+			from: date(2025, time.January, 1), to: date(2025, time.March, 1),
+			want: []time.Time{date(2025, time.January, 1), date(2025, time.February, 1)},
+		},
+		{
+			name: "every two months from a later window", period: models.BillingMonthly, interval: 2,
+			from: date(2025, time.February, 1), to: date(2025, time.August, 1),
+			want: []time.Time{date(2025, time.March, 1), date(2025, time.May, 1), date(2025, time.July, 1)},
+		},
+		{
+			name: "weekly across a month end", period: models.BillingWeekly, interval: 1,
+			from: date(2025, time.January, 20), to: date(2025, time.February, 12),
+			want: []time.Time{date(2025, time.January, 22), date(2025, time.January, 29), date(2025, time.February, 5)},
+		},
+		{
+			name: "every two weeks", period: models.BillingWeekly, interval: 2,
+			from: date(2025, time.January, 1), to: date(2025, time.February, 1),
+			want: []time.Time{date(2025, time.January, 1), date(2025, time.January, 15), date(2025, time.January, 29)},
+		},
+		{
+			name: "quarterly", period: models.BillingQuarterly, interval: 1,
+			from: date(2025, time.January, 1), to: date(2026, time.January, 1),
+			want: []time.Time{date(2025, time.January, 1), date(2025, time.April, 1), date(2025, time.July, 1), date(2025, time.October, 1)},
+		},
+		{
+			name: "yearly outside the window", period: models.BillingYearly, interval: 1,
+			from: date(2025, time.February, 1), to: date(2026, time.January, 1),
+		},
+		{
+			name: "every two years", period: models.BillingYearly, interval: 2,
+			from: date(2025, time.January, 1), to: date(2030, time.January, 1),
+			want: []time.Time{date(2025, time.January, 1), date(2027, time.January, 1), date(2029, time.January, 1)},
+		},
+		{
+			name: "end date is exclusive", period: models.BillingQuarterly, interval: 1, end: &end,
+			from: date(2025, time.January, 1), to: date(2026, time.January, 1),
+			want: []time.Time{date(2025, time.January, 1), date(2025, time.April, 1), date(2025, time.July, 1)},
+		},
+		{
+			name: "window before the start", period: models.BillingMonthly, interval: 1,
+			from: date(2024, time.January, 1), to: date(2024, time.December, 1),
+		},
+	}
+
+	for _, tt := range tests {
+		sub := &models.Subscription{
+			StartDate:       date(2025, time.January, 1),
+			EndDate:         tt.end,
+			BillingPeriod:   tt.period,
+			BillingInterval: tt.interval,
+		}
+
+		got := ChargeDates(sub, tt.from, tt.to)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+
+		for i := range got {
+			if !got[i].Equal(tt.want[i]) {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestChargeDateKeepsTheAnchor(t *testing.T) {
+	// Counting from the start date keeps the 31st for every month that has
+	// one, where adding a month to the previous charge would drift.
+	sub := &models.Subscription{StartDate: date(2025, time.January, 31), BillingPeriod: models.BillingMonthly, BillingInterval: 1}
+
+	if got := ChargeDate(sub, 2); !got.Equal(date(2025, time.March, 31)) {
+		t.Errorf("got %v, want 2025-03-31", got)
+	}
+
+	if got := ChargeDate(sub, 12); !got.Equal(date(2026, time.January, 31)) {
+		t.Errorf("got %v, want 2026-01-31", got)
+	}
+}
+
+func TestMonthlyAmount(t *testing.T) {
+	tests := []struct {
+		period   string
+		interval int
+		want     float64
+	}{
+		{period: models.BillingMonthly, interval: 1, want: 1200},
+		{period: models.BillingMonthly, interval: 3, want: 400},
+		{period: models.BillingWeekly, interval: 1, want: 5200},
+		{period: models.BillingWeekly, interval: 2, want: 2600},
+		{period: models.BillingQuarterly, interval: 1, want: 400},
+		{period: models.BillingYearly, interval: 1, want: 100},
+		{period: models.BillingYearly, interval: 2, want: 50},
+		{period: models.BillingMonthly, interval: 0, want: 1200},
+	}
+
+	for _, tt := range tests {
+		sub := &models.Subscription{Price: 1200, BillingPeriod: tt.period, BillingInterval: tt.interval}
+
+		if got := MonthlyAmount(sub); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s every %d: got %v, want %v", tt.period, tt.interval, got, tt.want)
+		}
+	}
+}
+
+func TestIsValidPeriod(t *testing.T) {
+	for _, period := range []string{models.BillingWeekly, models.BillingMonthly, models.BillingQuarterly, models.BillingYearly} {
+		if !IsValidPeriod(period) {
+			t.Errorf("%s: got invalid", period)
+		}
+	}
+
+	for _, period := range []string{"", "daily", "Monthly"} {
+		if IsValidPeriod(period) {
+			t.Errorf("%q: got valid", period)
+		}
+	}
+}
@@ -1,16 +1,75 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/caarlos0/env/v11"
 	_ "github.com/joho/godotenv/autoload"
 )
 
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingOTLP   = "otlp"
+)
+
 type Config struct {
+	StorageType      string `env:"STORAGE_TYPE" envDefault:"postgres"`
 	PostgresUser     string `env:"POSTGRES_USER"`
 	PostgresPassword string `env:"POSTGRES_PASSWORD"`
 	PostgresDB       string `env:"POSTGRES_DB"`
 	PostgresPort     string `env:"POSTGRES_PORT"`
 	PostgresHost     string `env:"POSTGRES_HOST"`
+	MigrateOnStartup bool   `env:"MIGRATE_ON_STARTUP" envDefault:"false"`
+	// PostgresAppRole is the role the storage switches to on every
+	// connection, so that row-level security applies even when POSTGRES_USER
+	// is a superuser. Empty keeps POSTGRES_USER, which then must not bypass
+	// row-level security either.
+	PostgresAppRole string `env:"POSTGRES_APP_ROLE" envDefault:"subscription_aggregator_app"`
+
+	PostgresMaxConns          int32         `env:"POSTGRES_MAX_CONNS" envDefault:"10"`
+	PostgresMinConns          int32         `env:"POSTGRES_MIN_CONNS" envDefault:"2"`
+	PostgresMaxConnLifetime   time.Duration `env:"POSTGRES_MAX_CONN_LIFETIME" envDefault:"1h"`
+	PostgresMaxConnIdleTime   time.Duration `env:"POSTGRES_MAX_CONN_IDLE_TIME" envDefault:"30m"`
+	PostgresHealthCheckPeriod time.Duration `env:"POSTGRES_HEALTH_CHECK_PERIOD" envDefault:"1m"`
+
+	// PurgeRetention is how long soft-deleted subscriptions are kept; zero
+	// keeps them forever.
+	PurgeRetention time.Duration `env:"PURGE_RETENTION" envDefault:"720h"`
+	// PurgeInterval must be positive whenever the purger runs.
+	PurgeInterval time.Duration `env:"PURGE_INTERVAL" envDefault:"1h"`
+
+	// IdempotencyTTL is how long the response to an Idempotency-Key is
+	// replayed; the purger removes older keys.
+	IdempotencyTTL time.Duration `env:"IDEMPOTENCY_TTL" envDefault:"24h"`
+
+	// AuthJWKSFile is a JSON Web Key Set with the keys that sign bearer
+	// tokens; without it authentication is turned off.
+	AuthJWKSFile string        `env:"AUTH_JWKS_FILE"`
+	AuthIssuer   string        `env:"AUTH_ISSUER"`
+	AuthAudience string        `env:"AUTH_AUDIENCE"`
+	AuthLeeway   time.Duration `env:"AUTH_LEEWAY" envDefault:"1m"`
+
+	// AuthAPIKeys accepts API keys next to bearer tokens; it turns
+	// authentication on by itself.
+	AuthAPIKeys bool `env:"AUTH_API_KEYS" envDefault:"false"`
+
+	// MetricsAddr is the address of the listener that serves /metrics apart
+	// from the API, so it can stay closed to clients; empty turns it off.
+	MetricsAddr string `env:"METRICS_ADDR" envDefault:":9090"`
+
+	// TracingExporter is where spans go: none, stdout or otlp. The OTLP
+	// exporter reads its endpoint from the standard OTEL_EXPORTER_OTLP_*
+	// variables.
+	TracingExporter    string  `env:"TRACING_EXPORTER" envDefault:"none"`
+	TracingSampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -18,5 +77,53 @@ func LoadConfig() (*Config, error) {
 	if err := env.Parse(&config); err != nil {
 		return nil, err
 	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
+
+func (c *Config) validate() error {
+	if (c.PurgeRetention > 0 || c.IdempotencyTTL > 0) && c.PurgeInterval <= 0 {
+		return errors.New("PURGE_INTERVAL must be positive while PURGE_RETENTION or IDEMPOTENCY_TTL is set")
+	}
+
+	if c.StorageType == StoragePostgres {
+		if err := c.validatePool(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validatePool rejects pool settings that pgxpool would refuse or, for the
+// durations, silently treat as "never".
+func (c *Config) validatePool() error {
+	if c.PostgresMaxConns <= 0 {
+		return errors.New("POSTGRES_MAX_CONNS must be positive")
+	}
+
+	if c.PostgresMinConns < 0 || c.PostgresMinConns > c.PostgresMaxConns {
+		return errors.New("POSTGRES_MIN_CONNS must be between 0 and POSTGRES_MAX_CONNS")
+	}
+
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{name: "POSTGRES_MAX_CONN_LIFETIME", value: c.PostgresMaxConnLifetime},
+		{name: "POSTGRES_MAX_CONN_IDLE_TIME", value: c.PostgresMaxConnIdleTime},
+		{name: "POSTGRES_HEALTH_CHECK_PERIOD", value: c.PostgresHealthCheckPeriod},
+	}
+
+	for _, duration := range durations {
+		if duration.value <= 0 {
+			return fmt.Errorf("%s must be positive", duration.name)
+		}
+	}
+
+	return nil
+}
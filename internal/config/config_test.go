@@ -0,0 +1,58 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func validConfig() Config {
+	return Config{
+		StorageType:               StoragePostgres,
+		PostgresMaxConns:          10,
+		PostgresMinConns:          2,
+		PostgresMaxConnLifetime:   time.Hour,
+		PostgresMaxConnIdleTime:   30 * time.Minute,
+		PostgresHealthCheckPeriod: time.Minute,
+		PurgeRetention:            720 * time.Hour,
+		PurgeInterval:             time.Hour,
+		IdempotencyTTL:            24 * time.Hour,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Config)
+		want   string
+	}{
+		{name: "defaults", change: func(*Config) {}},
+		{name: "min equals max", change: func(c *Config) { c.PostgresMinConns = c.PostgresMaxConns }},
+		{name: "no min", change: func(c *Config) { c.PostgresMinConns = 0 }},
+		{name: "zero max", change: func(c *Config) { c.PostgresMaxConns = 0; c.PostgresMinConns = 0 }, want: "POSTGRES_MAX_CONNS"},
+		{name: "min above max", change: func(c *Config) { c.PostgresMinConns = c.PostgresMaxConns + 1 }, want: "POSTGRES_MIN_CONNS"},
+		{name: "negative min", change: func(c *Config) { c.PostgresMinConns = -1 }, want: "POSTGRES_MIN_CONNS"},
+		{name: "zero lifetime", change: func(c *Config) { c.PostgresMaxConnLifetime = 0 }, want: "POSTGRES_MAX_CONN_LIFETIME"},
+		{name: "negative idle time", change: func(c *Config) { c.PostgresMaxConnIdleTime = -time.Second }, want: "POSTGRES_MAX_CONN_IDLE_TIME"},
+		{name: "zero health check period", change: func(c *Config) { c.PostgresHealthCheckPeriod = 0 }, want: "POSTGRES_HEALTH_CHECK_PERIOD"},
+		{name: "pool settings without postgres", change: func(c *Config) { c.StorageType = StorageMemory; c.PostgresMaxConns = 0 }},
+		{name: "no purge interval", change: func(c *Config) { c.PurgeInterval = 0 }, want: "PURGE_INTERVAL"},
+	}
+
+	for _, tt := range tests {
+		config := validConfig()
+		tt.change(&config)
+
+		err := config.validate()
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got error %v, want one about %s", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"errors"
+	"subscription-aggregator/internal/models"
+)
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// ErrBatchAborted is reported for the operations of an atomic batch that were
+// rolled back or never ran because another operation failed.
+var ErrBatchAborted = errors.New("batch aborted")
+
+// BatchOperation is one mutation of a batch: Subscription is set for create
+// and update, ID for delete.
+type BatchOperation struct {
+	Op           string
+	ID           string
+	Subscription *models.Subscription
+}
+
+// BatchResult is the outcome of the operation at the same index. On success
+// Subscription holds the stored row, except for deletes.
+type BatchResult struct {
+	Subscription *models.Subscription
+	Err          error
+}
+
+func IsBatchOp(op string) bool {
+	return op == BatchCreate || op == BatchUpdate || op == BatchDelete
+}
+
+// AbortBatch marks every operation of a failed atomic batch that has no
+// error of its own as aborted.
+func AbortBatch(results []BatchResult) {
+	for i := range results {
+		results[i].Subscription = nil
+		if results[i].Err == nil {
+			results[i].Err = ErrBatchAborted
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"subscription-aggregator/internal/models"
+	"time"
+)
+
+const (
+	GroupByService  = "service"
+	GroupByMonth    = "month"
+	GroupByYear     = "year"
+	GroupByCategory = "category"
+	// GroupByCurrency keeps the prices of different currencies apart. It is
+	// not a group_by value of the API: the HTTP layer adds it to check that a
+	// total is in a single currency.
+	GroupByCurrency = "currency"
+)
+
+// CostFilter selects the charges a cost query adds up. Empty ServiceNames
+// match every service; without GroupBy the result is a single group.
+type CostFilter struct {
+	UserID       string
+	ServiceNames []string
+	PeriodStart  time.Time
+	PeriodEnd    time.Time
+	GroupBy      []string
+}
+
+func IsGroupBy(dimension string) bool {
+	switch dimension {
+	case GroupByService, GroupByMonth, GroupByYear, GroupByCategory:
+		return true
+	}
+
+	return false
+}
+
+// GroupCharges adds monthly charges up along the requested dimensions, for
+// callers that already hold the per-month breakdown. Groups are ordered by
+// their dimensions in the order they were requested.
+func GroupCharges(charges []models.MonthlyCharge, groupBy []string) []models.CostGroup {
+	if len(groupBy) == 0 {
+		total := models.CostGroup{}
+		for _, charge := range charges {
+			total.TotalCost += charge.Amount
+		}
+
+		return []models.CostGroup{total}
+	}
+
+	var groups []models.CostGroup
+	index := make(map[models.CostGroup]int)
+
+	for _, charge := range charges {
+		key := models.CostGroup{}
+		for _, dimension := range groupBy {
+			switch dimension {
+			case GroupByService:
+				key.ServiceName = charge.ServiceName
+			case GroupByCategory:
+				key.Category = charge.Category
+			case GroupByMonth:
+				key.Month = charge.Month.Format("2006-01")
+			case GroupByYear:
+				key.Year = charge.Month.Year()
+			case GroupByCurrency:
+				key.Currency = charge.Currency
+			}
+		}
+
+		if i, ok := index[key]; ok {
+			groups[i].TotalCost += charge.Amount
+			continue
+		}
+
+		index[key] = len(groups)
+		key.TotalCost = charge.Amount
+		groups = append(groups, key)
+	}
+
+	slices.SortFunc(groups, func(a, b models.CostGroup) int {
+		for _, dimension := range groupBy {
+			var result int
+			switch dimension {
+			case GroupByService:
+				result = strings.Compare(a.ServiceName, b.ServiceName)
+			case GroupByCategory:
+				result = strings.Compare(a.Category, b.Category)
+			case GroupByMonth:
+				result = strings.Compare(a.Month, b.Month)
+			case GroupByYear:
+				result = cmp.Compare(a.Year, b.Year)
+			case GroupByCurrency:
+				result = strings.Compare(a.Currency, b.Currency)
+			}
+
+			if result != 0 {
+				return result
+			}
+		}
+
+		return 0
+	})
+
+	return groups
+}
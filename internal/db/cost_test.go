@@ -0,0 +1,67 @@
+package db
+
+import (
+	"subscription-aggregator/internal/models"
+	"testing"
+	"time"
+)
+
+func TestGroupCharges(t *testing.T) {
+	month := func(year int, m time.Month) time.Time {
+		return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	charges := []models.MonthlyCharge{
+		{Month: month(2024, time.December), ServiceName: "Okko", Category: "video", Currency: "RUB", Amount: 300},
+		{Month: month(2025, time.January), ServiceName: "Netflix", Category: "video", Currency: "RUB", Amount: 400},
+		{Month: month(2025, time.January), ServiceName: "Spotify", Category: "music", Currency: "USD", Amount: 10},
+		{Month: month(2025, time.February), ServiceName: "Netflix", Category: "video", Currency: "RUB", Amount: 400},
+	}
+
+	tests := []struct {
+		groupBy []string
+		want    []models.CostGroup
+	}{
+		{want: []models.CostGroup{{TotalCost: 1110}}},
+		{
+			groupBy: []string{GroupByService},
+			want:    []models.CostGroup{{ServiceName: "Netflix", TotalCost: 800}, {ServiceName: "Okko", TotalCost: 300}, {ServiceName: "Spotify", TotalCost: 10}},
+		},
+		{
+			groupBy: []string{GroupByYear, GroupByCategory},
+			want: []models.CostGroup{
+				{Year: 2024, Category: "video", TotalCost: 300},
+				{Year: 2025, Category: "music", TotalCost: 10},
+				{Year: 2025, Category: "video", TotalCost: 800},
+			},
+		},
+		{
+			groupBy: []string{GroupByMonth, GroupByCurrency},
+			want: []models.CostGroup{
+				{Month: "2024-12", Currency: "RUB", TotalCost: 300},
+				{Month: "2025-01", Currency: "RUB", TotalCost: 400},
+				{Month: "2025-01", Currency: "USD", TotalCost: 10},
+				{Month: "2025-02", Currency: "RUB", TotalCost: 400},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got := GroupCharges(charges, tt.groupBy)
+		if len(got) != len(tt.want) {
+			t.Errorf("%v: got %+v, want %+v", tt.groupBy, got, tt.want)
+			continue
+		}
+
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%v: got %+v, want %+v", tt.groupBy, got, tt.want)
+				break
+			}
+		}
+	}
+
+	if got := GroupCharges(nil, nil); len(got) != 1 || got[0].TotalCost != 0 {
+		t.Errorf("no charges: got %+v, want a single zero total", got)
+	}
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"strconv"
+	"subscription-aggregator/internal/models"
+	"time"
+)
+
+const (
+	SortStartDate   = "start_date"
+	SortPrice       = "price"
+	SortServiceName = "service_name"
+)
+
+const cursorDateLayout = "2006-01-02"
+
+// ListFilter describes one page of a subscription listing. An empty UserID
+// lists subscriptions of every user; soft-deleted subscriptions are only
+// listed with IncludeDeleted.
+type ListFilter struct {
+	UserID         string
+	IncludeDeleted bool
+	ServiceName    string
+	ActiveAt       *time.Time
+	MinPrice       *int
+	MaxPrice       *int
+	StartFrom      *time.Time
+	StartTo        *time.Time
+	EndFrom        *time.Time
+	EndTo          *time.Time
+
+	SortBy string
+	Desc   bool
+	Limit  int
+	Cursor *Cursor
+}
+
+// Cursor points at the last row of the previous page. Rows are ordered by
+// the sort field and then by ID, so (Value, ID) is unique.
+type Cursor struct {
+	SortBy string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Value  string `json:"v"`
+	ID     string `json:"id"`
+}
+
+func IsSortField(field string) bool {
+	switch field {
+	case SortStartDate, SortPrice, SortServiceName:
+		return true
+	}
+
+	return false
+}
+
+// NewCursor builds the cursor that continues a listing after sub.
+func NewCursor(sub *models.Subscription, sortBy string, desc bool) *Cursor {
+	cursor := &Cursor{SortBy: sortBy, Desc: desc, ID: sub.ID}
+
+	switch sortBy {
+	case SortPrice:
+		cursor.Value = strconv.Itoa(sub.Price)
+	case SortServiceName:
+		cursor.Value = sub.ServiceName
+	default:
+		cursor.Value = sub.StartDate.Format(cursorDateLayout)
+	}
+
+	return cursor
+}
+
+// Pivot returns a subscription carrying the cursor position in its sort
+// field, for backends that compare rows in Go.
+func (c *Cursor) Pivot() (*models.Subscription, error) {
+	pivot := &models.Subscription{ID: c.ID}
+
+	switch c.SortBy {
+	case SortPrice:
+		price, err := strconv.Atoi(c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidInput)
+		}
+		pivot.Price = price
+	case SortServiceName:
+		pivot.ServiceName = c.Value
+	default:
+		startDate, err := time.Parse(cursorDateLayout, c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidInput)
+		}
+		pivot.StartDate = startDate
+	}
+
+	return pivot, nil
+}
+
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidInput)
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || !IsSortField(cursor.SortBy) {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidInput)
+	}
+
+	// NewCursor only writes IDs in the canonical form; anything else was
+	// made up and must not reach the query.
+	if _, err := uuid.Parse(cursor.ID); err != nil || len(cursor.ID) != len(uuid.Nil.String()) {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidInput)
+	}
+
+	if _, err := cursor.Pivot(); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+// NewPage trims a result fetched with Limit+1 rows down to one page and sets
+// the cursor of the next page when more rows remain.
+func NewPage(subs []*models.Subscription, filter ListFilter, total int) *models.SubscriptionPage {
+	page := &models.SubscriptionPage{Items: subs, Total: total}
+	if page.Items == nil {
+		page.Items = []*models.Subscription{}
+	}
+
+	if len(page.Items) > filter.Limit {
+		page.Items = page.Items[:filter.Limit]
+		page.NextCursor = NewCursor(page.Items[len(page.Items)-1], filter.SortBy, filter.Desc).Encode()
+	}
+
+	return page
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"encoding/base64"
+	"errors"
+	"subscription-aggregator/internal/models"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	sub := &models.Subscription{
+		ID:          "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		ServiceName: "Yandex Plus",
+		Price:       400,
+		StartDate:   time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	for _, sortBy := range []string{SortStartDate, SortPrice, SortServiceName} {
+		for _, desc := range []bool{false, true} {
+			want := NewCursor(sub, sortBy, desc)
+
+			got, err := DecodeCursor(want.Encode())
+			if err != nil {
+				t.Errorf("%s desc %v: %v", sortBy, desc, err)
+				continue
+			}
+
+			if *got != *want {
+				t.Errorf("%s desc %v: got %+v, want %+v", sortBy, desc, got, want)
+			}
+		}
+	}
+}
+
+func TestDecodeCursorRejectsTampering(t *testing.T) {
+	encode := func(json string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(json))
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "not base64", value: "not a cursor!"},
+		{name: "not JSON", value: encode(`{"s":`)},
+		{name: "unknown sort field", value: encode(`{"s":"user_id","v":"x","id":"60601fee-2bf1-4721-ae6f-7636e79a0cba"}`)},
+		{name: "no ID", value: encode(`{"s":"price","v":"400"}`)},
+		{name: "ID not a UUID", value: encode(`{"s":"price","v":"400","id":"1' OR '1'='1"}`)},
+		{name: "ID as a URN", value: encode(`{"s":"price","v":"400","id":"urn:uuid:60601fee-2bf1-4721-ae6f-7636e79a0cba"}`)},
+		{name: "price not a number", value: encode(`{"s":"price","v":"cheap","id":"60601fee-2bf1-4721-ae6f-7636e79a0cba"}`)},
+		{name: "start date not a date", value: encode(`{"s":"start_date","v":"07-2025","id":"60601fee-2bf1-4721-ae6f-7636e79a0cba"}`)},
+	}
+
+	for _, tt := range tests {
+		if cursor, err := DecodeCursor(tt.value); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: got cursor %+v and error %v, want %v", tt.name, cursor, err, ErrInvalidInput)
+		}
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	subs := []*models.Subscription{
+		{ID: "60601fee-2bf1-4721-ae6f-7636e79a0cb1", Price: 100},
+		{ID: "60601fee-2bf1-4721-ae6f-7636e79a0cb2", Price: 200},
+		{ID: "60601fee-2bf1-4721-ae6f-7636e79a0cb3", Price: 300},
+	}
+
+	filter := ListFilter{SortBy: SortPrice, Limit: 2}
+
+	page := NewPage(subs, filter, 5)
+	if len(page.Items) != 2 || page.Total != 5 || page.NextCursor == "" {
+		t.Fatalf("got page %+v, want 2 items of 5 and a next cursor", page)
+	}
+
+	cursor, err := DecodeCursor(page.NextCursor)
+	if err != nil || cursor.ID != subs[1].ID || cursor.Value != "200" {
+		t.Errorf("got next cursor %+v and error %v, want one after %s", cursor, err, subs[1].ID)
+	}
+
+	if page := NewPage(subs[:2], filter, 2); page.NextCursor != "" {
+		t.Errorf("last page: got next cursor %q, want none", page.NextCursor)
+	}
+
+	if page := NewPage(nil, filter, 0); page.Items == nil {
+		t.Error("empty page: got nil items, want an empty list")
+	}
+}
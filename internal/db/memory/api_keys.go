@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
+	"time"
+)
+
+type apiKeyRecord struct {
+	key  models.APIKey
+	hash string
+}
+
+func (s *Storage) CreateAPIKey(ctx context.Context, key *models.APIKey, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.apiKeys[key.ID]; ok {
+		return fmt.Errorf("%w: api key %s already exists", db.ErrConflict, key.ID)
+	}
+
+	key.TenantID = tenant.ID(ctx)
+	key.CreatedAt = time.Now().UTC()
+	s.apiKeys[key.ID] = apiKeyRecord{key: copyAPIKey(key), hash: hash}
+
+	s.logger.Info("API key saved successfully", "ID", key.ID)
+
+	return nil
+}
+
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []models.APIKey{}
+	for _, record := range s.apiKeys {
+		if record.key.TenantID == tenant.ID(ctx) {
+			keys = append(keys, copyAPIKey(&record.key))
+		}
+	}
+
+	slices.SortFunc(keys, func(a, b models.APIKey) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
+
+	return keys, nil
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.apiKeys[id]
+	if !ok || record.key.TenantID != tenant.ID(ctx) {
+		s.logger.Error("Failed to find api key", "id", id)
+		return db.ErrNotFound
+	}
+
+	if record.key.RevokedAt == nil {
+		revokedAt := time.Now().UTC()
+		record.key.RevokedAt = &revokedAt
+		s.apiKeys[id] = record
+	}
+
+	s.logger.Info("API key revoked successfully", "ID", id)
+	return nil
+}
+
+func (s *Storage) RotateAPIKey(ctx context.Context, id string, prefix string, hash string) (*models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.apiKeys[id]
+	if !ok || record.key.TenantID != tenant.ID(ctx) || record.key.RevokedAt != nil {
+		s.logger.Error("Failed to find active api key", "id", id)
+		return nil, db.ErrNotFound
+	}
+
+	rotatedAt := time.Now().UTC()
+	record.key.Prefix = prefix
+	record.key.RotatedAt = &rotatedAt
+	record.hash = hash
+	s.apiKeys[id] = record
+
+	s.logger.Info("API key rotated successfully", "ID", id)
+
+	result := copyAPIKey(&record.key)
+	return &result, nil
+}
+
+func (s *Storage) UseAPIKey(ctx context.Context, hash string) (*models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, record := range s.apiKeys {
+		if record.hash != hash || record.key.RevokedAt != nil {
+			continue
+		}
+
+		now := time.Now().UTC()
+		if record.key.LastUsedAt == nil || now.Sub(*record.key.LastUsedAt) >= time.Minute {
+			record.key.LastUsedAt = &now
+			s.apiKeys[id] = record
+		}
+
+		result := copyAPIKey(&record.key)
+		return &result, nil
+	}
+
+	return nil, db.ErrNotFound
+}
+
+func copyAPIKey(key *models.APIKey) models.APIKey {
+	result := *key
+	result.Scopes = slices.Clone(key.Scopes)
+
+	return result
+}
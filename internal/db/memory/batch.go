@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"subscription-aggregator/internal/db"
+)
+
+// ApplyBatch holds the write lock for the whole batch. An atomic batch works
+// on the live maps and puts the saved state back when an operation fails.
+func (s *Storage) ApplyBatch(ctx context.Context, ops []db.BatchOperation, atomic bool) ([]db.BatchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tenant(ctx)
+
+	subscriptions := maps.Clone(t.subscriptions)
+	eventCount := len(t.events)
+
+	results := make([]db.BatchResult, len(ops))
+	for i, op := range ops {
+		err := s.applyOperation(ctx, op, &results[i])
+		if err == nil {
+			continue
+		}
+
+		results[i] = db.BatchResult{Err: err}
+		if atomic {
+			t.subscriptions = subscriptions
+			t.events = t.events[:eventCount]
+			db.AbortBatch(results)
+			s.logger.Warn("Atomic batch rolled back", "count", len(ops))
+			return results, nil
+		}
+	}
+
+	s.logger.Info("Batch applied successfully", "count", len(ops), "atomic", atomic)
+
+	return results, nil
+}
+
+func (s *Storage) applyOperation(ctx context.Context, op db.BatchOperation, result *db.BatchResult) error {
+	switch op.Op {
+	case db.BatchCreate:
+		if err := s.save(ctx, op.Subscription); err != nil {
+			return err
+		}
+	case db.BatchUpdate:
+		if err := s.update(ctx, op.Subscription); err != nil {
+			return err
+		}
+	case db.BatchDelete:
+		return s.delete(ctx, op.ID)
+	default:
+		return fmt.Errorf("%w: unknown batch operation %q", db.ErrInvalidInput, op.Op)
+	}
+
+	stored := copySubscription(op.Subscription)
+	result.Subscription = &stored
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"subscription-aggregator/internal/db"
+	"testing"
+)
+
+func TestApplyBatch(t *testing.T) {
+	for _, atomic := range []bool{true, false} {
+		storage, ctx := newTestStorage()
+		user := createTestUser(t, storage, ctx)
+
+		existing := newTestSubscription(user.ID)
+		if err := storage.Save(ctx, existing); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+
+		created := newTestSubscription(user.ID)
+		updated := newTestSubscription(user.ID)
+		updated.ID = existing.ID
+		updated.Price = 500
+		orphan := newTestSubscription(uuid.NewString())
+
+		ops := []db.BatchOperation{
+			{Op: db.BatchCreate, Subscription: created},
+			{Op: db.BatchUpdate, Subscription: updated},
+			{Op: db.BatchCreate, Subscription: orphan},
+			{Op: db.BatchDelete, ID: uuid.NewString()},
+		}
+
+		results, err := storage.ApplyBatch(ctx, ops, atomic)
+		if err != nil {
+			t.Fatalf("atomic %v: apply: %v", atomic, err)
+		}
+
+		want := []error{nil, nil, db.ErrUserNotFound, db.ErrNotFound}
+		if atomic {
+			// The first failure rolls back the operations before it and
+			// stops the ones after it.
+			want = []error{db.ErrBatchAborted, db.ErrBatchAborted, db.ErrUserNotFound, db.ErrBatchAborted}
+		}
+
+		for i, result := range results {
+			if !errors.Is(result.Err, want[i]) {
+				t.Errorf("atomic %v: operation %d: got %v, want %v", atomic, i, result.Err, want[i])
+			}
+
+			if (result.Subscription != nil) != (want[i] == nil) {
+				t.Errorf("atomic %v: operation %d: got subscription %+v", atomic, i, result.Subscription)
+			}
+		}
+
+		_, err = storage.GetByID(ctx, created.ID, false)
+		if atomic != errors.Is(err, db.ErrNotFound) {
+			t.Errorf("atomic %v: get created: %v", atomic, err)
+		}
+
+		wantPrice := 500
+		if atomic {
+			wantPrice = existing.Price
+		}
+
+		if got, _ := storage.GetByID(ctx, existing.ID, false); got.Price != wantPrice {
+			t.Errorf("atomic %v: got price %d, want %d", atomic, got.Price, wantPrice)
+		}
+
+		events, _ := storage.History(ctx, existing.ID)
+		if wantEvents := map[bool]int{true: 1, false: 2}[atomic]; len(events) != wantEvents {
+			t.Errorf("atomic %v: got %d events, want %d", atomic, len(events), wantEvents)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"subscription-aggregator/internal/audit"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"time"
+)
+
+// recordEvent appends a mutation to the audit log. The caller must hold the
+// write lock.
+func (s *Storage) recordEvent(ctx context.Context, subscriptionID string, action string, before *models.Subscription, after *models.Subscription) {
+	t := s.tenant(ctx)
+
+	event := models.SubscriptionEvent{
+		ID:             int64(len(t.events) + 1),
+		SubscriptionID: subscriptionID,
+		Action:         action,
+		Actor:          audit.Actor(ctx),
+		RequestID:      audit.RequestID(ctx),
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	if before != nil {
+		event.Before, _ = json.Marshal(before)
+	}
+
+	if after != nil {
+		event.After, _ = json.Marshal(after)
+	}
+
+	t.events = append(t.events, event)
+}
+
+func (s *Storage) History(ctx context.Context, subscriptionID string) ([]models.SubscriptionEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.tenant(ctx)
+
+	var events []models.SubscriptionEvent
+	for _, event := range t.events {
+		if event.SubscriptionID == subscriptionID {
+			events = append(events, event)
+		}
+	}
+
+	if len(events) == 0 {
+		return nil, db.ErrNotFound
+	}
+
+	return events, nil
+}
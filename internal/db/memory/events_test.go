@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"subscription-aggregator/internal/audit"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"testing"
+)
+
+func TestHistoryRecordsEveryMutation(t *testing.T) {
+	storage, ctx := newTestStorage()
+	user := createTestUser(t, storage, ctx)
+
+	ctx = audit.WithRequestID(audit.WithActor(ctx, "support"), "req-1")
+
+	sub := newTestSubscription(user.ID)
+	if err := storage.Save(ctx, sub); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	sub.Price = 500
+	if err := storage.Update(ctx, sub); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	if err := storage.Delete(ctx, sub.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	if err := storage.Restore(ctx, sub.ID); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	events, err := storage.History(ctx, sub.ID)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+
+	price := func(data json.RawMessage) int {
+		if data == nil {
+			return 0
+		}
+
+		var sub models.Subscription
+		if err := json.Unmarshal(data, &sub); err != nil {
+			t.Fatalf("decode %s: %v", data, err)
+		}
+
+		return sub.Price
+	}
+
+	tests := []struct {
+		action string
+		before int
+		after  int
+	}{
+		{action: audit.ActionCreated, after: 400},
+		{action: audit.ActionUpdated, before: 400, after: 500},
+		{action: audit.ActionDeleted, before: 500},
+		{action: audit.ActionRestored, before: 500, after: 500},
+	}
+
+	if len(events) != len(tests) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(tests), events)
+	}
+
+	for i, tt := range tests {
+		event := events[i]
+		if event.Action != tt.action {
+			t.Errorf("event %d: got action %s, want %s", i, event.Action, tt.action)
+		}
+
+		if event.SubscriptionID != sub.ID || event.Actor != "support" || event.RequestID != "req-1" {
+			t.Errorf("%s: got subscription %s, actor %s, request %s", tt.action, event.SubscriptionID, event.Actor, event.RequestID)
+		}
+
+		if got := price(event.Before); got != tt.before {
+			t.Errorf("%s: got price %d before, want %d", tt.action, got, tt.before)
+		}
+
+		if got := price(event.After); got != tt.after {
+			t.Errorf("%s: got price %d after, want %d", tt.action, got, tt.after)
+		}
+
+		if i > 0 && event.ID <= events[i-1].ID {
+			t.Errorf("%s: got ID %d after %d", tt.action, event.ID, events[i-1].ID)
+		}
+	}
+}
+
+func TestHistoryWithoutEvents(t *testing.T) {
+	storage, ctx := newTestStorage()
+	user := createTestUser(t, storage, ctx)
+
+	sub := newTestSubscription(user.ID)
+	if err := storage.Save(ctx, sub); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := storage.History(ctx, uuid.NewString()); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("unknown subscription: got %v, want %v", err, db.ErrNotFound)
+	}
+
+	events, err := storage.History(ctx, sub.ID)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+
+	if len(events) != 1 || events[0].Actor != audit.AnonymousActor {
+		t.Errorf("without an actor: got %+v, want one event by %s", events, audit.AnonymousActor)
+	}
+}
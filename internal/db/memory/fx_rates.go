@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"time"
+)
+
+type fxRateKey struct {
+	baseCurrency  string
+	quoteCurrency string
+	month         time.Time
+}
+
+func (s *Storage) SaveRates(ctx context.Context, rates []models.FXRate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.addTenant(ctx)
+
+	for _, rate := range rates {
+		t.fxRates[fxRateKey{baseCurrency: rate.BaseCurrency, quoteCurrency: rate.QuoteCurrency, month: rate.Month}] = rate
+	}
+
+	s.logger.Info("FX rates saved successfully", "count", len(rates))
+
+	return nil
+}
+
+func (s *Storage) ListRates(ctx context.Context) ([]models.FXRate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.tenant(ctx)
+
+	rates := make([]models.FXRate, 0, len(t.fxRates))
+	for _, rate := range t.fxRates {
+		rates = append(rates, rate)
+	}
+
+	slices.SortFunc(rates, func(a, b models.FXRate) int {
+		return cmp.Or(
+			strings.Compare(a.BaseCurrency, b.BaseCurrency),
+			strings.Compare(a.QuoteCurrency, b.QuoteCurrency),
+			a.Month.Compare(b.Month),
+		)
+	})
+
+	return rates, nil
+}
+
+func (s *Storage) GetRate(ctx context.Context, baseCurrency string, quoteCurrency string, month time.Time) (*models.FXRate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.tenant(ctx)
+
+	var found *models.FXRate
+	for key, rate := range t.fxRates {
+		if key.baseCurrency != baseCurrency || key.quoteCurrency != quoteCurrency || key.month.After(month) {
+			continue
+		}
+
+		if found == nil || rate.Month.After(found.Month) {
+			found = &rate
+		}
+	}
+
+	if found == nil {
+		return nil, db.ErrNotFound
+	}
+
+	return found, nil
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"time"
+)
+
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, key string, requestHash string, notBefore time.Time) (*models.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.addTenant(ctx)
+
+	if record, ok := t.idempotencyKeys[key]; ok && !record.CreatedAt.Before(notBefore) {
+		record.Response = slices.Clone(record.Response)
+		return &record, nil
+	}
+
+	t.idempotencyKeys[key] = models.IdempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	return nil, nil
+}
+
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, location string, response []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tenant(ctx)
+
+	record, ok := t.idempotencyKeys[key]
+	if !ok {
+		return db.ErrNotFound
+	}
+
+	record.StatusCode = statusCode
+	record.Location = location
+	record.Response = slices.Clone(response)
+	t.idempotencyKeys[key] = record
+
+	return nil
+}
+
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tenant(ctx)
+
+	if record, ok := t.idempotencyKeys[key]; ok && record.StatusCode == 0 {
+		delete(t.idempotencyKeys, key)
+	}
+
+	return nil
+}
+
+func (s *Storage) PurgeIdempotencyKeys(ctx context.Context, createdBefore time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for _, t := range s.tenants {
+		for key, record := range t.idempotencyKeys {
+			if record.CreatedAt.Before(createdBefore) {
+				delete(t.idempotencyKeys, key)
+				purged++
+			}
+		}
+	}
+
+	return purged, nil
+}
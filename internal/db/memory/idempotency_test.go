@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKeyLifecycle(t *testing.T) {
+	storage, ctx := newTestStorage()
+	notBefore := time.Now().Add(-time.Hour)
+
+	if record, err := storage.ReserveIdempotencyKey(ctx, "key", "hash", notBefore); err != nil || record != nil {
+		t.Fatalf("first reserve: got %+v, %v, want the key", record, err)
+	}
+
+	// While the first request runs the record has no status yet.
+	record, err := storage.ReserveIdempotencyKey(ctx, "key", "hash", notBefore)
+	if err != nil || record == nil || record.StatusCode != 0 {
+		t.Fatalf("reserve in progress: got %+v, %v, want a record without a status", record, err)
+	}
+
+	if err := storage.CompleteIdempotencyKey(ctx, "key", 201, "/subscriptions/1", []byte(`{"id":"1"}`)); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	// A completed key stays taken after a release.
+	if err := storage.ReleaseIdempotencyKey(ctx, "key"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+
+	record, err = storage.ReserveIdempotencyKey(ctx, "key", "other", notBefore)
+	if err != nil || record == nil {
+		t.Fatalf("reserve completed: got %+v, %v, want the stored record", record, err)
+	}
+
+	if record.RequestHash != "hash" || record.StatusCode != 201 || record.Location != "/subscriptions/1" || string(record.Response) != `{"id":"1"}` {
+		t.Errorf("reserve completed: got %+v", record)
+	}
+
+	// Past the TTL the key can be reserved again.
+	if record, err := storage.ReserveIdempotencyKey(ctx, "key", "other", time.Now().Add(time.Hour)); err != nil || record != nil {
+		t.Errorf("reserve expired: got %+v, %v, want the key", record, err)
+	}
+
+	if err := storage.ReleaseIdempotencyKey(ctx, "key"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+
+	if record, err := storage.ReserveIdempotencyKey(ctx, "key", "hash", notBefore); err != nil || record != nil {
+		t.Errorf("reserve released: got %+v, %v, want the key", record, err)
+	}
+
+	purged, err := storage.PurgeIdempotencyKeys(ctx, time.Now().Add(time.Hour))
+	if err != nil || purged != 1 {
+		t.Errorf("purge: got %d, %v, want 1", purged, err)
+	}
+}
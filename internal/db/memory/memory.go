@@ -0,0 +1,516 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"subscription-aggregator/internal/audit"
+	"subscription-aggregator/internal/billing"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
+	"sync"
+	"time"
+)
+
+type Storage struct {
+	mu sync.RWMutex
+	// tenants holds the data of every tenant apart. A tenant is only added
+	// by a write that stores something of its own, so reads for made-up
+	// tenant IDs do not grow the map.
+	tenants map[string]*tenantData
+	// apiKeys are shared, since a key is looked up before its tenant is
+	// known.
+	apiKeys map[string]apiKeyRecord
+	logger  *slog.Logger
+}
+
+// tenantData is everything one tenant owns. The methods of Storage only
+// reach the tenant of their context, except the purges and UseAPIKey.
+type tenantData struct {
+	subscriptions   map[string]models.Subscription
+	fxRates         map[fxRateKey]models.FXRate
+	users           map[string]models.User
+	services        map[string]models.Service
+	serviceAliases  map[string]string
+	events          []models.SubscriptionEvent
+	idempotencyKeys map[string]models.IdempotencyRecord
+}
+
+func New(logger *slog.Logger) *Storage {
+	logger.Info("Using in-memory storage")
+
+	return &Storage{
+		tenants: make(map[string]*tenantData),
+		apiKeys: make(map[string]apiKeyRecord),
+		logger:  logger,
+	}
+}
+
+// tenant returns the data of the tenant of ctx, or an empty tenant that is
+// not stored when it has none yet. Writes to the empty tenant are lost, so
+// only operations that find existing data first may write through it. The
+// caller must hold the read or the write lock.
+func (s *Storage) tenant(ctx context.Context) *tenantData {
+	if t, ok := s.tenants[tenant.ID(ctx)]; ok {
+		return t
+	}
+
+	return &tenantData{}
+}
+
+// addTenant returns the data of the tenant of ctx and adds the tenant when it
+// is new. The caller must hold the write lock.
+func (s *Storage) addTenant(ctx context.Context) *tenantData {
+	id := tenant.ID(ctx)
+	if t, ok := s.tenants[id]; ok {
+		return t
+	}
+
+	t := &tenantData{
+		subscriptions:   make(map[string]models.Subscription),
+		fxRates:         make(map[fxRateKey]models.FXRate),
+		users:           make(map[string]models.User),
+		services:        make(map[string]models.Service),
+		serviceAliases:  make(map[string]string),
+		idempotencyKeys: make(map[string]models.IdempotencyRecord),
+	}
+	s.tenants[id] = t
+
+	return t
+}
+
+func (s *Storage) Save(ctx context.Context, sub *models.Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.save(ctx, sub); err != nil {
+		s.logger.Error("Unable to save subscription", "error", err, "id", sub.ID)
+		return err
+	}
+
+	s.logger.Info("Subscription saved successfully", "ID", sub.ID)
+
+	return nil
+}
+
+func (s *Storage) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.delete(ctx, id); err != nil {
+		s.logger.Error("Failed to find subscription", "id", id)
+		return err
+	}
+
+	s.logger.Info("Subscription deleted successfully", "ID", id)
+	return nil
+}
+
+func (s *Storage) Restore(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tenant(ctx)
+
+	before, ok := t.subscriptions[id]
+	if !ok {
+		s.logger.Error("Failed to find subscription", "id", id)
+		return db.ErrNotFound
+	}
+
+	if before.DeletedAt == nil {
+		return fmt.Errorf("%w: subscription is not deleted", db.ErrConflict)
+	}
+
+	after := copySubscription(&before)
+	after.DeletedAt = nil
+	after.Version++
+	after.UpdatedAt = time.Now().UTC()
+
+	t.subscriptions[id] = after
+	s.recordEvent(ctx, id, audit.ActionRestored, &before, &after)
+
+	s.logger.Info("Subscription restored successfully", "ID", id)
+	return nil
+}
+
+func (s *Storage) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for _, t := range s.tenants {
+		for id, sub := range t.subscriptions {
+			if sub.DeletedAt != nil && sub.DeletedAt.Before(deletedBefore) {
+				delete(t.subscriptions, id)
+				purged++
+			}
+		}
+	}
+
+	return purged, nil
+}
+
+func (s *Storage) GetByID(ctx context.Context, id string, includeDeleted bool) (*models.Subscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.tenant(ctx)
+
+	sub, ok := t.subscriptions[id]
+	if !ok || (sub.DeletedAt != nil && !includeDeleted) {
+		s.logger.Error("Failed to find subscription", "id", id)
+		return nil, db.ErrNotFound
+	}
+
+	s.logger.Info("Subscription found successfully", "ID", id)
+
+	result := copySubscription(&sub)
+	return &result, nil
+}
+
+func (s *Storage) List(ctx context.Context, filter db.ListFilter) (*models.SubscriptionPage, error) {
+	var pivot *models.Subscription
+	if filter.Cursor != nil {
+		cursorPivot, err := filter.Cursor.Pivot()
+		if err != nil {
+			return nil, err
+		}
+		pivot = cursorPivot
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.tenant(ctx)
+
+	if _, ok := t.users[filter.UserID]; filter.UserID != "" && !ok {
+		s.logger.Error("Failed to find user", "user_id", filter.UserID)
+		return nil, db.ErrNotFound
+	}
+
+	var matched []*models.Subscription
+	for _, sub := range t.subscriptions {
+		if !matchesFilter(&sub, filter) {
+			continue
+		}
+
+		result := copySubscription(&sub)
+		matched = append(matched, &result)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return compareSubscriptions(matched[i], matched[j], filter) < 0
+	})
+
+	total := len(matched)
+
+	if pivot != nil {
+		matched = slices.DeleteFunc(matched, func(sub *models.Subscription) bool {
+			return compareSubscriptions(sub, pivot, filter) <= 0
+		})
+	}
+
+	if len(matched) > filter.Limit+1 {
+		matched = matched[:filter.Limit+1]
+	}
+
+	s.logger.Info("Subscriptions listed successfully", "user_id", filter.UserID, "count", len(matched))
+
+	return db.NewPage(matched, filter, total), nil
+}
+
+// Export collects the matching subscriptions under the read lock and calls fn
+// after releasing it, so a slow reader does not hold up writers.
+func (s *Storage) Export(ctx context.Context, filter db.ListFilter, fn func(*models.Subscription) error) error {
+	s.mu.RLock()
+
+	t := s.tenant(ctx)
+
+	if _, ok := t.users[filter.UserID]; filter.UserID != "" && !ok {
+		s.mu.RUnlock()
+		s.logger.Error("Failed to find user", "user_id", filter.UserID)
+		return db.ErrNotFound
+	}
+
+	var matched []*models.Subscription
+	for _, sub := range t.subscriptions {
+		if !matchesFilter(&sub, filter) {
+			continue
+		}
+
+		result := copySubscription(&sub)
+		matched = append(matched, &result)
+	}
+
+	s.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return compareSubscriptions(matched[i], matched[j], filter) < 0
+	})
+
+	for _, sub := range matched {
+		if err := fn(sub); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Info("Subscriptions exported successfully", "user_id", filter.UserID, "count", len(matched))
+
+	return nil
+}
+
+func (s *Storage) Update(ctx context.Context, sub *models.Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.update(ctx, sub); err != nil {
+		if errors.Is(err, db.ErrPreconditionFailed) {
+			s.logger.Warn("Subscription version mismatch", "id", sub.ID, "expected_version", sub.Version)
+			return err
+		}
+
+		s.logger.Error("Failed to find subscription for update", "id", sub.ID)
+		return err
+	}
+
+	s.logger.Info("Subscription updated successfully", "ID", sub.ID)
+
+	return nil
+}
+
+// SumTotalCost groups the monthly charges in Go; the charge dates follow the
+// same billing rules as the postgres query.
+func (s *Storage) SumTotalCost(ctx context.Context, filter db.CostFilter) ([]models.CostGroup, error) {
+	charges, err := s.MonthlyCharges(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GroupCharges(charges, filter.GroupBy), nil
+}
+
+func (s *Storage) MonthlyCharges(ctx context.Context, filter db.CostFilter) ([]models.MonthlyCharge, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.tenant(ctx)
+
+	type chargeKey struct {
+		month       time.Time
+		serviceName string
+		category    string
+		currency    string
+	}
+
+	matchesService := t.serviceFilter(filter.ServiceNames)
+
+	amounts := make(map[chargeKey]int)
+	for _, sub := range t.subscriptions {
+		if sub.UserID != filter.UserID || sub.DeletedAt != nil || !matchesService(&sub) {
+			continue
+		}
+
+		for _, date := range billing.ChargeDates(&sub, filter.PeriodStart, filter.PeriodEnd) {
+			key := chargeKey{
+				month:       time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC),
+				serviceName: sub.ServiceName,
+				category:    sub.Category,
+				currency:    sub.Currency,
+			}
+			amounts[key] += sub.Price
+		}
+	}
+
+	charges := make([]models.MonthlyCharge, 0, len(amounts))
+	for key, amount := range amounts {
+		charges = append(charges, models.MonthlyCharge{
+			Month:       key.month,
+			ServiceName: key.serviceName,
+			Category:    key.category,
+			Currency:    key.currency,
+			Amount:      amount,
+		})
+	}
+
+	slices.SortFunc(charges, func(a, b models.MonthlyCharge) int {
+		return cmp.Or(
+			a.Month.Compare(b.Month),
+			strings.Compare(a.ServiceName, b.ServiceName),
+			strings.Compare(a.Category, b.Category),
+			strings.Compare(a.Currency, b.Currency),
+		)
+	})
+
+	return charges, nil
+}
+
+// save, delete and update implement the mutations for callers that hold the
+// write lock.
+func (s *Storage) save(ctx context.Context, sub *models.Subscription) error {
+	t := s.tenant(ctx)
+
+	if _, ok := t.subscriptions[sub.ID]; ok {
+		return fmt.Errorf("%w: subscription %s already exists", db.ErrConflict, sub.ID)
+	}
+
+	if _, ok := t.users[sub.UserID]; !ok {
+		return db.ErrUserNotFound
+	}
+
+	t.resolveService(sub)
+
+	now := time.Now().UTC()
+	sub.DeletedAt = nil
+	sub.Version = 1
+	sub.CreatedAt = now
+	sub.UpdatedAt = now
+	t.subscriptions[sub.ID] = copySubscription(sub)
+	s.recordEvent(ctx, sub.ID, audit.ActionCreated, nil, sub)
+
+	return nil
+}
+
+func (s *Storage) delete(ctx context.Context, id string) error {
+	t := s.tenant(ctx)
+
+	before, ok := t.subscriptions[id]
+	if !ok || before.DeletedAt != nil {
+		return db.ErrNotFound
+	}
+
+	after := copySubscription(&before)
+	deletedAt := time.Now().UTC()
+	after.DeletedAt = &deletedAt
+	after.Version++
+	after.UpdatedAt = deletedAt
+
+	t.subscriptions[id] = after
+	s.recordEvent(ctx, id, audit.ActionDeleted, &before, nil)
+
+	return nil
+}
+
+func (s *Storage) update(ctx context.Context, sub *models.Subscription) error {
+	t := s.tenant(ctx)
+
+	before, ok := t.subscriptions[sub.ID]
+	if !ok || before.DeletedAt != nil {
+		return db.ErrNotFound
+	}
+
+	if sub.Version != 0 && sub.Version != before.Version {
+		return db.ErrPreconditionFailed
+	}
+
+	if _, ok := t.users[sub.UserID]; !ok {
+		return db.ErrUserNotFound
+	}
+
+	t.resolveService(sub)
+
+	sub.Version = before.Version + 1
+	sub.DeletedAt = nil
+	sub.CreatedAt = before.CreatedAt
+	sub.UpdatedAt = time.Now().UTC()
+	t.subscriptions[sub.ID] = copySubscription(sub)
+	s.recordEvent(ctx, sub.ID, audit.ActionUpdated, &before, sub)
+
+	return nil
+}
+
+func matchesFilter(sub *models.Subscription, filter db.ListFilter) bool {
+	if sub.DeletedAt != nil && !filter.IncludeDeleted {
+		return false
+	}
+
+	if filter.UserID != "" && sub.UserID != filter.UserID {
+		return false
+	}
+
+	if filter.ServiceName != "" && sub.ServiceName != filter.ServiceName {
+		return false
+	}
+
+	if filter.ActiveAt != nil {
+		if sub.StartDate.After(*filter.ActiveAt) || (sub.EndDate != nil && !sub.EndDate.After(*filter.ActiveAt)) {
+			return false
+		}
+	}
+
+	if filter.MinPrice != nil && sub.Price < *filter.MinPrice {
+		return false
+	}
+
+	if filter.MaxPrice != nil && sub.Price > *filter.MaxPrice {
+		return false
+	}
+
+	if filter.StartFrom != nil && sub.StartDate.Before(*filter.StartFrom) {
+		return false
+	}
+
+	if filter.StartTo != nil && sub.StartDate.After(*filter.StartTo) {
+		return false
+	}
+
+	if filter.EndFrom != nil && (sub.EndDate == nil || sub.EndDate.Before(*filter.EndFrom)) {
+		return false
+	}
+
+	if filter.EndTo != nil && (sub.EndDate == nil || sub.EndDate.After(*filter.EndTo)) {
+		return false
+	}
+
+	return true
+}
+
+// compareSubscriptions orders rows the same way the postgres backend does:
+// by the sort field, then by ID.
+func compareSubscriptions(a *models.Subscription, b *models.Subscription, filter db.ListFilter) int {
+	var result int
+	switch filter.SortBy {
+	case db.SortPrice:
+		result = cmp.Compare(a.Price, b.Price)
+	case db.SortServiceName:
+		result = strings.Compare(a.ServiceName, b.ServiceName)
+	default:
+		result = a.StartDate.Compare(b.StartDate)
+	}
+
+	if result == 0 {
+		result = strings.Compare(a.ID, b.ID)
+	}
+
+	if filter.Desc {
+		return -result
+	}
+
+	return result
+}
+
+func copySubscription(sub *models.Subscription) models.Subscription {
+	result := *sub
+	if sub.EndDate != nil {
+		endDate := *sub.EndDate
+		result.EndDate = &endDate
+	}
+
+	if sub.DeletedAt != nil {
+		deletedAt := *sub.DeletedAt
+		result.DeletedAt = &deletedAt
+	}
+
+	if sub.ServiceID != nil {
+		serviceID := *sub.ServiceID
+		result.ServiceID = &serviceID
+	}
+
+	return result
+}
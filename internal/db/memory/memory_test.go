@@ -0,0 +1,388 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
+	"testing"
+	"time"
+)
+
+// newTestStorage returns an empty storage and a context for one tenant.
+func newTestStorage() (*Storage, context.Context) {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil))), tenant.WithID(context.Background(), "acme")
+}
+
+func createTestUser(t *testing.T, storage *Storage, ctx context.Context) *models.User {
+	t.Helper()
+
+	user := &models.User{ID: uuid.NewString(), DisplayName: "Test", DefaultCurrency: models.DefaultCurrency, Timezone: "UTC"}
+	if err := storage.CreateUser(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	return user
+}
+
+func newTestSubscription(userID string) *models.Subscription {
+	return &models.Subscription{
+		ID:              uuid.NewString(),
+		ServiceName:     "Netflix",
+		Price:           400,
+		UserID:          userID,
+		StartDate:       time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+		BillingPeriod:   "monthly",
+		BillingInterval: 1,
+		Currency:        models.DefaultCurrency,
+	}
+}
+
+func TestSubscriptionLifecycle(t *testing.T) {
+	storage, ctx := newTestStorage()
+	user := createTestUser(t, storage, ctx)
+
+	sub := newTestSubscription(user.ID)
+	if err := storage.Save(ctx, sub); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if err := storage.Save(ctx, sub); !errors.Is(err, db.ErrConflict) {
+		t.Errorf("save twice: got %v, want %v", err, db.ErrConflict)
+	}
+
+	got, err := storage.GetByID(ctx, sub.ID, false)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	if got.ServiceName != sub.ServiceName || got.Price != sub.Price || got.UserID != user.ID {
+		t.Errorf("get: got %+v, want %+v", got, sub)
+	}
+
+	// The storage hands out copies, so callers cannot change stored rows.
+	got.Price = 1
+	if again, _ := storage.GetByID(ctx, sub.ID, false); again.Price != sub.Price {
+		t.Errorf("changing a returned subscription changed the stored price to %d", again.Price)
+	}
+
+	sub.Price = 500
+	if err := storage.Update(ctx, sub); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	if got, _ := storage.GetByID(ctx, sub.ID, false); got.Price != 500 {
+		t.Errorf("after update: got price %d, want 500", got.Price)
+	}
+
+	if err := storage.Delete(ctx, sub.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "get deleted", err: func() error { _, err := storage.GetByID(ctx, sub.ID, false); return err }()},
+		{name: "update deleted", err: storage.Update(ctx, sub)},
+		{name: "delete deleted", err: storage.Delete(ctx, sub.ID)},
+		{name: "get unknown", err: func() error { _, err := storage.GetByID(ctx, uuid.NewString(), false); return err }()},
+	}
+
+	for _, tt := range tests {
+		if !errors.Is(tt.err, db.ErrNotFound) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.err, db.ErrNotFound)
+		}
+	}
+}
+
+func TestReadsDoNotAddTenants(t *testing.T) {
+	storage, _ := newTestStorage()
+
+	sub := &models.Subscription{
+		ID:          uuid.NewString(),
+		ServiceName: "Netflix",
+		Price:       400,
+		UserID:      uuid.NewString(),
+		StartDate:   time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	for i := range 100 {
+		ctx := tenant.WithID(context.Background(), fmt.Sprintf("made-up-%d", i))
+
+		if _, err := storage.GetByID(ctx, sub.ID, false); !errors.Is(err, db.ErrNotFound) {
+			t.Fatalf("GetByID: got error %v, want %v", err, db.ErrNotFound)
+		}
+
+		if _, err := storage.List(ctx, db.ListFilter{Limit: 10}); err != nil {
+			t.Fatalf("List: %v", err)
+		}
+
+		if _, err := storage.SumTotalCost(ctx, db.CostFilter{UserID: sub.UserID}); err != nil {
+			t.Fatalf("SumTotalCost: %v", err)
+		}
+
+		_, _ = storage.History(ctx, sub.ID)
+		_, _ = storage.GetUser(ctx, sub.UserID)
+		_, _ = storage.ListUsers(ctx)
+		_, _ = storage.ListServices(ctx)
+		_, _ = storage.ListRates(ctx)
+		_ = storage.Update(ctx, sub)
+		_ = storage.Delete(ctx, sub.ID)
+		_ = storage.ReleaseIdempotencyKey(ctx, "key")
+
+		if err := storage.Save(ctx, sub); !errors.Is(err, db.ErrUserNotFound) {
+			t.Fatalf("Save: got error %v, want %v", err, db.ErrUserNotFound)
+		}
+
+		if _, err := storage.ApplyBatch(ctx, []db.BatchOperation{{Op: db.BatchCreate, Subscription: sub}}, true); err != nil {
+			t.Fatalf("ApplyBatch: %v", err)
+		}
+	}
+
+	if len(storage.tenants) != 0 {
+		t.Fatalf("got %d tenants after reads, want none", len(storage.tenants))
+	}
+
+	ctx := tenant.WithID(context.Background(), "acme")
+	if err := storage.CreateUser(ctx, &models.User{ID: sub.UserID, DisplayName: "Test"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	if err := storage.Save(ctx, sub); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if len(storage.tenants) != 1 {
+		t.Errorf("got %d tenants after writes for one tenant, want 1", len(storage.tenants))
+	}
+}
+
+func TestListWalksEveryPageOnce(t *testing.T) {
+	storage, ctx := newTestStorage()
+	user := createTestUser(t, storage, ctx)
+
+	// Equal prices and names make the ID decide the order within them.
+	for i, price := range []int{300, 100, 300, 200, 100, 300, 500} {
+		sub := newTestSubscription(user.ID)
+		sub.Price = price
+		sub.ServiceName = []string{"Netflix", "Okko"}[i%2]
+		sub.StartDate = sub.StartDate.AddDate(0, i%3, 0)
+		if err := storage.Save(ctx, sub); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	for _, sortBy := range []string{db.SortStartDate, db.SortPrice, db.SortServiceName} {
+		for _, desc := range []bool{false, true} {
+			filter := db.ListFilter{UserID: user.ID, SortBy: sortBy, Desc: desc, Limit: 3}
+
+			var walked []*models.Subscription
+			for pages := 0; ; pages++ {
+				if pages > 3 {
+					t.Fatalf("%s desc %v: more pages than rows", sortBy, desc)
+				}
+
+				page, err := storage.List(ctx, filter)
+				if err != nil {
+					t.Fatalf("%s desc %v: list: %v", sortBy, desc, err)
+				}
+
+				if page.Total != 7 {
+					t.Errorf("%s desc %v: got total %d, want 7", sortBy, desc, page.Total)
+				}
+
+				walked = append(walked, page.Items...)
+				if page.NextCursor == "" {
+					break
+				}
+
+				filter.Cursor, err = db.DecodeCursor(page.NextCursor)
+				if err != nil {
+					t.Fatalf("%s desc %v: decode cursor: %v", sortBy, desc, err)
+				}
+			}
+
+			if len(walked) != 7 {
+				t.Errorf("%s desc %v: walked %d subscriptions, want 7", sortBy, desc, len(walked))
+				continue
+			}
+
+			for i := 1; i < len(walked); i++ {
+				if compareSubscriptions(walked[i-1], walked[i], filter) >= 0 {
+					t.Errorf("%s desc %v: %s is listed after %s", sortBy, desc, walked[i].ID, walked[i-1].ID)
+				}
+			}
+		}
+	}
+}
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	storage, ctx := newTestStorage()
+	user := createTestUser(t, storage, ctx)
+
+	sub := newTestSubscription(user.ID)
+	if err := storage.Save(ctx, sub); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if err := storage.Restore(ctx, sub.ID); !errors.Is(err, db.ErrConflict) {
+		t.Errorf("restore live: got %v, want %v", err, db.ErrConflict)
+	}
+
+	if err := storage.Delete(ctx, sub.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	costFilter := db.CostFilter{
+		UserID:      user.ID,
+		PeriodStart: time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:   time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	count := func(includeDeleted bool) int {
+		t.Helper()
+
+		page, err := storage.List(ctx, db.ListFilter{UserID: user.ID, IncludeDeleted: includeDeleted, Limit: 10})
+		if err != nil {
+			t.Fatalf("list: %v", err)
+		}
+
+		return len(page.Items)
+	}
+
+	if got := count(false); got != 0 {
+		t.Errorf("list after delete: got %d subscriptions, want 0", got)
+	}
+
+	if got := count(true); got != 1 {
+		t.Errorf("list deleted: got %d subscriptions, want 1", got)
+	}
+
+	if groups, err := storage.SumTotalCost(ctx, costFilter); err != nil || groups[0].TotalCost != 0 {
+		t.Errorf("total cost after delete: got %+v, %v, want 0", groups, err)
+	}
+
+	deleted, err := storage.GetByID(ctx, sub.ID, true)
+	if err != nil {
+		t.Fatalf("get deleted: %v", err)
+	}
+
+	if deleted.DeletedAt == nil {
+		t.Errorf("get deleted: got no deleted_at")
+	}
+
+	if err := storage.Restore(ctx, sub.ID); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+
+	restored, err := storage.GetByID(ctx, sub.ID, false)
+	if err != nil {
+		t.Fatalf("get restored: %v", err)
+	}
+
+	if restored.DeletedAt != nil || restored.Version <= deleted.Version {
+		t.Errorf("restore: got deleted_at %v and version %d after %d", restored.DeletedAt, restored.Version, deleted.Version)
+	}
+
+	if groups, err := storage.SumTotalCost(ctx, costFilter); err != nil || groups[0].TotalCost != 400 {
+		t.Errorf("total cost after restore: got %+v, %v, want 400", groups, err)
+	}
+
+	if err := storage.Restore(ctx, uuid.NewString()); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("restore unknown: got %v, want %v", err, db.ErrNotFound)
+	}
+}
+
+func TestPurgeRemovesOnlyExpiredDeletes(t *testing.T) {
+	storage, ctx := newTestStorage()
+	user := createTestUser(t, storage, ctx)
+
+	var ids []string
+	for range 3 {
+		sub := newTestSubscription(user.ID)
+		if err := storage.Save(ctx, sub); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		ids = append(ids, sub.ID)
+	}
+
+	if err := storage.Delete(ctx, ids[0]); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	cutoff := time.Now().Add(time.Second)
+
+	if purged, err := storage.Purge(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Errorf("purge before the delete: got %d, %v, want 0", purged, err)
+	}
+
+	// Deleted after the cutoff, so still within its retention window.
+	deletedAt := cutoff.Add(time.Hour)
+	late := storage.tenant(ctx).subscriptions[ids[1]]
+	late.DeletedAt = &deletedAt
+	storage.tenant(ctx).subscriptions[ids[1]] = late
+
+	if purged, err := storage.Purge(ctx, cutoff); err != nil || purged != 1 {
+		t.Errorf("purge: got %d, %v, want 1", purged, err)
+	}
+
+	tests := []struct {
+		id   string
+		want error
+	}{
+		{id: ids[0], want: db.ErrNotFound},
+		{id: ids[1]},
+		{id: ids[2]},
+	}
+
+	for i, tt := range tests {
+		if _, err := storage.GetByID(ctx, tt.id, true); !errors.Is(err, tt.want) {
+			t.Errorf("subscription %d: got %v, want %v", i, err, tt.want)
+		}
+	}
+}
+
+func TestUpdateChecksVersion(t *testing.T) {
+	storage, ctx := newTestStorage()
+	user := createTestUser(t, storage, ctx)
+
+	sub := newTestSubscription(user.ID)
+	if err := storage.Save(ctx, sub); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	stored, _ := storage.GetByID(ctx, sub.ID, false)
+
+	update := copySubscription(stored)
+	update.Price = 500
+	if err := storage.Update(ctx, &update); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	if update.Version != stored.Version+1 {
+		t.Errorf("update: got version %d, want %d", update.Version, stored.Version+1)
+	}
+
+	// stored still carries the version before the update.
+	stale := copySubscription(stored)
+	stale.Price = 600
+	if err := storage.Update(ctx, &stale); !errors.Is(err, db.ErrPreconditionFailed) {
+		t.Errorf("stale update: got %v, want %v", err, db.ErrPreconditionFailed)
+	}
+
+	if got, _ := storage.GetByID(ctx, sub.ID, false); got.Price != 500 {
+		t.Errorf("after the stale update: got price %d, want 500", got.Price)
+	}
+
+	// A zero version skips the check.
+	stale.Version = 0
+	if err := storage.Update(ctx, &stale); err != nil {
+		t.Errorf("unconditional update: %v", err)
+	}
+}
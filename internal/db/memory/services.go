@@ -0,0 +1,212 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"time"
+)
+
+func (s *Storage) CreateService(ctx context.Context, svc *models.Service) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.addTenant(ctx)
+
+	if _, ok := t.services[svc.ID]; ok {
+		return fmt.Errorf("%w: service %s already exists", db.ErrConflict, svc.ID)
+	}
+
+	if err := t.claimServiceAliases(svc); err != nil {
+		s.logger.Error("Unable to save service", "error", err, "id", svc.ID)
+		return err
+	}
+
+	now := time.Now().UTC()
+	svc.CreatedAt = now
+	svc.UpdatedAt = now
+	svc.Aliases = sortedAliases(svc.Aliases)
+	t.services[svc.ID] = copyService(svc)
+
+	s.logger.Info("Service saved successfully", "ID", svc.ID)
+
+	return nil
+}
+
+func (s *Storage) GetService(ctx context.Context, id string) (*models.Service, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.tenant(ctx)
+
+	svc, ok := t.services[id]
+	if !ok {
+		s.logger.Error("Failed to find service", "id", id)
+		return nil, db.ErrNotFound
+	}
+
+	result := copyService(&svc)
+	return &result, nil
+}
+
+func (s *Storage) ListServices(ctx context.Context) ([]models.Service, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.tenant(ctx)
+
+	services := make([]models.Service, 0, len(t.services))
+	for _, svc := range t.services {
+		services = append(services, copyService(&svc))
+	}
+
+	slices.SortFunc(services, func(a, b models.Service) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.ID, b.ID))
+	})
+
+	return services, nil
+}
+
+func (s *Storage) UpdateService(ctx context.Context, svc *models.Service) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tenant(ctx)
+
+	before, ok := t.services[svc.ID]
+	if !ok {
+		s.logger.Error("Failed to find service for update", "id", svc.ID)
+		return db.ErrNotFound
+	}
+
+	t.releaseServiceAliases(svc.ID)
+	if err := t.claimServiceAliases(svc); err != nil {
+		// Put the old aliases back; they cannot collide with anything.
+		_ = t.claimServiceAliases(&before)
+		s.logger.Error("Failed to update service", "error", err, "id", svc.ID)
+		return err
+	}
+
+	svc.CreatedAt = before.CreatedAt
+	svc.UpdatedAt = time.Now().UTC()
+	svc.Aliases = sortedAliases(svc.Aliases)
+	t.services[svc.ID] = copyService(svc)
+
+	s.logger.Info("Service updated successfully", "ID", svc.ID)
+
+	return nil
+}
+
+func (s *Storage) DeleteService(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tenant(ctx)
+
+	if _, ok := t.services[id]; !ok {
+		s.logger.Error("Failed to find service", "id", id)
+		return db.ErrNotFound
+	}
+
+	t.releaseServiceAliases(id)
+	delete(t.services, id)
+
+	for subID, sub := range t.subscriptions {
+		if sub.ServiceID != nil && *sub.ServiceID == id {
+			sub.ServiceID = nil
+			t.subscriptions[subID] = sub
+		}
+	}
+
+	s.logger.Info("Service deleted successfully", "ID", id)
+	return nil
+}
+
+// resolveService links sub to the service its name resolves to.
+func (t *tenantData) resolveService(sub *models.Subscription) {
+	var found *models.Service
+	if id, ok := t.serviceAliases[db.ServiceKey(sub.ServiceName)]; ok {
+		svc := t.services[id]
+		found = &svc
+	}
+
+	db.ResolveService(sub, found)
+}
+
+// serviceFilter returns whether a subscription belongs to one of the named
+// services: it is linked to the catalog service a name resolves to, or its
+// own name has the same key. Without names every subscription belongs.
+func (t *tenantData) serviceFilter(names []string) func(sub *models.Subscription) bool {
+	if len(names) == 0 {
+		return func(*models.Subscription) bool { return true }
+	}
+
+	keys := make(map[string]bool, len(names))
+	ids := make(map[string]bool, len(names))
+	for _, name := range names {
+		key := db.ServiceKey(name)
+		keys[key] = true
+		if id, ok := t.serviceAliases[key]; ok {
+			ids[id] = true
+		}
+	}
+
+	return func(sub *models.Subscription) bool {
+		return (sub.ServiceID != nil && ids[*sub.ServiceID]) || keys[db.ServiceKey(sub.ServiceName)]
+	}
+}
+
+// claimServiceAliases registers the name and aliases of svc, or claims
+// nothing when one of them belongs to another service.
+func (t *tenantData) claimServiceAliases(svc *models.Service) error {
+	keys := []string{db.ServiceKey(svc.Name)}
+	for _, alias := range svc.Aliases {
+		keys = append(keys, db.ServiceKey(alias))
+	}
+
+	for _, key := range keys {
+		if owner, ok := t.serviceAliases[key]; ok && owner != svc.ID {
+			return fmt.Errorf("%w: %q is already used by service %s", db.ErrConflict, key, owner)
+		}
+	}
+
+	for _, key := range keys {
+		t.serviceAliases[key] = svc.ID
+	}
+
+	return nil
+}
+
+func (t *tenantData) releaseServiceAliases(id string) {
+	for key, owner := range t.serviceAliases {
+		if owner == id {
+			delete(t.serviceAliases, key)
+		}
+	}
+}
+
+func sortedAliases(aliases []string) []string {
+	result := slices.Clone(aliases)
+	if result == nil {
+		result = []string{}
+	}
+
+	slices.Sort(result)
+	return result
+}
+
+func copyService(svc *models.Service) models.Service {
+	result := *svc
+	result.Aliases = slices.Clone(svc.Aliases)
+
+	if svc.DefaultPrice != nil {
+		defaultPrice := *svc.DefaultPrice
+		result.DefaultPrice = &defaultPrice
+	}
+
+	return result
+}
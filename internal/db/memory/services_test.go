@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"github.com/google/uuid"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"testing"
+	"time"
+)
+
+func TestCostFilterResolvesServiceNames(t *testing.T) {
+	storage, ctx := newTestStorage()
+	user := createTestUser(t, storage, ctx)
+
+	svc := &models.Service{ID: uuid.NewString(), Name: "Netflix", Aliases: []string{"NFLX"}}
+	if err := storage.CreateService(ctx, svc); err != nil {
+		t.Fatalf("create service: %v", err)
+	}
+
+	// nflx resolves to Netflix; the Okko spellings are not in the catalog.
+	for i, name := range []string{"nflx", "Okko", " okko "} {
+		sub := newTestSubscription(user.ID)
+		sub.ServiceName = name
+		sub.Price = 100 * (i + 1)
+		if err := storage.Save(ctx, sub); err != nil {
+			t.Fatalf("save %s: %v", name, err)
+		}
+	}
+
+	// An alias added later still finds the subscriptions linked before.
+	svc.Aliases = append(svc.Aliases, "Нетфликс")
+	if err := storage.UpdateService(ctx, svc); err != nil {
+		t.Fatalf("update service: %v", err)
+	}
+
+	tests := []struct {
+		names []string
+		want  int
+	}{
+		{want: 600},
+		{names: []string{"Netflix"}, want: 100},
+		{names: []string{"netflix "}, want: 100},
+		{names: []string{"nflx"}, want: 100},
+		{names: []string{"НЕТФЛИКС"}, want: 100},
+		{names: []string{"OKKO"}, want: 500},
+		{names: []string{"NFLX", "okko"}, want: 600},
+		{names: []string{"Kion"}, want: 0},
+	}
+
+	for _, tt := range tests {
+		groups, err := storage.SumTotalCost(ctx, db.CostFilter{
+			UserID:       user.ID,
+			ServiceNames: tt.names,
+			PeriodStart:  time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+			PeriodEnd:    time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC),
+		})
+		if err != nil {
+			t.Fatalf("%v: %v", tt.names, err)
+		}
+
+		if groups[0].TotalCost != tt.want {
+			t.Errorf("%v: got %d, want %d", tt.names, groups[0].TotalCost, tt.want)
+		}
+	}
+}
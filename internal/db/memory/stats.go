@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"subscription-aggregator/internal/billing"
+	"subscription-aggregator/internal/models"
+	"time"
+)
+
+func (s *Storage) ServiceRevenue(ctx context.Context, at time.Time) ([]models.ServiceRevenue, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type revenueKey struct {
+		serviceName string
+		currency    string
+	}
+
+	revenues := make(map[revenueKey]*models.ServiceRevenue)
+	for _, t := range s.tenants {
+		for _, sub := range t.subscriptions {
+			if sub.DeletedAt != nil || sub.StartDate.After(at) || (sub.EndDate != nil && !sub.EndDate.After(at)) {
+				continue
+			}
+
+			key := revenueKey{serviceName: sub.ServiceName, currency: sub.Currency}
+			revenue, ok := revenues[key]
+			if !ok {
+				revenue = &models.ServiceRevenue{ServiceName: sub.ServiceName, Currency: sub.Currency}
+				revenues[key] = revenue
+			}
+
+			revenue.Active++
+			revenue.MonthlyRevenue += billing.MonthlyAmount(&sub)
+		}
+	}
+
+	result := make([]models.ServiceRevenue, 0, len(revenues))
+	for _, revenue := range revenues {
+		result = append(result, *revenue)
+	}
+
+	slices.SortFunc(result, func(a, b models.ServiceRevenue) int {
+		return cmp.Or(
+			strings.Compare(a.ServiceName, b.ServiceName),
+			strings.Compare(a.Currency, b.Currency),
+		)
+	})
+
+	return result, nil
+}
@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"subscription-aggregator/internal/audit"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"time"
+)
+
+func (s *Storage) CreateUser(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.addTenant(ctx)
+
+	if _, ok := t.users[user.ID]; ok {
+		return fmt.Errorf("%w: user %s already exists", db.ErrConflict, user.ID)
+	}
+
+	if err := t.checkEmail(user); err != nil {
+		s.logger.Error("Unable to save user", "error", err, "id", user.ID)
+		return err
+	}
+
+	now := time.Now().UTC()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	t.users[user.ID] = *user
+
+	s.logger.Info("User saved successfully", "ID", user.ID)
+
+	return nil
+}
+
+func (s *Storage) GetUser(ctx context.Context, id string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.tenant(ctx)
+
+	user, ok := t.users[id]
+	if !ok {
+		s.logger.Error("Failed to find user", "id", id)
+		return nil, db.ErrNotFound
+	}
+
+	return &user, nil
+}
+
+func (s *Storage) ListUsers(ctx context.Context) ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := s.tenant(ctx)
+
+	users := make([]models.User, 0, len(t.users))
+	for _, user := range t.users {
+		users = append(users, user)
+	}
+
+	slices.SortFunc(users, func(a, b models.User) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
+
+	return users, nil
+}
+
+func (s *Storage) UpdateUser(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tenant(ctx)
+
+	before, ok := t.users[user.ID]
+	if !ok {
+		s.logger.Error("Failed to find user for update", "id", user.ID)
+		return db.ErrNotFound
+	}
+
+	if err := t.checkEmail(user); err != nil {
+		s.logger.Error("Failed to update user", "error", err, "id", user.ID)
+		return err
+	}
+
+	user.CreatedAt = before.CreatedAt
+	user.UpdatedAt = time.Now().UTC()
+	t.users[user.ID] = *user
+
+	s.logger.Info("User updated successfully", "ID", user.ID)
+
+	return nil
+}
+
+// DeleteUser removes the subscriptions of the user as well, like the
+// cascading foreign key does in Postgres, and records a deleted event for
+// each live one.
+func (s *Storage) DeleteUser(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tenant(ctx)
+
+	if _, ok := t.users[id]; !ok {
+		s.logger.Error("Failed to find user", "id", id)
+		return db.ErrNotFound
+	}
+
+	delete(t.users, id)
+
+	for subID, sub := range t.subscriptions {
+		if sub.UserID != id {
+			continue
+		}
+
+		delete(t.subscriptions, subID)
+		if sub.DeletedAt == nil {
+			s.recordEvent(ctx, subID, audit.ActionDeleted, &sub, nil)
+		}
+	}
+
+	s.logger.Info("User deleted successfully", "ID", id)
+	return nil
+}
+
+// checkEmail fails with ErrConflict when another user has the same email,
+// ignoring case.
+func (t *tenantData) checkEmail(user *models.User) error {
+	if user.Email == "" {
+		return nil
+	}
+
+	for _, other := range t.users {
+		if other.ID != user.ID && strings.EqualFold(other.Email, user.Email) {
+			return fmt.Errorf("%w: email is already used by user %s", db.ErrConflict, other.ID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"errors"
+	"subscription-aggregator/internal/audit"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"testing"
+)
+
+func TestDeleteUserRecordsDeletedSubscriptions(t *testing.T) {
+	storage, ctx := newTestStorage()
+	user := createTestUser(t, storage, ctx)
+	other := createTestUser(t, storage, ctx)
+
+	live := newTestSubscription(user.ID)
+	deleted := newTestSubscription(user.ID)
+	kept := newTestSubscription(other.ID)
+	for _, sub := range []*models.Subscription{live, deleted, kept} {
+		if err := storage.Save(ctx, sub); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	if err := storage.Delete(ctx, deleted.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	ctx = audit.WithActor(ctx, "support")
+	if err := storage.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		id      string
+		actions []string
+	}{
+		{name: "live", id: live.ID, actions: []string{audit.ActionCreated, audit.ActionDeleted}},
+		// Its deleted event was recorded by the soft delete.
+		{name: "soft-deleted", id: deleted.ID, actions: []string{audit.ActionCreated, audit.ActionDeleted}},
+		{name: "of another user", id: kept.ID, actions: []string{audit.ActionCreated}},
+	}
+
+	for _, tt := range tests {
+		events, err := storage.History(ctx, tt.id)
+		if err != nil {
+			t.Fatalf("%s: history: %v", tt.name, err)
+		}
+
+		if len(events) != len(tt.actions) {
+			t.Errorf("%s: got %d events, want %v", tt.name, len(events), tt.actions)
+			continue
+		}
+
+		for i, action := range tt.actions {
+			if events[i].Action != action {
+				t.Errorf("%s: event %d: got %s, want %s", tt.name, i, events[i].Action, action)
+			}
+		}
+	}
+
+	last, _ := storage.History(ctx, live.ID)
+	if event := last[len(last)-1]; event.Actor != "support" || event.Before == nil || event.After != nil {
+		t.Errorf("deleted event: got %+v, want one by support with only a before snapshot", event)
+	}
+
+	for _, id := range []string{live.ID, deleted.ID} {
+		if _, err := storage.GetByID(ctx, id, true); !errors.Is(err, db.ErrNotFound) {
+			t.Errorf("subscription %s of the deleted user: got %v, want %v", id, err, db.ErrNotFound)
+		}
+	}
+
+	if _, err := storage.GetByID(ctx, kept.ID, false); err != nil {
+		t.Errorf("subscription of another user: %v", err)
+	}
+
+	if err := storage.DeleteUser(ctx, user.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("delete user twice: got %v, want %v", err, db.ErrNotFound)
+	}
+}
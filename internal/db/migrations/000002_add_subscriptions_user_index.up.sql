@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS subscriptions_user_id_start_date_idx ON subscriptions (user_id, start_date, id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS fx_rates;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS currency;
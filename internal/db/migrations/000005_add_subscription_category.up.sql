@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions
+    ADD COLUMN category VARCHAR(64) NOT NULL DEFAULT '';
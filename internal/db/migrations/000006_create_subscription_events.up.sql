@@ -0,0 +1,23 @@
+CREATE TABLE subscription_events (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id UUID NOT NULL,
+    action VARCHAR(16) NOT NULL CHECK (action IN ('created', 'updated', 'deleted')),
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(255) NOT NULL DEFAULT '',
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX subscription_events_subscription_id_idx ON subscription_events (subscription_id, id);
+
+-- The log is append-only: rows may be inserted but never changed.
+CREATE FUNCTION subscription_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'subscription_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER subscription_events_append_only
+    BEFORE UPDATE OR DELETE ON subscription_events
+    FOR EACH ROW EXECUTE FUNCTION subscription_events_append_only();
@@ -0,0 +1,18 @@
+-- The old constraint has no 'restored'; a restore brought a subscription back
+-- by changing it, so those events are kept as updates. The log is
+-- append-only, so its trigger is lifted for the rewrite.
+ALTER TABLE subscription_events DISABLE TRIGGER subscription_events_append_only;
+
+UPDATE subscription_events SET action = 'updated' WHERE action = 'restored';
+
+ALTER TABLE subscription_events ENABLE TRIGGER subscription_events_append_only;
+
+ALTER TABLE subscription_events
+    DROP CONSTRAINT subscription_events_action_check,
+    ADD CONSTRAINT subscription_events_action_check
+        CHECK (action IN ('created', 'updated', 'deleted'));
+
+DROP INDEX IF EXISTS subscriptions_deleted_at_idx;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,9 @@
+ALTER TABLE subscriptions
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX subscriptions_deleted_at_idx ON subscriptions (deleted_at) WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE subscription_events
+    DROP CONSTRAINT subscription_events_action_check,
+    ADD CONSTRAINT subscription_events_action_check
+        CHECK (action IN ('created', 'updated', 'deleted', 'restored'));
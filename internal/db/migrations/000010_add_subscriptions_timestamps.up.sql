@@ -0,0 +1,14 @@
+ALTER TABLE subscriptions
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- Existing rows take their timestamps from the audit log where it has them.
+UPDATE subscriptions s
+SET created_at = e.first_event,
+    updated_at = e.last_event
+FROM (
+    SELECT subscription_id, min(created_at) AS first_event, max(created_at) AS last_event
+    FROM subscription_events
+    GROUP BY subscription_id
+) e
+WHERE e.subscription_id = s.id;
@@ -0,0 +1 @@
+ALTER TABLE idempotency_keys ADD COLUMN location VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS subscriptions_service_id_idx;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS service_id;
+
+DROP TABLE IF EXISTS service_aliases;
+
+DROP TABLE IF EXISTS services;
@@ -0,0 +1,53 @@
+CREATE TABLE services (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    category VARCHAR(64) NOT NULL DEFAULT '',
+    default_price INT CHECK (default_price >= 0),
+    website VARCHAR(2048) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Every service owns its normalized name and aliases, so a name resolves to
+-- at most one service. alias_key is db.ServiceKey of alias.
+CREATE TABLE service_aliases (
+    alias_key VARCHAR(255) PRIMARY KEY,
+    service_id UUID NOT NULL REFERENCES services (id) ON DELETE CASCADE,
+    alias VARCHAR(255) NOT NULL,
+    is_name BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX service_aliases_service_id_idx ON service_aliases (service_id);
+
+ALTER TABLE subscriptions
+    ADD COLUMN service_id UUID REFERENCES services (id) ON DELETE SET NULL;
+
+CREATE INDEX subscriptions_service_id_idx ON subscriptions (service_id);
+
+-- Existing service names become one service per normalized name, called by
+-- its most common spelling, and their subscriptions move to that name.
+CREATE TEMPORARY TABLE service_backfill AS
+SELECT lower(regexp_replace(btrim(service_name), '\s+', ' ', 'g')) AS alias_key,
+       mode() WITHIN GROUP (ORDER BY btrim(service_name)) AS name,
+       COALESCE(mode() WITHIN GROUP (ORDER BY category) FILTER (WHERE category <> ''), '') AS category,
+       gen_random_uuid() AS id
+FROM subscriptions
+WHERE btrim(service_name) <> ''
+GROUP BY 1;
+
+INSERT INTO services (id, name, category)
+SELECT id, name, category FROM service_backfill;
+
+INSERT INTO service_aliases (alias_key, service_id, alias, is_name)
+SELECT alias_key, id, name, true FROM service_backfill;
+
+UPDATE subscriptions s
+SET service_id = a.service_id,
+    service_name = b.name,
+    version = CASE WHEN s.service_name <> b.name THEN s.version + 1 ELSE s.version END,
+    updated_at = CASE WHEN s.service_name <> b.name THEN now() ELSE s.updated_at END
+FROM service_aliases a
+JOIN service_backfill b ON b.id = a.service_id
+WHERE a.alias_key = lower(regexp_replace(btrim(s.service_name), '\s+', ' ', 'g'));
+
+DROP TABLE service_backfill;
@@ -0,0 +1,22 @@
+CREATE TABLE users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    display_name VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(320),
+    default_currency CHAR(3) NOT NULL DEFAULT 'RUB',
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX users_email_idx ON users (lower(email));
+
+-- Every user that already owns a subscription gets a row, so the foreign key
+-- holds for existing data.
+INSERT INTO users (id, created_at)
+SELECT user_id, min(created_at)
+FROM subscriptions
+GROUP BY user_id;
+
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_user_id_fkey
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
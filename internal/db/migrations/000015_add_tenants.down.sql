@@ -0,0 +1,89 @@
+DROP POLICY IF EXISTS tenant_isolation ON subscriptions;
+ALTER TABLE subscriptions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON subscription_events;
+ALTER TABLE subscription_events NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subscription_events DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON fx_rates;
+ALTER TABLE fx_rates NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE fx_rates DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON idempotency_keys;
+ALTER TABLE idempotency_keys NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE idempotency_keys DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON services;
+ALTER TABLE services NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE services DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON service_aliases;
+ALTER TABLE service_aliases NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE service_aliases DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON users;
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE users DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON api_keys;
+ALTER TABLE api_keys NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE api_keys DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS subscriptions_tenant_id_user_id_idx;
+
+-- Restoring the global keys fails when two tenants share an ID, a service
+-- name or an email.
+ALTER TABLE subscriptions
+    DROP CONSTRAINT subscriptions_user_id_fkey,
+    DROP CONSTRAINT subscriptions_service_id_fkey;
+
+ALTER TABLE service_aliases
+    DROP CONSTRAINT service_aliases_service_id_fkey,
+    DROP CONSTRAINT service_aliases_pkey,
+    ADD PRIMARY KEY (alias_key);
+
+ALTER TABLE services
+    DROP CONSTRAINT services_tenant_id_id_key;
+
+ALTER TABLE service_aliases
+    ADD CONSTRAINT service_aliases_service_id_fkey
+        FOREIGN KEY (service_id) REFERENCES services (id) ON DELETE CASCADE;
+
+DROP INDEX users_email_idx;
+CREATE UNIQUE INDEX users_email_idx ON users (lower(email));
+
+ALTER TABLE users
+    DROP CONSTRAINT users_pkey,
+    ADD PRIMARY KEY (id);
+
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_user_id_fkey
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    ADD CONSTRAINT subscriptions_service_id_fkey
+        FOREIGN KEY (service_id) REFERENCES services (id) ON DELETE SET NULL;
+
+ALTER TABLE fx_rates
+    DROP CONSTRAINT fx_rates_pkey,
+    ADD PRIMARY KEY (base_currency, quote_currency, month);
+
+ALTER TABLE idempotency_keys
+    DROP CONSTRAINT idempotency_keys_pkey,
+    ADD PRIMARY KEY (key);
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE subscription_events
+    DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE fx_rates
+    DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE idempotency_keys
+    DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE services
+    DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE service_aliases
+    DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE api_keys
+    DROP COLUMN IF EXISTS tenant_id;
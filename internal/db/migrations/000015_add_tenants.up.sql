@@ -0,0 +1,123 @@
+-- Every row belongs to a tenant; rows that exist already go to the default
+-- tenant. The storage always names the tenant on insert, so the columns keep
+-- no default.
+ALTER TABLE subscriptions
+    ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE subscription_events
+    ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE fx_rates
+    ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE idempotency_keys
+    ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE services
+    ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE service_aliases
+    ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE users
+    ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys
+    ADD COLUMN tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+ALTER TABLE subscriptions
+    ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE subscription_events
+    ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE fx_rates
+    ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE idempotency_keys
+    ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE services
+    ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE service_aliases
+    ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE users
+    ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE api_keys
+    ALTER COLUMN tenant_id DROP DEFAULT;
+
+-- Keys and unique names are unique per tenant, and references cannot cross
+-- tenants.
+ALTER TABLE subscriptions
+    DROP CONSTRAINT subscriptions_user_id_fkey,
+    DROP CONSTRAINT subscriptions_service_id_fkey;
+
+ALTER TABLE service_aliases
+    DROP CONSTRAINT service_aliases_service_id_fkey;
+
+ALTER TABLE users
+    DROP CONSTRAINT users_pkey,
+    ADD PRIMARY KEY (tenant_id, id);
+
+DROP INDEX users_email_idx;
+CREATE UNIQUE INDEX users_email_idx ON users (tenant_id, lower(email));
+
+ALTER TABLE services
+    ADD CONSTRAINT services_tenant_id_id_key UNIQUE (tenant_id, id);
+
+ALTER TABLE service_aliases
+    DROP CONSTRAINT service_aliases_pkey,
+    ADD PRIMARY KEY (tenant_id, alias_key),
+    ADD CONSTRAINT service_aliases_service_id_fkey
+        FOREIGN KEY (tenant_id, service_id) REFERENCES services (tenant_id, id) ON DELETE CASCADE;
+
+ALTER TABLE subscriptions
+    ADD CONSTRAINT subscriptions_user_id_fkey
+        FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE,
+    ADD CONSTRAINT subscriptions_service_id_fkey
+        FOREIGN KEY (tenant_id, service_id) REFERENCES services (tenant_id, id) ON DELETE SET NULL (service_id);
+
+ALTER TABLE fx_rates
+    DROP CONSTRAINT fx_rates_pkey,
+    ADD PRIMARY KEY (tenant_id, base_currency, quote_currency, month);
+
+ALTER TABLE idempotency_keys
+    DROP CONSTRAINT idempotency_keys_pkey,
+    ADD PRIMARY KEY (tenant_id, key);
+
+CREATE INDEX subscriptions_tenant_id_user_id_idx ON subscriptions (tenant_id, user_id);
+
+-- Row-level security limits every connection to the tenant it names in
+-- app.tenant_id, which the application sets locally in every transaction;
+-- app.all_tenants lifts the limit for purges and API key lookups. FORCE
+-- applies the policies to the owner of the tables as well, so later data
+-- migrations have to set one of the two. Superusers and roles with BYPASSRLS
+-- are never limited.
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON subscriptions
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.all_tenants', true) = 'on');
+
+ALTER TABLE subscription_events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscription_events FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON subscription_events
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.all_tenants', true) = 'on');
+
+ALTER TABLE fx_rates ENABLE ROW LEVEL SECURITY;
+ALTER TABLE fx_rates FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON fx_rates
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.all_tenants', true) = 'on');
+
+ALTER TABLE idempotency_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE idempotency_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON idempotency_keys
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.all_tenants', true) = 'on');
+
+ALTER TABLE services ENABLE ROW LEVEL SECURITY;
+ALTER TABLE services FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON services
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.all_tenants', true) = 'on');
+
+ALTER TABLE service_aliases ENABLE ROW LEVEL SECURITY;
+ALTER TABLE service_aliases FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON service_aliases
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.all_tenants', true) = 'on');
+
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON users
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.all_tenants', true) = 'on');
+
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON api_keys
+    USING (tenant_id = current_setting('app.tenant_id', true) OR current_setting('app.all_tenants', true) = 'on');
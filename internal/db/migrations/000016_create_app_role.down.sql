@@ -0,0 +1,23 @@
+REVOKE ALL ON
+    subscriptions,
+    subscription_events,
+    fx_rates,
+    idempotency_keys,
+    services,
+    service_aliases,
+    users,
+    api_keys
+FROM subscription_aggregator_app;
+
+REVOKE ALL ON SEQUENCE subscription_events_id_seq FROM subscription_aggregator_app;
+REVOKE USAGE ON SCHEMA public FROM subscription_aggregator_app;
+
+-- Other databases of the cluster may still grant the role privileges; it is
+-- then left in place.
+DO $$
+BEGIN
+    DROP ROLE subscription_aggregator_app;
+EXCEPTION WHEN dependent_objects_still_exist THEN
+    NULL;
+END
+$$;
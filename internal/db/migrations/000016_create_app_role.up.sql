@@ -0,0 +1,32 @@
+-- The service runs as this role (see POSTGRES_APP_ROLE) instead of the role
+-- that owns the tables: row-level security never limits superusers or roles
+-- with BYPASSRLS, so the policies of 000015 only hold for a role like this.
+-- Roles belong to the whole cluster, so another database may have created it.
+DO $$
+BEGIN
+    CREATE ROLE subscription_aggregator_app NOLOGIN NOSUPERUSER NOBYPASSRLS NOCREATEDB NOCREATEROLE;
+EXCEPTION WHEN duplicate_object THEN
+    NULL;
+END
+$$;
+
+GRANT USAGE ON SCHEMA public TO subscription_aggregator_app;
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON
+    subscriptions,
+    fx_rates,
+    idempotency_keys,
+    services,
+    service_aliases,
+    users,
+    api_keys
+TO subscription_aggregator_app;
+
+-- The audit log is append-only.
+GRANT SELECT, INSERT ON subscription_events TO subscription_aggregator_app;
+GRANT USAGE ON SEQUENCE subscription_events_id_seq TO subscription_aggregator_app;
+
+-- The service logs in as POSTGRES_USER and then switches to this role, which
+-- needs membership unless POSTGRES_USER is a superuser. The migrations run as
+-- that same user by default.
+GRANT subscription_aggregator_app TO CURRENT_USER;
@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"errors"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"io/fs"
+	"testing"
+)
+
+// TestMigrationsArePaired checks that the embedded migrations are numbered
+// without gaps from 1 and that each one can be rolled back.
+func TestMigrationsArePaired(t *testing.T) {
+	source, err := iofs.New(FS, ".")
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	defer source.Close()
+
+	files, err := fs.Glob(FS, "*.sql")
+	if err != nil {
+		t.Fatalf("list migrations: %v", err)
+	}
+
+	want := uint(1)
+	version, err := source.First()
+	for err == nil {
+		if version != want {
+			t.Errorf("got migration %d, want %d", version, want)
+		}
+
+		if up, _, err := source.ReadUp(version); err != nil {
+			t.Errorf("migration %d has no up file: %v", version, err)
+		} else {
+			up.Close()
+		}
+
+		if down, _, err := source.ReadDown(version); err != nil {
+			t.Errorf("migration %d has no down file: %v", version, err)
+		} else {
+			down.Close()
+		}
+
+		want = version + 1
+		version, err = source.Next(version)
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("list migrations: %v", err)
+	}
+
+	if count := int(want - 1); count*2 != len(files) {
+		t.Errorf("got %d SQL files for %d migrations, want an up and a down file each", len(files), count)
+	}
+}
@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
+)
+
+const apiKeyColumns = `id, name, prefix, scopes, created_at, rotated_at, last_used_at, revoked_at, tenant_id`
+
+func (s *Storage) CreateAPIKey(ctx context.Context, key *models.APIKey, hash string) error {
+	sql := `
+      INSERT INTO api_keys (id, name, prefix, key_hash, scopes, tenant_id) VALUES ($1, $2, $3, $4, $5, $6)
+      RETURNING ` + apiKeyColumns
+
+	saved, err := scanAPIKey(s.database.QueryRow(ctx, sql, key.ID, key.Name, key.Prefix, hash, key.Scopes, tenant.ID(ctx)))
+	if err != nil {
+		s.logger.Error("Unable to save api key", "error", err, "id", key.ID)
+		return fmt.Errorf("unable to save api key: %w", mapError(err))
+	}
+
+	*key = *saved
+
+	s.logger.Info("API key saved successfully", "ID", key.ID)
+
+	return nil
+}
+
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	sql := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE tenant_id = $1 ORDER BY created_at, id`
+
+	rows, err := s.database.Query(ctx, sql, tenant.ID(ctx))
+	if err != nil {
+		s.logger.Error("Failed to list api keys", "error", err)
+		return nil, fmt.Errorf("failed to list api keys: %w", mapError(err))
+	}
+
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			s.logger.Error("Failed to scan api key row", "error", err)
+			return nil, err
+		}
+
+		keys = append(keys, *key)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error rows iterations", "error", err)
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, id string) error {
+	sql := `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now()) WHERE id = $1 AND tenant_id = $2`
+
+	result, err := s.database.Exec(ctx, sql, id, tenant.ID(ctx))
+	if err != nil {
+		s.logger.Error("Failed to revoke api key", "error", err)
+		return fmt.Errorf("failed to revoke api key: %w", mapError(err))
+	}
+
+	if result.RowsAffected() == 0 {
+		s.logger.Error("Failed to find api key", "id", id)
+		return db.ErrNotFound
+	}
+
+	s.logger.Info("API key revoked successfully", "ID", id)
+	return nil
+}
+
+func (s *Storage) RotateAPIKey(ctx context.Context, id string, prefix string, hash string) (*models.APIKey, error) {
+	sql := `
+      UPDATE api_keys SET key_hash = $1, prefix = $2, rotated_at = now() WHERE id = $3 AND tenant_id = $4 AND revoked_at IS NULL
+      RETURNING ` + apiKeyColumns
+
+	key, err := scanAPIKey(s.database.QueryRow(ctx, sql, hash, prefix, id, tenant.ID(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Error("Failed to find active api key", "id", id)
+			return nil, db.ErrNotFound
+		}
+
+		s.logger.Error("Failed to rotate api key", "error", err)
+		return nil, fmt.Errorf("failed to rotate api key: %w", mapError(err))
+	}
+
+	s.logger.Info("API key rotated successfully", "ID", id)
+
+	return key, nil
+}
+
+// UseAPIKey looks in every tenant, since the key is what names the tenant of
+// the request.
+func (s *Storage) UseAPIKey(ctx context.Context, hash string) (*models.APIKey, error) {
+	sql := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+
+	ctx = withAllTenants(ctx)
+
+	key, err := scanAPIKey(s.database.QueryRow(ctx, sql, hash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, db.ErrNotFound
+		}
+
+		s.logger.Error("Failed to get api key", "error", err)
+		return nil, fmt.Errorf("failed to get api key: %w", mapError(err))
+	}
+
+	// Writing on every request would turn each read into a write, so the
+	// timestamp is only moved once a minute.
+	touchSQL := `
+      UPDATE api_keys SET last_used_at = now()
+      WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`
+
+	if _, err := s.database.Exec(ctx, touchSQL, key.ID); err != nil {
+		s.logger.Warn("Failed to record api key use", "error", err, "id", key.ID)
+	}
+
+	return key, nil
+}
+
+func scanAPIKey(row pgx.Row) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		&key.Scopes,
+		&key.CreatedAt,
+		&key.RotatedAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.TenantID,
+	); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
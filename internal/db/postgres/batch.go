@@ -0,0 +1,293 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"subscription-aggregator/internal/audit"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
+	"time"
+)
+
+// ApplyBatch runs the whole batch in one transaction. An atomic batch loads
+// each run of consecutive creates with COPY; a best-effort batch wraps every
+// operation in a savepoint so a failure only undoes that operation.
+func (s *Storage) ApplyBatch(ctx context.Context, ops []db.BatchOperation, atomic bool) ([]db.BatchResult, error) {
+	results := make([]db.BatchResult, len(ops))
+
+	tx, err := s.database.Begin(ctx)
+	if err != nil {
+		s.logger.Error("Unable to begin batch", "error", err)
+		return nil, fmt.Errorf("unable to begin batch: %w", mapError(err))
+	}
+
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if atomic {
+		if !applyAtomic(ctx, tx, ops, results) {
+			db.AbortBatch(results)
+			s.logger.Warn("Atomic batch rolled back", "count", len(ops))
+			return results, nil
+		}
+	} else {
+		if err := applyBestEffort(ctx, tx, ops, results); err != nil {
+			s.logger.Error("Unable to apply batch", "error", err)
+			return nil, fmt.Errorf("unable to apply batch: %w", mapError(err))
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		s.logger.Error("Unable to commit batch", "error", err)
+		return nil, fmt.Errorf("unable to commit batch: %w", mapError(err))
+	}
+
+	s.logger.Info("Batch applied successfully", "count", len(ops), "atomic", atomic)
+
+	return results, nil
+}
+
+// applyAtomic stops at the first failing operation and reports whether every
+// operation succeeded.
+func applyAtomic(ctx context.Context, tx pgx.Tx, ops []db.BatchOperation, results []db.BatchResult) bool {
+	for i := 0; i < len(ops); {
+		if ops[i].Op == db.BatchCreate {
+			end := i
+			for end < len(ops) && ops[end].Op == db.BatchCreate {
+				end++
+			}
+
+			if !applyCreates(ctx, tx, ops[i:end], results[i:end]) {
+				return false
+			}
+
+			i = end
+			continue
+		}
+
+		if err := applyOperation(ctx, tx, ops[i], &results[i]); err != nil {
+			results[i].Err = mapError(err)
+			return false
+		}
+
+		i++
+	}
+
+	return true
+}
+
+// applyCreates loads a run of creates with COPY inside a savepoint. COPY does
+// not say which row failed, so after a failure the run is replayed with one
+// INSERT per row to pin the error on the right operation.
+func applyCreates(ctx context.Context, tx pgx.Tx, ops []db.BatchOperation, results []db.BatchResult) bool {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		results[0].Err = mapError(err)
+		return false
+	}
+
+	if err := copySubscriptions(ctx, savepoint, ops); err == nil {
+		if err := savepoint.Commit(ctx); err != nil {
+			results[0].Err = mapError(err)
+			return false
+		}
+
+		for i := range ops {
+			results[i].Subscription = ops[i].Subscription
+		}
+
+		return true
+	}
+
+	if err := savepoint.Rollback(ctx); err != nil {
+		results[0].Err = mapError(err)
+		return false
+	}
+
+	for i := range ops {
+		if err := applyOperation(ctx, tx, ops[i], &results[i]); err != nil {
+			results[i].Err = mapError(err)
+			return false
+		}
+	}
+
+	return true
+}
+
+// applyBestEffort runs every operation in its own savepoint. Only a failure
+// of the transaction itself is returned.
+func applyBestEffort(ctx context.Context, tx pgx.Tx, ops []db.BatchOperation, results []db.BatchResult) error {
+	for i, op := range ops {
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := applyOperation(ctx, savepoint, op, &results[i]); err != nil {
+			results[i] = db.BatchResult{Err: mapError(err)}
+			if err := savepoint.Rollback(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := savepoint.Commit(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applyOperation(ctx context.Context, tx pgx.Tx, op db.BatchOperation, result *db.BatchResult) error {
+	switch op.Op {
+	case db.BatchCreate:
+		if err := insertSubscription(ctx, tx, op.Subscription); err != nil {
+			return err
+		}
+		result.Subscription = op.Subscription
+	case db.BatchUpdate:
+		if err := updateSubscription(ctx, tx, op.Subscription); err != nil {
+			return err
+		}
+		result.Subscription = op.Subscription
+	case db.BatchDelete:
+		return deleteSubscription(ctx, tx, op.ID)
+	default:
+		return fmt.Errorf("%w: unknown batch operation %q", db.ErrInvalidInput, op.Op)
+	}
+
+	return nil
+}
+
+// copySubscriptions inserts new subscriptions and their audit events with
+// COPY. The rows get the transaction timestamp, just like the column
+// defaults would give them.
+func copySubscriptions(ctx context.Context, tx pgx.Tx, ops []db.BatchOperation) error {
+	var now time.Time
+	if err := tx.QueryRow(ctx, `SELECT now()`).Scan(&now); err != nil {
+		return err
+	}
+
+	subs := make([]*models.Subscription, 0, len(ops))
+	for _, op := range ops {
+		subs = append(subs, op.Subscription)
+	}
+
+	if err := resolveServices(ctx, tx, subs...); err != nil {
+		return err
+	}
+
+	actor := audit.Actor(ctx)
+	requestID := audit.RequestID(ctx)
+	tenantID := tenant.ID(ctx)
+
+	subscriptionRows := make([][]any, 0, len(ops))
+	eventRows := make([][]any, 0, len(ops))
+	for _, op := range ops {
+		sub := op.Subscription
+		sub.DeletedAt = nil
+		sub.Version = 1
+		sub.CreatedAt = now
+		sub.UpdatedAt = now
+
+		id, err := copyUUID(sub.ID)
+		if err != nil {
+			return err
+		}
+
+		userID, err := copyUUID(sub.UserID)
+		if err != nil {
+			return err
+		}
+
+		serviceID := pgtype.UUID{}
+		if sub.ServiceID != nil {
+			if serviceID, err = copyUUID(*sub.ServiceID); err != nil {
+				return err
+			}
+		}
+
+		after, err := marshalSnapshot(sub)
+		if err != nil {
+			return err
+		}
+
+		subscriptionRows = append(subscriptionRows, []any{
+			id,
+			sub.ServiceName,
+			serviceID,
+			sub.Price,
+			userID,
+			sub.StartDate,
+			sub.EndDate,
+			sub.BillingPeriod,
+			sub.BillingInterval,
+			sub.Currency,
+			sub.Category,
+			sub.Version,
+			sub.CreatedAt,
+			sub.UpdatedAt,
+			tenantID,
+		})
+
+		eventRows = append(eventRows, []any{
+			id,
+			audit.ActionCreated,
+			actor,
+			requestID,
+			after,
+			tenantID,
+		})
+	}
+
+	if _, err := tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"subscriptions"},
+		[]string{
+			"id",
+			"service_name",
+			"service_id",
+			"price",
+			"user_id",
+			"start_date",
+			"end_date",
+			"billing_period",
+			"billing_interval",
+			"currency",
+			"category",
+			"version",
+			"created_at",
+			"updated_at",
+			"tenant_id",
+		},
+		pgx.CopyFromRows(subscriptionRows),
+	); err != nil {
+		return err
+	}
+
+	_, err := tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"subscription_events"},
+		[]string{"subscription_id", "action", "actor", "request_id", "after", "tenant_id"},
+		pgx.CopyFromRows(eventRows),
+	)
+
+	return err
+}
+
+// copyUUID converts a UUID string for COPY, which only encodes UUID columns
+// from binary values.
+func copyUUID(value string) (pgtype.UUID, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return pgtype.UUID{}, fmt.Errorf("%w: invalid uuid %q", db.ErrInvalidInput, value)
+	}
+
+	return pgtype.UUID{Bytes: id, Valid: true}, nil
+}
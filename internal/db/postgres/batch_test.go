@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"subscription-aggregator/internal/db"
+	"testing"
+)
+
+func TestApplyBatchAtomicReportsFailingCreate(t *testing.T) {
+	storage, ctx := newTestStorage(t)
+	user := createTestUser(t, storage, ctx)
+
+	ops := []db.BatchOperation{
+		{Op: db.BatchCreate, Subscription: newTestSubscription(user.ID)},
+		{Op: db.BatchCreate, Subscription: newTestSubscription(uuid.NewString())},
+		{Op: db.BatchCreate, Subscription: newTestSubscription(user.ID)},
+	}
+
+	results, err := storage.ApplyBatch(ctx, ops, true)
+	if err != nil {
+		t.Fatalf("apply batch: %v", err)
+	}
+
+	if !errors.Is(results[1].Err, db.ErrUserNotFound) {
+		t.Errorf("create with unknown user: got %v, want %v", results[1].Err, db.ErrUserNotFound)
+	}
+
+	for _, i := range []int{0, 2} {
+		if !errors.Is(results[i].Err, db.ErrBatchAborted) {
+			t.Errorf("create %d: got %v, want %v", i, results[i].Err, db.ErrBatchAborted)
+		}
+	}
+
+	page, err := storage.List(ctx, db.ListFilter{UserID: user.ID, Limit: 10})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+
+	if len(page.Items) != 0 {
+		t.Errorf("rolled back batch left %d subscriptions", len(page.Items))
+	}
+}
+
+func TestApplyBatchAtomicCopiesCreates(t *testing.T) {
+	storage, ctx := newTestStorage(t)
+	user := createTestUser(t, storage, ctx)
+
+	ops := make([]db.BatchOperation, 0, 5)
+	for range 5 {
+		ops = append(ops, db.BatchOperation{Op: db.BatchCreate, Subscription: newTestSubscription(user.ID)})
+	}
+
+	results, err := storage.ApplyBatch(ctx, ops, true)
+	if err != nil {
+		t.Fatalf("apply batch: %v", err)
+	}
+
+	for i, result := range results {
+		if result.Err != nil || result.Subscription == nil || result.Subscription.Version != 1 {
+			t.Errorf("create %d: got %+v", i, result)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"subscription-aggregator/internal/db"
+)
+
+// subscriptionsUserFK is the foreign key from subscriptions to users.
+const subscriptionsUserFK = "subscriptions_user_id_fkey"
+
+// mapError translates Postgres error codes into the storage error taxonomy,
+// keeping the original error in the chain for logging.
+func mapError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	if pgErr.Code == pgerrcode.ForeignKeyViolation && pgErr.ConstraintName == subscriptionsUserFK {
+		return fmt.Errorf("%w: %w", db.ErrUserNotFound, err)
+	}
+
+	switch pgErr.Code {
+	case pgerrcode.UniqueViolation, pgerrcode.ExclusionViolation:
+		return fmt.Errorf("%w: %w", db.ErrConflict, err)
+	case pgerrcode.ForeignKeyViolation,
+		pgerrcode.NotNullViolation,
+		pgerrcode.CheckViolation,
+		pgerrcode.InvalidTextRepresentation,
+		pgerrcode.InvalidDatetimeFormat,
+		pgerrcode.DatetimeFieldOverflow,
+		pgerrcode.NumericValueOutOfRange,
+		pgerrcode.StringDataRightTruncationDataException:
+		return fmt.Errorf("%w: %w", db.ErrInvalidInput, err)
+	}
+
+	return err
+}
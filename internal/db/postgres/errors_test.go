@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"errors"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"subscription-aggregator/internal/db"
+	"testing"
+)
+
+func TestMapError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "unique violation", err: &pgconn.PgError{Code: pgerrcode.UniqueViolation}, want: db.ErrConflict},
+		{name: "exclusion violation", err: &pgconn.PgError{Code: pgerrcode.ExclusionViolation}, want: db.ErrConflict},
+		{name: "unknown user", err: &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation, ConstraintName: subscriptionsUserFK}, want: db.ErrUserNotFound},
+		{name: "other foreign key", err: &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation, ConstraintName: "other_fkey"}, want: db.ErrInvalidInput},
+		{name: "check violation", err: &pgconn.PgError{Code: pgerrcode.CheckViolation}, want: db.ErrInvalidInput},
+		{name: "malformed uuid", err: &pgconn.PgError{Code: pgerrcode.InvalidTextRepresentation}, want: db.ErrInvalidInput},
+		{name: "value too long", err: &pgconn.PgError{Code: pgerrcode.StringDataRightTruncationDataException}, want: db.ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		got := mapError(tt.err)
+		if !errors.Is(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+
+		var pgErr *pgconn.PgError
+		if !errors.As(got, &pgErr) {
+			t.Errorf("%s: the Postgres error was dropped from %v", tt.name, got)
+		}
+	}
+
+	for _, err := range []error{&pgconn.PgError{Code: pgerrcode.DeadlockDetected}, errors.New("connection reset")} {
+		got := mapError(err)
+		if got != err {
+			t.Errorf("got %v for %v, want it unchanged", got, err)
+		}
+
+		for _, taxonomy := range []error{db.ErrConflict, db.ErrInvalidInput, db.ErrNotFound, db.ErrUserNotFound} {
+			if errors.Is(got, taxonomy) {
+				t.Errorf("%v: mapped to %v", err, taxonomy)
+			}
+		}
+	}
+}
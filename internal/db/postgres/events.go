@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"subscription-aggregator/internal/audit"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
+)
+
+// lockSubscription reads a subscription inside tx and locks its row until
+// the transaction ends.
+func lockSubscription(ctx context.Context, tx pgx.Tx, id string) (*models.Subscription, error) {
+	sql := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE id = $1 AND tenant_id = $2 FOR UPDATE`
+
+	sub, err := scanSubscription(tx.QueryRow(ctx, sql, id, tenant.ID(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, db.ErrNotFound
+	}
+
+	return sub, err
+}
+
+// recordEvent appends a mutation to subscription_events inside tx, so the
+// event is stored exactly when the change itself is committed.
+func recordEvent(ctx context.Context, tx pgx.Tx, subscriptionID string, action string, before *models.Subscription, after *models.Subscription) error {
+	sql := `
+      INSERT INTO subscription_events (subscription_id, action, actor, request_id, before, after, tenant_id)
+      VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	beforeJSON, err := marshalSnapshot(before)
+	if err != nil {
+		return err
+	}
+
+	afterJSON, err := marshalSnapshot(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, sql, subscriptionID, action, audit.Actor(ctx), audit.RequestID(ctx), beforeJSON, afterJSON, tenant.ID(ctx))
+	return err
+}
+
+func marshalSnapshot(sub *models.Subscription) ([]byte, error) {
+	if sub == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode subscription snapshot: %w", err)
+	}
+
+	return data, nil
+}
+
+func (s *Storage) History(ctx context.Context, subscriptionID string) ([]models.SubscriptionEvent, error) {
+	sql := `
+      SELECT id, subscription_id, action, actor, request_id, before, after, created_at
+      FROM subscription_events
+      WHERE subscription_id = $1 AND tenant_id = $2
+      ORDER BY id`
+
+	rows, err := s.database.Query(ctx, sql, subscriptionID, tenant.ID(ctx))
+	if err != nil {
+		s.logger.Error("Failed to get subscription history", "error", err, "subscription_id", subscriptionID)
+		return nil, fmt.Errorf("failed to get subscription history: %w", mapError(err))
+	}
+
+	defer rows.Close()
+
+	var events []models.SubscriptionEvent
+	for rows.Next() {
+		var event models.SubscriptionEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.SubscriptionID,
+			&event.Action,
+			&event.Actor,
+			&event.RequestID,
+			&event.Before,
+			&event.After,
+			&event.CreatedAt,
+		); err != nil {
+			s.logger.Error("Failed to scan subscription event row", "error", err, "subscription_id", subscriptionID)
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error rows iterations", "error", err, "subscription_id", subscriptionID)
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		return nil, db.ErrNotFound
+	}
+
+	return events, nil
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"subscription-aggregator/internal/audit"
+	"subscription-aggregator/internal/db"
+	"testing"
+)
+
+func TestHistoryRecordsEveryMutation(t *testing.T) {
+	storage, ctx := newTestStorage(t)
+	user := createTestUser(t, storage, ctx)
+
+	ctx = audit.WithRequestID(audit.WithActor(ctx, "support"), "req-1")
+
+	sub := newTestSubscription(user.ID)
+	if err := storage.Save(ctx, sub); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	sub.Price = 500
+	if err := storage.Update(ctx, sub); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	if err := storage.Delete(ctx, sub.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	// A mutation that fails writes no event.
+	if err := storage.Update(ctx, sub); err == nil {
+		t.Fatalf("update deleted: got no error")
+	}
+
+	events, err := storage.History(ctx, sub.ID)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+
+	want := []string{audit.ActionCreated, audit.ActionUpdated, audit.ActionDeleted}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+
+	for i, action := range want {
+		event := events[i]
+		if event.Action != action || event.Actor != "support" || event.RequestID != "req-1" {
+			t.Errorf("event %d: got %s by %s in %s, want %s by support in req-1", i, event.Action, event.Actor, event.RequestID, action)
+		}
+
+		if (event.Before == nil) != (action == audit.ActionCreated) || (event.After == nil) != (action == audit.ActionDeleted) {
+			t.Errorf("%s: got before %s, after %s", action, event.Before, event.After)
+		}
+	}
+
+	if _, err := storage.History(ctx, uuid.NewString()); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("unknown subscription: got %v, want %v", err, db.ErrNotFound)
+	}
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
+	"time"
+)
+
+func (s *Storage) SaveRates(ctx context.Context, rates []models.FXRate) error {
+	sql := `
+      INSERT INTO fx_rates (month, base_currency, quote_currency, rate, tenant_id) VALUES ($1, $2, $3, $4, $5)
+      ON CONFLICT (tenant_id, base_currency, quote_currency, month) DO UPDATE SET rate = EXCLUDED.rate`
+
+	batch := &pgx.Batch{}
+	for _, rate := range rates {
+		batch.Queue(sql, rate.Month, rate.BaseCurrency, rate.QuoteCurrency, rate.Rate, tenant.ID(ctx))
+	}
+
+	err := pgx.BeginFunc(ctx, s.database, func(tx pgx.Tx) error {
+		return tx.SendBatch(ctx, batch).Close()
+	})
+	if err != nil {
+		s.logger.Error("Unable to save fx rates", "error", err)
+		return fmt.Errorf("unable to save fx rates: %w", mapError(err))
+	}
+
+	s.logger.Info("FX rates saved successfully", "count", len(rates))
+
+	return nil
+}
+
+func (s *Storage) ListRates(ctx context.Context) ([]models.FXRate, error) {
+	sql := `
+      SELECT month, base_currency, quote_currency, rate FROM fx_rates
+      WHERE tenant_id = $1
+      ORDER BY base_currency, quote_currency, month`
+
+	rows, err := s.database.Query(ctx, sql, tenant.ID(ctx))
+	if err != nil {
+		s.logger.Error("Failed to list fx rates", "error", err)
+		return nil, fmt.Errorf("failed to list fx rates: %w", mapError(err))
+	}
+
+	defer rows.Close()
+
+	rates := []models.FXRate{}
+	for rows.Next() {
+		var rate models.FXRate
+		if err := rows.Scan(&rate.Month, &rate.BaseCurrency, &rate.QuoteCurrency, &rate.Rate); err != nil {
+			s.logger.Error("Failed to scan fx rate row", "error", err)
+			return nil, err
+		}
+
+		rates = append(rates, rate)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error rows iterations", "error", err)
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+func (s *Storage) GetRate(ctx context.Context, baseCurrency string, quoteCurrency string, month time.Time) (*models.FXRate, error) {
+	sql := `
+      SELECT month, base_currency, quote_currency, rate FROM fx_rates
+      WHERE base_currency = $1 AND quote_currency = $2 AND month <= $3::date AND tenant_id = $4
+      ORDER BY month DESC
+      LIMIT 1`
+
+	var rate models.FXRate
+	err := s.database.QueryRow(ctx, sql, baseCurrency, quoteCurrency, month, tenant.ID(ctx)).Scan(
+		&rate.Month,
+		&rate.BaseCurrency,
+		&rate.QuoteCurrency,
+		&rate.Rate,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, db.ErrNotFound
+		}
+
+		s.logger.Error("Failed to get fx rate", "error", err, "base", baseCurrency, "quote", quoteCurrency)
+		return nil, fmt.Errorf("failed to get fx rate: %w", mapError(err))
+	}
+
+	return &rate, nil
+}
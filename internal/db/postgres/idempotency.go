@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
+	"time"
+)
+
+// ReserveIdempotencyKey relies on the primary key: a concurrent request with
+// the same key waits on the insert and then sees the first one's record.
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, key string, requestHash string, notBefore time.Time) (*models.IdempotencyRecord, error) {
+	var existing *models.IdempotencyRecord
+	tenantID := tenant.ID(ctx)
+
+	err := pgx.BeginFunc(ctx, s.database, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND created_at < $2 AND tenant_id = $3`, key, notBefore, tenantID); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(
+			ctx,
+			`INSERT INTO idempotency_keys (key, request_hash, tenant_id) VALUES ($1, $2, $3) ON CONFLICT (tenant_id, key) DO NOTHING`,
+			key,
+			requestHash,
+			tenantID,
+		)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 1 {
+			return nil
+		}
+
+		existing = &models.IdempotencyRecord{}
+		err = tx.QueryRow(
+			ctx,
+			`SELECT key, request_hash, status_code, location, response, created_at FROM idempotency_keys WHERE key = $1 AND tenant_id = $2`,
+			key,
+			tenantID,
+		).Scan(&existing.Key, &existing.RequestHash, &existing.StatusCode, &existing.Location, &existing.Response, &existing.CreatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The other request released the key between the two statements.
+			return fmt.Errorf("%w: idempotency key %s is in use", db.ErrConflict, key)
+		}
+
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Failed to reserve idempotency key", "error", err, "key", key)
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", mapError(err))
+	}
+
+	return existing, nil
+}
+
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, location string, response []byte) error {
+	sql := `UPDATE idempotency_keys SET status_code = $2, location = $3, response = $4 WHERE key = $1 AND tenant_id = $5`
+
+	result, err := s.database.Exec(ctx, sql, key, statusCode, location, response, tenant.ID(ctx))
+	if err != nil {
+		s.logger.Error("Failed to complete idempotency key", "error", err, "key", key)
+		return fmt.Errorf("failed to complete idempotency key: %w", mapError(err))
+	}
+
+	if result.RowsAffected() == 0 {
+		return db.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	sql := `DELETE FROM idempotency_keys WHERE key = $1 AND status_code = 0 AND tenant_id = $2`
+
+	if _, err := s.database.Exec(ctx, sql, key, tenant.ID(ctx)); err != nil {
+		s.logger.Error("Failed to release idempotency key", "error", err, "key", key)
+		return fmt.Errorf("failed to release idempotency key: %w", mapError(err))
+	}
+
+	return nil
+}
+
+func (s *Storage) PurgeIdempotencyKeys(ctx context.Context, createdBefore time.Time) (int, error) {
+	sql := `DELETE FROM idempotency_keys WHERE created_at < $1`
+
+	result, err := s.database.Exec(withAllTenants(ctx), sql, createdBefore)
+	if err != nil {
+		s.logger.Error("Failed to purge idempotency keys", "error", err)
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", mapError(err))
+	}
+
+	return int(result.RowsAffected()), nil
+}
@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"io/fs"
+	"log/slog"
+	"os"
+	"subscription-aggregator/internal/config"
+	"subscription-aggregator/internal/db/migrations"
+)
+
+// Migrator applies the embedded migrations. The pgx driver holds a Postgres
+// advisory lock for the whole run, so replicas starting at the same time
+// apply each migration exactly once.
+type Migrator struct {
+	migrate *migrate.Migrate
+	source  source.Driver
+	logger  *slog.Logger
+}
+
+type MigrationStatus struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+func NewMigrator(cfg *config.Config, logger *slog.Logger) (*Migrator, error) {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("unable to load embedded migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, connString("pgx5", cfg))
+	if err != nil {
+		logger.Error("Unable to create migrator", "error", err)
+		return nil, fmt.Errorf("unable to create migrator: %w", err)
+	}
+
+	return &Migrator{
+		migrate: m,
+		source:  src,
+		logger:  logger,
+	}, nil
+}
+
+func (m *Migrator) Up() error {
+	if err := m.migrate.Up(); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			m.logger.Info("Database schema is up to date")
+			return nil
+		}
+
+		m.logger.Error("Failed to apply migrations", "error", err)
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	m.logger.Info("Migrations applied successfully")
+
+	return nil
+}
+
+// Down rolls back the given number of applied migrations.
+func (m *Migrator) Down(steps int) error {
+	if err := m.migrate.Steps(-steps); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) || errors.Is(err, os.ErrNotExist) {
+			m.logger.Info("No migrations to roll back")
+			return nil
+		}
+
+		m.logger.Error("Failed to roll back migrations", "error", err)
+		return fmt.Errorf("failed to roll back migrations: %w", err)
+	}
+
+	m.logger.Info("Migrations rolled back successfully", "steps", steps)
+
+	return nil
+}
+
+// Version returns the current schema version; ok is false when no migration
+// has been applied yet.
+func (m *Migrator) Version() (version uint, dirty bool, ok bool, err error) {
+	version, dirty, err = m.migrate.Version()
+	if err != nil {
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return 0, false, false, nil
+		}
+
+		return 0, false, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	return version, dirty, true, nil
+}
+
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	current, _, ok, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+
+	version, err := m.source.First()
+	for err == nil {
+		reader, name, readErr := m.source.ReadUp(version)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read migration %d: %w", version, readErr)
+		}
+		reader.Close()
+
+		statuses = append(statuses, MigrationStatus{
+			Version: version,
+			Name:    name,
+			Applied: ok && version <= current,
+		})
+
+		version, err = m.source.Next(version)
+	}
+
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	return statuses, nil
+}
+
+func (m *Migrator) Close() error {
+	sourceErr, databaseErr := m.migrate.Close()
+	return errors.Join(sourceErr, databaseErr)
+}
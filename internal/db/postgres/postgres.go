@@ -5,40 +5,65 @@ import (
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"log/slog"
+	"strings"
+	"subscription-aggregator/internal/audit"
 	"subscription-aggregator/internal/config"
 	"subscription-aggregator/internal/db"
 	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
 	"time"
 )
 
 type Storage struct {
-	database *pgx.Conn
+	database *tenantPool
 	logger   *slog.Logger
 }
 
 func New(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*Storage, error) {
-	dbUrl := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		cfg.PostgresUser,
-		cfg.PostgresPassword,
-		cfg.PostgresHost,
-		cfg.PostgresPort,
-		cfg.PostgresDB,
-	)
+	poolConfig, err := pgxpool.ParseConfig(connString("postgres", cfg))
+	if err != nil {
+		logger.Error("Unable to parse database config", "error", err)
+		return nil, err
+	}
 
-	database, err := pgx.Connect(ctx, dbUrl)
+	poolConfig.MaxConns = cfg.PostgresMaxConns
+	poolConfig.MinConns = cfg.PostgresMinConns
+	poolConfig.MaxConnLifetime = cfg.PostgresMaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.PostgresMaxConnIdleTime
+	poolConfig.HealthCheckPeriod = cfg.PostgresHealthCheckPeriod
+	poolConfig.ConnConfig.Tracer = newQueryTracer()
+
+	if cfg.PostgresAppRole != "" {
+		setRole := "SET ROLE " + pgx.Identifier{cfg.PostgresAppRole}.Sanitize()
+		poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+			_, err := conn.Exec(ctx, setRole)
+			return err
+		}
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		logger.Error("Unable to connect to database", "error", err)
 		return nil, err
 	}
 
+	database := &tenantPool{pool: pool}
+
 	if err := database.Ping(ctx); err != nil {
 		logger.Error("Ping to connect database failed", "error", err)
-		database.Close(ctx)
+		database.Close()
 		return nil, err
 	}
 
-	logger.Info("Connected to database")
+	if err := checkRowLevelSecurity(ctx, database); err != nil {
+		logger.Error("Database role bypasses row-level security", "error", err)
+		database.Close()
+		return nil, err
+	}
+
+	logger.Info("Connected to database", "max_conns", poolConfig.MaxConns, "min_conns", poolConfig.MinConns)
 
 	return &Storage{
 		database: database,
@@ -46,20 +71,43 @@ func New(ctx context.Context, cfg *config.Config, logger *slog.Logger) (*Storage
 	}, nil
 }
 
+// checkRowLevelSecurity refuses a role the policies of the tenants do not
+// apply to. Superusers and roles with BYPASSRLS skip them even on tables
+// that force row-level security.
+func checkRowLevelSecurity(ctx context.Context, database *tenantPool) error {
+	var role string
+	var bypass bool
+
+	sql := `SELECT rolname, rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user`
+	if err := database.pool.QueryRow(ctx, sql).Scan(&role, &bypass); err != nil {
+		return fmt.Errorf("failed to check database role: %w", err)
+	}
+
+	if bypass {
+		return fmt.Errorf("database role %s bypasses row-level security; set POSTGRES_APP_ROLE", role)
+	}
+
+	return nil
+}
+
+func connString(scheme string, cfg *config.Config) string {
+	return fmt.Sprintf("%s://%s:%s@%s:%s/%s?sslmode=disable",
+		scheme,
+		cfg.PostgresUser,
+		cfg.PostgresPassword,
+		cfg.PostgresHost,
+		cfg.PostgresPort,
+		cfg.PostgresDB,
+	)
+}
+
 func (s *Storage) Save(ctx context.Context, sub *models.Subscription) error {
-	sql := `INSERT INTO subscriptions (id, service_name, price, user_ID, start_date, end_date) VALUES ($1, $2, $3, $4, $5, $6)`
-	if _, err := s.database.Exec(
-		ctx,
-		sql,
-		sub.ID,
-		sub.ServiceName,
-		sub.Price,
-		sub.UserID,
-		sub.StartDate,
-		sub.EndDate,
-	); err != nil {
+	err := pgx.BeginFunc(ctx, s.database, func(tx pgx.Tx) error {
+		return insertSubscription(ctx, tx, sub)
+	})
+	if err != nil {
 		s.logger.Error("Unable to save subscription", "error", err)
-		return fmt.Errorf("unable to save subscription: %w", err)
+		return fmt.Errorf("unable to save subscription: %w", mapError(err))
 	}
 
 	s.logger.Info("Subscription saved successfully", "ID", sub.ID)
@@ -68,36 +116,78 @@ func (s *Storage) Save(ctx context.Context, sub *models.Subscription) error {
 }
 
 func (s *Storage) Delete(ctx context.Context, id string) error {
-	sql := `DELETE FROM subscriptions WHERE id = $1`
-	result, err := s.database.Exec(ctx, sql, id)
+	err := pgx.BeginFunc(ctx, s.database, func(tx pgx.Tx) error {
+		return deleteSubscription(ctx, tx, id)
+	})
 	if err != nil {
-		s.logger.Error("Failed to delete subscription", "error", err)
-		return err
-	}
+		if errors.Is(err, db.ErrNotFound) {
+			s.logger.Error("Failed to find subscription", "id", id)
+			return err
+		}
 
-	if result.RowsAffected() == 0 {
-		s.logger.Error("Failed to find subscription", "error", err, "id", id)
-		return db.ErrNotFound
+		s.logger.Error("Failed to delete subscription", "error", err)
+		return fmt.Errorf("failed to delete subscription: %w", mapError(err))
 	}
 
 	s.logger.Info("Subscription deleted successfully", "ID", id)
 	return nil
+}
+
+func (s *Storage) Restore(ctx context.Context, id string) error {
+	sql := `
+      UPDATE subscriptions SET deleted_at = NULL, version = version + 1, updated_at = now() WHERE id = $1 AND tenant_id = $2
+      RETURNING ` + subscriptionColumns
+
+	err := pgx.BeginFunc(ctx, s.database, func(tx pgx.Tx) error {
+		before, err := lockSubscription(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if before.DeletedAt == nil {
+			return fmt.Errorf("%w: subscription is not deleted", db.ErrConflict)
+		}
+
+		after, err := scanSubscription(tx.QueryRow(ctx, sql, id, tenant.ID(ctx)))
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, tx, id, audit.ActionRestored, before, after)
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) || errors.Is(err, db.ErrConflict) {
+			s.logger.Error("Failed to restore subscription", "error", err, "id", id)
+			return err
+		}
+
+		s.logger.Error("Failed to restore subscription", "error", err)
+		return fmt.Errorf("failed to restore subscription: %w", mapError(err))
+	}
 
+	s.logger.Info("Subscription restored successfully", "ID", id)
+	return nil
 }
 
-func (s *Storage) GetByID(ctx context.Context, id string) (*models.Subscription, error) {
-	sql := `SELECT id, service_name, price, user_ID, start_date, end_date FROM subscriptions WHERE id = $1`
+func (s *Storage) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	sql := `DELETE FROM subscriptions WHERE deleted_at < $1`
 
-	var sub models.Subscription
-	err := s.database.QueryRow(ctx, sql, id).Scan(
-		&sub.ID,
-		&sub.ServiceName,
-		&sub.Price,
-		&sub.UserID,
-		&sub.StartDate,
-		&sub.EndDate,
-	)
+	result, err := s.database.Exec(withAllTenants(ctx), sql, deletedBefore)
+	if err != nil {
+		s.logger.Error("Failed to purge subscriptions", "error", err)
+		return 0, fmt.Errorf("failed to purge subscriptions: %w", mapError(err))
+	}
+
+	return int(result.RowsAffected()), nil
+}
 
+func (s *Storage) GetByID(ctx context.Context, id string, includeDeleted bool) (*models.Subscription, error) {
+	sql := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE id = $1 AND tenant_id = $2`
+	if !includeDeleted {
+		sql += ` AND deleted_at IS NULL`
+	}
+
+	sub, err := scanSubscription(s.database.QueryRow(ctx, sql, id, tenant.ID(ctx)))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			s.logger.Error("Failed to find subscription", "error", err, "id", id)
@@ -105,127 +195,370 @@ func (s *Storage) GetByID(ctx context.Context, id string) (*models.Subscription,
 		}
 
 		s.logger.Error("Failed to get subscription", "error", err)
-		return nil, fmt.Errorf("failed to get subscription by id: %w", err)
+		return nil, fmt.Errorf("failed to get subscription by id: %w", mapError(err))
 	}
 
 	s.logger.Info("Subscription found successfully", "ID", id)
 
-	return &sub, nil
+	return sub, nil
 }
 
-func (s *Storage) List(ctx context.Context, userID string) ([]*models.Subscription, error) {
-	sql := `SELECT id, service_name, price, user_id, start_date, end_date FROM subscriptions WHERE user_id = $1`
+func (s *Storage) List(ctx context.Context, filter db.ListFilter) (*models.SubscriptionPage, error) {
+	args := &queryArgs{}
+	conditions := listConditions(tenant.ID(ctx), filter, args)
+
+	var total int
+	countSQL := `SELECT COUNT(*) FROM subscriptions` + whereClause(conditions)
+	if err := s.database.QueryRow(ctx, countSQL, args.values...).Scan(&total); err != nil {
+		s.logger.Error("Failed to count subscriptions", "error", err, "user_id", filter.UserID)
+		return nil, fmt.Errorf("failed to count subscriptions: %w", mapError(err))
+	}
+
+	if total == 0 && filter.UserID != "" {
+		if err := s.userExists(ctx, filter.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	column, cast := sortColumn(filter.SortBy)
+	order, comparison := "ASC", ">"
+	if filter.Desc {
+		order, comparison = "DESC", "<"
+	}
+
+	if filter.Cursor != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s::%s, %s::uuid)",
+			column, comparison, args.add(filter.Cursor.Value), cast, args.add(filter.Cursor.ID)))
+	}
+
+	sql := fmt.Sprintf(`SELECT `+subscriptionColumns+` FROM subscriptions%s ORDER BY %s %s, id %s LIMIT %s`,
+		whereClause(conditions), column, order, order, args.add(filter.Limit+1))
 
-	rows, err := s.database.Query(ctx, sql, userID)
+	rows, err := s.database.Query(ctx, sql, args.values...)
 	if err != nil {
-		s.logger.Error("Failed to list subscriptions", "error", err, "user_id", userID)
-		return nil, err
+		s.logger.Error("Failed to list subscriptions", "error", err, "user_id", filter.UserID)
+		return nil, fmt.Errorf("failed to list subscriptions: %w", mapError(err))
 	}
 
 	defer rows.Close()
 
-	var subs []*models.Subscription
+	subs := make([]*models.Subscription, 0, filter.Limit+1)
 	for rows.Next() {
-		var sub models.Subscription
-		scan := rows.Scan(
-			&sub.ID,
-			&sub.ServiceName,
-			&sub.Price,
-			&sub.UserID,
-			&sub.StartDate,
-			&sub.EndDate,
-		)
-
-		if err := scan; err != nil {
-			s.logger.Error("Failed to scan subscription row", "error", err, "user_id", userID)
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			s.logger.Error("Failed to scan subscription row", "error", err, "user_id", filter.UserID)
 			return nil, err
 		}
 
-		subs = append(subs, &sub)
+		subs = append(subs, sub)
 	}
 
 	if err := rows.Err(); err != nil {
-		s.logger.Error("Error rows iterations", "error", err, "user_id", userID)
+		s.logger.Error("Error rows iterations", "error", err, "user_id", filter.UserID)
 		return nil, err
 	}
 
-	s.logger.Info("Subscriptions listed successfully", "user_id", userID)
+	s.logger.Info("Subscriptions listed successfully", "user_id", filter.UserID, "count", len(subs))
 
-	return subs, nil
+	return db.NewPage(subs, filter, total), nil
+}
+
+// Export streams the rows of one query, so the connection stays taken until
+// fn has seen the last of them.
+func (s *Storage) Export(ctx context.Context, filter db.ListFilter, fn func(*models.Subscription) error) error {
+	if filter.UserID != "" {
+		if err := s.userExists(ctx, filter.UserID); err != nil {
+			return err
+		}
+	}
+
+	args := &queryArgs{}
+	conditions := listConditions(tenant.ID(ctx), filter, args)
+
+	column, _ := sortColumn(filter.SortBy)
+	order := "ASC"
+	if filter.Desc {
+		order = "DESC"
+	}
+
+	sql := fmt.Sprintf(`SELECT `+subscriptionColumns+` FROM subscriptions%s ORDER BY %s %s, id %s`,
+		whereClause(conditions), column, order, order)
+
+	rows, err := s.database.Query(ctx, sql, args.values...)
+	if err != nil {
+		s.logger.Error("Failed to export subscriptions", "error", err, "user_id", filter.UserID)
+		return fmt.Errorf("failed to export subscriptions: %w", mapError(err))
+	}
+
+	defer rows.Close()
+
+	exported := 0
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			s.logger.Error("Failed to scan subscription row", "error", err, "user_id", filter.UserID)
+			return err
+		}
+
+		if err := fn(sub); err != nil {
+			return err
+		}
+
+		exported++
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error rows iterations", "error", err, "user_id", filter.UserID)
+		return err
+	}
+
+	s.logger.Info("Subscriptions exported successfully", "user_id", filter.UserID, "count", exported)
+
+	return nil
 }
 
 func (s *Storage) Update(ctx context.Context, sub *models.Subscription) error {
-	sql := `UPDATE subscriptions SET service_name = $1, price = $2, user_ID = $3, start_date = $4, end_date = $5 WHERE id = $6`
+	err := pgx.BeginFunc(ctx, s.database, func(tx pgx.Tx) error {
+		return updateSubscription(ctx, tx, sub)
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			s.logger.Error("Failed to find subscription for update", "id", sub.ID)
+			return err
+		}
+
+		if errors.Is(err, db.ErrPreconditionFailed) {
+			s.logger.Warn("Subscription version mismatch", "id", sub.ID, "expected_version", sub.Version)
+			return err
+		}
+
+		s.logger.Error("Failed to update subscription", "error", err)
+		return fmt.Errorf("failed to update subscription: %w", mapError(err))
+	}
+
+	s.logger.Info("Subscription updated successfully", "ID", sub.ID)
+
+	return nil
+}
 
-	result, err := s.database.Exec(
+// insertSubscription stores a new subscription inside tx and fills sub with
+// the stored row.
+func insertSubscription(ctx context.Context, tx pgx.Tx, sub *models.Subscription) error {
+	sql := `
+      INSERT INTO subscriptions (id, service_name, service_id, price, user_id, start_date, end_date, billing_period, billing_interval, currency, category, tenant_id)
+      VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+      RETURNING ` + subscriptionColumns
+
+	if err := resolveServices(ctx, tx, sub); err != nil {
+		return err
+	}
+
+	saved, err := scanSubscription(tx.QueryRow(
 		ctx,
 		sql,
+		sub.ID,
 		sub.ServiceName,
+		sub.ServiceID,
 		sub.Price,
 		sub.UserID,
 		sub.StartDate,
 		sub.EndDate,
-		sub.ID,
-	)
+		sub.BillingPeriod,
+		sub.BillingInterval,
+		sub.Currency,
+		sub.Category,
+		tenant.ID(ctx),
+	))
 	if err != nil {
-		s.logger.Error("Failed to update subscription", "error", err)
-		return fmt.Errorf("failed to update subscription: %w", err)
+		return err
 	}
 
-	if result.RowsAffected() == 0 {
-		s.logger.Error("Failed to find subscription for update", "error", err, "id", sub.ID)
+	*sub = *saved
+
+	return recordEvent(ctx, tx, sub.ID, audit.ActionCreated, nil, sub)
+}
+
+// deleteSubscription soft-deletes a subscription inside tx.
+func deleteSubscription(ctx context.Context, tx pgx.Tx, id string) error {
+	sql := `UPDATE subscriptions SET deleted_at = now(), version = version + 1, updated_at = now() WHERE id = $1 AND tenant_id = $2`
+
+	before, err := lockSubscription(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if before.DeletedAt != nil {
 		return db.ErrNotFound
 	}
 
-	s.logger.Info("Subscription updated successfully", "ID", sub.ID)
+	if _, err := tx.Exec(ctx, sql, id, tenant.ID(ctx)); err != nil {
+		return err
+	}
 
-	return nil
+	return recordEvent(ctx, tx, id, audit.ActionDeleted, before, nil)
 }
 
-func (s *Storage) Close(ctx context.Context) error {
+// updateSubscription overwrites a subscription inside tx after checking the
+// expected version, and fills sub with the stored row.
+func updateSubscription(ctx context.Context, tx pgx.Tx, sub *models.Subscription) error {
+	sql := `UPDATE subscriptions SET service_name = $1, service_id = $2, price = $3, user_ID = $4, start_date = $5, end_date = $6,
+      billing_period = $7, billing_interval = $8, currency = $9, category = $10, version = version + 1, updated_at = now()
+      WHERE id = $11 AND tenant_id = $12
+      RETURNING ` + subscriptionColumns
+
+	before, err := lockSubscription(ctx, tx, sub.ID)
+	if err != nil {
+		return err
+	}
+
+	if before.DeletedAt != nil {
+		return db.ErrNotFound
+	}
+
+	if sub.Version != 0 && sub.Version != before.Version {
+		return db.ErrPreconditionFailed
+	}
+
+	if err := resolveServices(ctx, tx, sub); err != nil {
+		return err
+	}
+
+	updated, err := scanSubscription(tx.QueryRow(
+		ctx,
+		sql,
+		sub.ServiceName,
+		sub.ServiceID,
+		sub.Price,
+		sub.UserID,
+		sub.StartDate,
+		sub.EndDate,
+		sub.BillingPeriod,
+		sub.BillingInterval,
+		sub.Currency,
+		sub.Category,
+		sub.ID,
+		tenant.ID(ctx),
+	))
+	if err != nil {
+		return err
+	}
+
+	*sub = *updated
+
+	return recordEvent(ctx, tx, sub.ID, audit.ActionUpdated, before, sub)
+}
+
+func (s *Storage) Close() {
 	if s.database != nil {
-		return s.database.Close(ctx)
+		s.database.Close()
 	}
+}
 
-	return nil
+// Stat reports the state of the connection pool.
+func (s *Storage) Stat() *pgxpool.Stat {
+	return s.database.Stat()
+}
+
+// SumTotalCost adds up the price of every charge that falls into the period.
+// Charges happen on the start date and then once per billing period, so a
+// yearly subscription is charged only in the months of its anniversaries.
+func (s *Storage) SumTotalCost(ctx context.Context, filter db.CostFilter) ([]models.CostGroup, error) {
+	args := &queryArgs{}
+	from := chargesFrom(tenant.ID(ctx), filter, args)
+
+	var columns []string
+	for _, dimension := range filter.GroupBy {
+		columns = append(columns, costGroupColumns[dimension])
+	}
+
+	sql := `SELECT ` + strings.Join(append(columns, "COALESCE(SUM(s.price), 0) AS total_cost"), ", ") + from
+	if len(columns) > 0 {
+		sql += " GROUP BY " + strings.Join(columns, ", ") + " ORDER BY " + strings.Join(columns, ", ")
+	}
+
+	rows, err := s.database.Query(ctx, sql, args.values...)
+	if err != nil {
+		s.logger.Error("Failed to sum total cost", "error", err, "user_id", filter.UserID)
+		return nil, fmt.Errorf("failed to sum total cost: %w", mapError(err))
+	}
+
+	defer rows.Close()
+
+	var groups []models.CostGroup
+	for rows.Next() {
+		var group models.CostGroup
+		var totalCost int64
+
+		targets := make([]any, 0, len(filter.GroupBy)+1)
+		for _, dimension := range filter.GroupBy {
+			switch dimension {
+			case db.GroupByService:
+				targets = append(targets, &group.ServiceName)
+			case db.GroupByCategory:
+				targets = append(targets, &group.Category)
+			case db.GroupByMonth:
+				targets = append(targets, &group.Month)
+			case db.GroupByYear:
+				targets = append(targets, &group.Year)
+			case db.GroupByCurrency:
+				targets = append(targets, &group.Currency)
+			}
+		}
+
+		if err := rows.Scan(append(targets, &totalCost)...); err != nil {
+			s.logger.Error("Failed to scan total cost row", "error", err, "user_id", filter.UserID)
+			return nil, err
+		}
+
+		group.TotalCost = int(totalCost)
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error rows iterations", "error", err, "user_id", filter.UserID)
+		return nil, err
+	}
+
+	return groups, nil
 }
 
-func (s *Storage) SumTotalCost(ctx context.Context, userID string, serviceName string, periodStart time.Time, periodEnd time.Time) (int, error) {
+func (s *Storage) MonthlyCharges(ctx context.Context, filter db.CostFilter) ([]models.MonthlyCharge, error) {
+	args := &queryArgs{}
 	sql := `
-      WITH subs_in_period AS (
-       SELECT 
-         s.id, 
-         s.user_id, 
-         s.service_name, 
-         s.price, 
-         s.start_date, 
-         s.end_date, 
-         GREATEST(s.start_date, $1::date) AS actual_start, 
-         LEAST(COALESCE(s.end_date, $2::date), $2::date) AS actual_end
-       FROM subscriptions s
-       WHERE s.user_id = $3
-         AND s.service_name = $4
-         AND (s.end_date IS NULL OR s.end_date > $1::date)
-         AND s.start_date < $2::date
-      )
-      SELECT COALESCE(SUM(
-        price * GREATEST(0, (
-          EXTRACT(YEAR FROM date_trunc('month', actual_end - interval '1 day')) * 12 + 
-          EXTRACT(MONTH FROM date_trunc('month', actual_end - interval '1 day'))
-        ) - (
-          EXTRACT(YEAR FROM date_trunc('month', actual_start)) * 12 + 
-          EXTRACT(MONTH FROM date_trunc('month', actual_start))
-        ) + 1)
-      ), 0) AS total_cost
-      FROM subs_in_period
-      WHERE actual_start < actual_end;
-    `
-
-	var totalCost int64
-	row := s.database.QueryRow(ctx, sql, periodStart, periodEnd, userID, serviceName)
-	if err := row.Scan(&totalCost); err != nil {
-		return 0, err
-	}
-
-	return int(totalCost), nil
+      SELECT
+        date_trunc('month', charge.charged_at)::date AS month,
+        s.service_name,
+        s.category,
+        s.currency,
+        SUM(s.price) AS amount` + chargesFrom(tenant.ID(ctx), filter, args) + `
+      GROUP BY 1, 2, 3, 4
+      ORDER BY 1, 2, 3, 4`
+
+	rows, err := s.database.Query(ctx, sql, args.values...)
+	if err != nil {
+		s.logger.Error("Failed to get monthly charges", "error", err, "user_id", filter.UserID)
+		return nil, fmt.Errorf("failed to get monthly charges: %w", mapError(err))
+	}
+
+	defer rows.Close()
+
+	var charges []models.MonthlyCharge
+	for rows.Next() {
+		var charge models.MonthlyCharge
+		var amount int64
+		if err := rows.Scan(&charge.Month, &charge.ServiceName, &charge.Category, &charge.Currency, &amount); err != nil {
+			s.logger.Error("Failed to scan monthly charge row", "error", err, "user_id", filter.UserID)
+			return nil, err
+		}
+
+		charge.Amount = int(amount)
+		charges = append(charges, charge)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error rows iterations", "error", err, "user_id", filter.UserID)
+		return nil, err
+	}
+
+	return charges, nil
 }
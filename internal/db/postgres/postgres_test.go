@@ -0,0 +1,312 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"subscription-aggregator/internal/config"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestConfig loads the database settings from the POSTGRES_* variables
+// and migrates the database up; without POSTGRES_HOST the test is skipped.
+func newTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	if os.Getenv("POSTGRES_HOST") == "" {
+		t.Skip("POSTGRES_HOST is not set")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	migrator, err := NewMigrator(cfg, testLogger)
+	if err != nil {
+		t.Fatalf("create migrator: %v", err)
+	}
+
+	if err := migrator.Up(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	if err := migrator.Close(); err != nil {
+		t.Fatalf("close migrator: %v", err)
+	}
+
+	return cfg
+}
+
+// newTestStorage connects to the test database. The returned context acts
+// for a tenant of its own, so tests never see each other's rows.
+func newTestStorage(t *testing.T) (*Storage, context.Context) {
+	t.Helper()
+
+	return connectTestStorage(t, newTestConfig(t)), tenant.WithID(context.Background(), newTestTenant())
+}
+
+func connectTestStorage(t *testing.T, cfg *config.Config) *Storage {
+	t.Helper()
+
+	storage, err := New(context.Background(), cfg, testLogger)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	t.Cleanup(storage.Close)
+
+	return storage
+}
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func newTestTenant() string {
+	return "test-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:16]
+}
+
+func createTestUser(t *testing.T, storage *Storage, ctx context.Context) *models.User {
+	t.Helper()
+
+	user := &models.User{
+		ID:              uuid.NewString(),
+		DisplayName:     "Test",
+		DefaultCurrency: models.DefaultCurrency,
+		Timezone:        "UTC",
+	}
+
+	if err := storage.CreateUser(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	return user
+}
+
+func newTestSubscription(userID string) *models.Subscription {
+	return &models.Subscription{
+		ID:              uuid.NewString(),
+		ServiceName:     "Netflix",
+		Price:           400,
+		UserID:          userID,
+		StartDate:       time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+		BillingPeriod:   "monthly",
+		BillingInterval: 1,
+		Currency:        models.DefaultCurrency,
+	}
+}
+
+func TestExportStreamsInSortOrder(t *testing.T) {
+	storage, ctx := newTestStorage(t)
+	user := createTestUser(t, storage, ctx)
+
+	for _, price := range []int{200, 500, 100} {
+		sub := newTestSubscription(user.ID)
+		sub.Price = price
+		if err := storage.Save(ctx, sub); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	var prices []int
+	err := storage.Export(ctx, db.ListFilter{UserID: user.ID, SortBy: db.SortPrice, Desc: true}, func(sub *models.Subscription) error {
+		prices = append(prices, sub.Price)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	if len(prices) != 3 || prices[0] != 500 || prices[1] != 200 || prices[2] != 100 {
+		t.Errorf("got prices %v, want [500 200 100]", prices)
+	}
+
+	err = storage.Export(ctx, db.ListFilter{UserID: uuid.NewString()}, func(*models.Subscription) error {
+		t.Error("export of an unknown user called fn")
+		return nil
+	})
+	if !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("unknown user: got %v, want %v", err, db.ErrNotFound)
+	}
+}
+
+// TestConcurrentStorageAccess runs many goroutines against a pool much
+// smaller than their number. Run it with -race:
+//
+//	POSTGRES_HOST=localhost ... go test -race -run TestConcurrentStorageAccess ./internal/db/postgres
+func TestConcurrentStorageAccess(t *testing.T) {
+	const (
+		maxConns = 4
+		workers  = 32
+		rounds   = 20
+		users    = 4
+	)
+
+	cfg := newTestConfig(t)
+	cfg.PostgresMaxConns = maxConns
+	cfg.PostgresMinConns = 0
+
+	storage := connectTestStorage(t, cfg)
+
+	// A leaked connection starves the pool, so a hang fails the test instead
+	// of blocking it.
+	ctx, cancel := context.WithTimeout(tenant.WithID(context.Background(), newTestTenant()), 2*time.Minute)
+	defer cancel()
+
+	userIDs := make([]string, users)
+	for i := range userIDs {
+		userIDs[i] = createTestUser(t, storage, ctx).ID
+	}
+
+	shared := newTestSubscription(userIDs[0])
+	if err := storage.Save(ctx, shared); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	var (
+		wg            sync.WaitGroup
+		sharedUpdates atomic.Int64
+	)
+
+	for worker := range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			userID := userIDs[worker%users]
+
+			for round := range rounds {
+				sub := newTestSubscription(userID)
+				if err := storage.Save(ctx, sub); err != nil {
+					t.Errorf("worker %d round %d: save: %v", worker, round, err)
+					return
+				}
+
+				sub.Price = worker*rounds + round
+				if err := storage.Update(ctx, sub); err != nil {
+					t.Errorf("worker %d round %d: update: %v", worker, round, err)
+					return
+				}
+
+				got, err := storage.GetByID(ctx, sub.ID, false)
+				if err != nil {
+					t.Errorf("worker %d round %d: get: %v", worker, round, err)
+					return
+				}
+
+				if got.Price != sub.Price {
+					t.Errorf("worker %d round %d: got price %d, want %d", worker, round, got.Price, sub.Price)
+				}
+
+				if _, err := storage.List(ctx, db.ListFilter{UserID: userID, SortBy: db.SortStartDate, Limit: 10}); err != nil {
+					t.Errorf("worker %d round %d: list: %v", worker, round, err)
+					return
+				}
+
+				// Every worker also updates the shared subscription at the
+				// version it read; only one update per version may win.
+				current, err := storage.GetByID(ctx, shared.ID, false)
+				if err != nil {
+					t.Errorf("worker %d round %d: get shared: %v", worker, round, err)
+					return
+				}
+
+				current.Price++
+				err = storage.Update(ctx, current)
+				switch {
+				case err == nil:
+					sharedUpdates.Add(1)
+				case !errors.Is(err, db.ErrPreconditionFailed):
+					t.Errorf("worker %d round %d: update shared: %v", worker, round, err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if t.Failed() {
+		return
+	}
+
+	for _, userID := range userIDs {
+		count := 0
+		err := storage.Export(ctx, db.ListFilter{UserID: userID, SortBy: db.SortStartDate}, func(*models.Subscription) error {
+			count++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("export: %v", err)
+		}
+
+		want := workers / users * rounds
+		if userID == shared.UserID {
+			want++
+		}
+
+		if count != want {
+			t.Errorf("user %s: got %d subscriptions, want %d", userID, count, want)
+		}
+	}
+
+	got, err := storage.GetByID(ctx, shared.ID, false)
+	if err != nil {
+		t.Fatalf("get shared: %v", err)
+	}
+
+	if updates := int(sharedUpdates.Load()); got.Version != shared.Version+updates || got.Price != shared.Price+updates {
+		t.Errorf("shared subscription: got version %d and price %d after %d updates from version %d and price %d",
+			got.Version, got.Price, updates, shared.Version, shared.Price)
+	}
+
+	stat := storage.Stat()
+	if stat.AcquiredConns() != 0 || stat.TotalConns() > maxConns {
+		t.Errorf("pool: got %d acquired of %d connections, want 0 of at most %d", stat.AcquiredConns(), stat.TotalConns(), maxConns)
+	}
+}
+
+func TestMigratorReportsEveryMigrationApplied(t *testing.T) {
+	cfg := newTestConfig(t)
+
+	migrator, err := NewMigrator(cfg, testLogger)
+	if err != nil {
+		t.Fatalf("create migrator: %v", err)
+	}
+	defer migrator.Close()
+
+	// newTestConfig migrated up already, so a second run changes nothing.
+	if err := migrator.Up(); err != nil {
+		t.Fatalf("migrate again: %v", err)
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+
+	version, dirty, ok, err := migrator.Version()
+	if err != nil || !ok || dirty {
+		t.Fatalf("version: got %d, dirty %v, ok %v, error %v", version, dirty, ok, err)
+	}
+
+	if len(statuses) == 0 || statuses[len(statuses)-1].Version != version {
+		t.Errorf("got statuses %+v, want the last one at version %d", statuses, version)
+	}
+
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Errorf("migration %d %s is not applied", status.Version, status.Name)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"strings"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+)
+
+const subscriptionColumns = `id, service_name, service_id, price, user_id, start_date, end_date, billing_period, billing_interval, currency, category, deleted_at, version, created_at, updated_at`
+
+// billingStepSQL is the interval between two charges of the subscription
+// row aliased as s.
+const billingStepSQL = `CASE s.billing_period
+          WHEN 'weekly' THEN make_interval(weeks => s.billing_interval)
+          WHEN 'quarterly' THEN make_interval(months => 3 * s.billing_interval)
+          WHEN 'yearly' THEN make_interval(years => s.billing_interval)
+          ELSE make_interval(months => s.billing_interval)
+        END`
+
+// serviceKeySQL is db.ServiceKey of the service_name of the subscription row
+// aliased as s, as the backfill of the service catalog computes it.
+const serviceKeySQL = `lower(regexp_replace(btrim(s.service_name), '\s+', ' ', 'g'))`
+
+// costGroupColumns are the SQL expressions behind the group_by dimensions,
+// valid in queries built on chargesFrom.
+var costGroupColumns = map[string]string{
+	db.GroupByService:  "s.service_name",
+	db.GroupByCategory: "s.category",
+	db.GroupByMonth:    "to_char(charge.charged_at, 'YYYY-MM')",
+	db.GroupByYear:     "EXTRACT(YEAR FROM charge.charged_at)::int",
+	db.GroupByCurrency: "s.currency",
+}
+
+// chargesFrom returns the FROM and WHERE clauses that expand every matching
+// subscription (aliased s) of the tenant into one charge(charged_at) row per
+// charge date inside the period.
+func chargesFrom(tenantID string, filter db.CostFilter, args *queryArgs) string {
+	periodStart := args.add(filter.PeriodStart)
+	periodEnd := args.add(filter.PeriodEnd)
+
+	conditions := []string{
+		"s.tenant_id = " + args.add(tenantID),
+		"s.user_id = " + args.add(filter.UserID),
+		"s.deleted_at IS NULL",
+		fmt.Sprintf("(s.end_date IS NULL OR s.end_date > %s::date)", periodStart),
+		fmt.Sprintf("s.start_date < %s::date", periodEnd),
+		fmt.Sprintf("charge.charged_at >= %s::date", periodStart),
+	}
+
+	if len(filter.ServiceNames) > 0 {
+		keys := make([]string, 0, len(filter.ServiceNames))
+		for _, name := range filter.ServiceNames {
+			keys = append(keys, db.ServiceKey(name))
+		}
+
+		// A name matches the subscriptions linked to the catalog service it
+		// resolves to, whatever name they were saved with, and those whose
+		// own name has the same key.
+		keysArg := args.add(keys)
+		conditions = append(conditions, fmt.Sprintf(`(s.service_id IN (
+          SELECT a.service_id FROM service_aliases a
+          WHERE a.tenant_id = s.tenant_id AND a.alias_key = ANY(%[1]s::text[])
+        ) OR %[2]s = ANY(%[1]s::text[]))`, keysArg, serviceKeySQL))
+	}
+
+	return fmt.Sprintf(`
+      FROM subscriptions s
+      CROSS JOIN LATERAL generate_series(
+        s.start_date::timestamp,
+        (LEAST(COALESCE(s.end_date, %[1]s::date), %[1]s::date) - 1)::timestamp,
+        %[2]s
+      ) AS charge(charged_at)`, periodEnd, billingStepSQL) + whereClause(conditions)
+}
+
+// scanSubscription reads one row selected with subscriptionColumns.
+func scanSubscription(row pgx.Row) (*models.Subscription, error) {
+	var sub models.Subscription
+	if err := row.Scan(
+		&sub.ID,
+		&sub.ServiceName,
+		&sub.ServiceID,
+		&sub.Price,
+		&sub.UserID,
+		&sub.StartDate,
+		&sub.EndDate,
+		&sub.BillingPeriod,
+		&sub.BillingInterval,
+		&sub.Currency,
+		&sub.Category,
+		&sub.DeletedAt,
+		&sub.Version,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &sub, nil
+}
+
+// queryArgs collects positional arguments while a query is being assembled.
+type queryArgs struct {
+	values []any
+}
+
+func (a *queryArgs) add(value any) string {
+	a.values = append(a.values, value)
+	return fmt.Sprintf("$%d", len(a.values))
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// listConditions turns the filter part of a ListFilter into SQL conditions
+// on the subscriptions of the tenant.
+func listConditions(tenantID string, filter db.ListFilter, args *queryArgs) []string {
+	conditions := []string{"tenant_id = " + args.add(tenantID)}
+
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	if filter.UserID != "" {
+		conditions = append(conditions, "user_id = "+args.add(filter.UserID))
+	}
+
+	if filter.ServiceName != "" {
+		conditions = append(conditions, "service_name = "+args.add(filter.ServiceName))
+	}
+
+	if filter.ActiveAt != nil {
+		activeAt := args.add(*filter.ActiveAt)
+		conditions = append(conditions, fmt.Sprintf("start_date <= %s::date AND (end_date IS NULL OR end_date > %s::date)", activeAt, activeAt))
+	}
+
+	if filter.MinPrice != nil {
+		conditions = append(conditions, "price >= "+args.add(*filter.MinPrice))
+	}
+
+	if filter.MaxPrice != nil {
+		conditions = append(conditions, "price <= "+args.add(*filter.MaxPrice))
+	}
+
+	if filter.StartFrom != nil {
+		conditions = append(conditions, "start_date >= "+args.add(*filter.StartFrom)+"::date")
+	}
+
+	if filter.StartTo != nil {
+		conditions = append(conditions, "start_date <= "+args.add(*filter.StartTo)+"::date")
+	}
+
+	if filter.EndFrom != nil {
+		conditions = append(conditions, "end_date >= "+args.add(*filter.EndFrom)+"::date")
+	}
+
+	if filter.EndTo != nil {
+		conditions = append(conditions, "end_date <= "+args.add(*filter.EndTo)+"::date")
+	}
+
+	return conditions
+}
+
+// sortColumn returns the column for a sort field and the type its cursor
+// value is cast to.
+func sortColumn(sortBy string) (string, string) {
+	switch sortBy {
+	case db.SortPrice:
+		return "price", "int"
+	case db.SortServiceName:
+		return "service_name", "text"
+	default:
+		return "start_date", "date"
+	}
+}
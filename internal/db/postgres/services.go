@@ -0,0 +1,229 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
+)
+
+// serviceSelect selects services with their aliases; the caller adds the
+// WHERE clause, which names the tenant, and GROUP BY s.id.
+const serviceSelect = `
+      SELECT s.id, s.name, s.category, s.default_price, s.website, s.created_at, s.updated_at,
+        COALESCE(array_agg(a.alias ORDER BY a.alias) FILTER (WHERE NOT a.is_name), '{}')
+      FROM services s
+      LEFT JOIN service_aliases a ON a.service_id = s.id`
+
+func (s *Storage) CreateService(ctx context.Context, svc *models.Service) error {
+	sql := `INSERT INTO services (id, name, category, default_price, website, tenant_id) VALUES ($1, $2, $3, $4, $5, $6)`
+
+	err := pgx.BeginFunc(ctx, s.database, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, sql, svc.ID, svc.Name, svc.Category, svc.DefaultPrice, svc.Website, tenant.ID(ctx)); err != nil {
+			return err
+		}
+
+		if err := insertServiceAliases(ctx, tx, svc); err != nil {
+			return err
+		}
+
+		return reloadService(ctx, tx, svc)
+	})
+	if err != nil {
+		s.logger.Error("Unable to save service", "error", err, "id", svc.ID)
+		return fmt.Errorf("unable to save service: %w", mapError(err))
+	}
+
+	s.logger.Info("Service saved successfully", "ID", svc.ID)
+
+	return nil
+}
+
+func (s *Storage) GetService(ctx context.Context, id string) (*models.Service, error) {
+	svc, err := scanService(s.database.QueryRow(ctx, serviceSelect+` WHERE s.id = $1 AND s.tenant_id = $2 GROUP BY s.id`, id, tenant.ID(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Error("Failed to find service", "error", err, "id", id)
+			return nil, db.ErrNotFound
+		}
+
+		s.logger.Error("Failed to get service", "error", err)
+		return nil, fmt.Errorf("failed to get service by id: %w", mapError(err))
+	}
+
+	return svc, nil
+}
+
+func (s *Storage) ListServices(ctx context.Context) ([]models.Service, error) {
+	rows, err := s.database.Query(ctx, serviceSelect+` WHERE s.tenant_id = $1 GROUP BY s.id ORDER BY s.name, s.id`, tenant.ID(ctx))
+	if err != nil {
+		s.logger.Error("Failed to list services", "error", err)
+		return nil, fmt.Errorf("failed to list services: %w", mapError(err))
+	}
+
+	defer rows.Close()
+
+	services := []models.Service{}
+	for rows.Next() {
+		svc, err := scanService(rows)
+		if err != nil {
+			s.logger.Error("Failed to scan service row", "error", err)
+			return nil, err
+		}
+
+		services = append(services, *svc)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error rows iterations", "error", err)
+		return nil, err
+	}
+
+	return services, nil
+}
+
+func (s *Storage) UpdateService(ctx context.Context, svc *models.Service) error {
+	sql := `UPDATE services SET name = $1, category = $2, default_price = $3, website = $4, updated_at = now() WHERE id = $5 AND tenant_id = $6`
+
+	err := pgx.BeginFunc(ctx, s.database, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, sql, svc.Name, svc.Category, svc.DefaultPrice, svc.Website, svc.ID, tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return db.ErrNotFound
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM service_aliases WHERE service_id = $1 AND tenant_id = $2`, svc.ID, tenant.ID(ctx)); err != nil {
+			return err
+		}
+
+		if err := insertServiceAliases(ctx, tx, svc); err != nil {
+			return err
+		}
+
+		return reloadService(ctx, tx, svc)
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			s.logger.Error("Failed to find service for update", "id", svc.ID)
+			return err
+		}
+
+		s.logger.Error("Failed to update service", "error", err)
+		return fmt.Errorf("failed to update service: %w", mapError(err))
+	}
+
+	s.logger.Info("Service updated successfully", "ID", svc.ID)
+
+	return nil
+}
+
+func (s *Storage) DeleteService(ctx context.Context, id string) error {
+	result, err := s.database.Exec(ctx, `DELETE FROM services WHERE id = $1 AND tenant_id = $2`, id, tenant.ID(ctx))
+	if err != nil {
+		s.logger.Error("Failed to delete service", "error", err)
+		return fmt.Errorf("failed to delete service: %w", mapError(err))
+	}
+
+	if result.RowsAffected() == 0 {
+		s.logger.Error("Failed to find service", "id", id)
+		return db.ErrNotFound
+	}
+
+	s.logger.Info("Service deleted successfully", "ID", id)
+	return nil
+}
+
+// insertServiceAliases claims the name and aliases of svc. A key that is
+// already taken by another service fails with a unique violation.
+func insertServiceAliases(ctx context.Context, tx pgx.Tx, svc *models.Service) error {
+	sql := `INSERT INTO service_aliases (alias_key, service_id, alias, is_name, tenant_id) VALUES ($1, $2, $3, $4, $5)`
+
+	tenantID := tenant.ID(ctx)
+
+	batch := &pgx.Batch{}
+	batch.Queue(sql, db.ServiceKey(svc.Name), svc.ID, svc.Name, true, tenantID)
+	for _, alias := range svc.Aliases {
+		batch.Queue(sql, db.ServiceKey(alias), svc.ID, alias, false, tenantID)
+	}
+
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// reloadService fills svc with its stored row inside tx.
+func reloadService(ctx context.Context, tx pgx.Tx, svc *models.Service) error {
+	stored, err := scanService(tx.QueryRow(ctx, serviceSelect+` WHERE s.id = $1 AND s.tenant_id = $2 GROUP BY s.id`, svc.ID, tenant.ID(ctx)))
+	if err != nil {
+		return err
+	}
+
+	*svc = *stored
+
+	return nil
+}
+
+// resolveServices looks up the services the names of subs resolve to in one
+// query and links every subscription with db.ResolveService.
+func resolveServices(ctx context.Context, tx pgx.Tx, subs ...*models.Subscription) error {
+	sql := `
+      SELECT a.alias_key, s.id, s.name, s.category
+      FROM service_aliases a
+      JOIN services s ON s.id = a.service_id
+      WHERE a.alias_key = ANY($1::text[]) AND a.tenant_id = $2`
+
+	keys := make([]string, 0, len(subs))
+	for _, sub := range subs {
+		keys = append(keys, db.ServiceKey(sub.ServiceName))
+	}
+
+	rows, err := tx.Query(ctx, sql, keys, tenant.ID(ctx))
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	services := make(map[string]*models.Service)
+	for rows.Next() {
+		var key string
+		var svc models.Service
+		if err := rows.Scan(&key, &svc.ID, &svc.Name, &svc.Category); err != nil {
+			return err
+		}
+
+		services[key] = &svc
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i, sub := range subs {
+		db.ResolveService(sub, services[keys[i]])
+	}
+
+	return nil
+}
+
+func scanService(row pgx.Row) (*models.Service, error) {
+	var svc models.Service
+	if err := row.Scan(
+		&svc.ID,
+		&svc.Name,
+		&svc.Category,
+		&svc.DefaultPrice,
+		&svc.Website,
+		&svc.CreatedAt,
+		&svc.UpdatedAt,
+		&svc.Aliases,
+	); err != nil {
+		return nil, err
+	}
+
+	return &svc, nil
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"subscription-aggregator/internal/models"
+	"time"
+)
+
+// monthlyAmountSQL is billing.MonthlyAmount for the subscription row aliased
+// as s.
+const monthlyAmountSQL = `CASE s.billing_period
+          WHEN 'weekly' THEN s.price * 52.0 / 12
+          WHEN 'quarterly' THEN s.price / 3.0
+          WHEN 'yearly' THEN s.price / 12.0
+          ELSE s.price::numeric
+        END / GREATEST(s.billing_interval, 1)`
+
+func (s *Storage) ServiceRevenue(ctx context.Context, at time.Time) ([]models.ServiceRevenue, error) {
+	sql := `
+      SELECT
+        s.service_name,
+        s.currency,
+        COUNT(*) AS active,
+        SUM(` + monthlyAmountSQL + `)::float8 AS monthly_revenue
+      FROM subscriptions s
+      WHERE s.deleted_at IS NULL
+        AND s.start_date <= $1::date
+        AND (s.end_date IS NULL OR s.end_date > $1::date)
+      GROUP BY 1, 2
+      ORDER BY 1, 2`
+
+	rows, err := s.database.Query(withAllTenants(ctx), sql, at)
+	if err != nil {
+		s.logger.Error("Failed to get service revenue", "error", err)
+		return nil, fmt.Errorf("failed to get service revenue: %w", mapError(err))
+	}
+
+	defer rows.Close()
+
+	var revenues []models.ServiceRevenue
+	for rows.Next() {
+		var revenue models.ServiceRevenue
+		var active int64
+		if err := rows.Scan(&revenue.ServiceName, &revenue.Currency, &active, &revenue.MonthlyRevenue); err != nil {
+			s.logger.Error("Failed to scan service revenue row", "error", err)
+			return nil, err
+		}
+
+		revenue.Active = int(active)
+		revenues = append(revenues, revenue)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error rows iterations", "error", err)
+		return nil, err
+	}
+
+	return revenues, nil
+}
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"subscription-aggregator/internal/tenant"
+)
+
+// Every query names the tenant of its context itself. The row-level security
+// policies of migration 000015 back that up for roles they apply to: a
+// connection only sees the rows of the tenant in app.tenant_id, or of every
+// tenant when app.all_tenants is on.
+
+type allTenantsKey struct{}
+
+// withAllTenants marks ctx for the queries that span tenants: the purges and
+// the API key lookup that finds the tenant of a request in the first place.
+func withAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allTenantsKey{}, true)
+}
+
+func allTenants(ctx context.Context) string {
+	if ctx.Value(allTenantsKey{}) != nil {
+		return "on"
+	}
+
+	return "off"
+}
+
+// setTenantSQL sets the policy settings for the current transaction only, so
+// they never outlive it on a pooled connection.
+const setTenantSQL = `SELECT set_config('app.tenant_id', $1, true), set_config('app.all_tenants', $2, true)`
+
+// tenantPool runs every statement as the tenant of its context. A
+// transaction sets the settings with setTenantSQL right after its BEGIN. A
+// statement outside a transaction is sent in one pipeline behind
+// setTenantSQL, without a round trip of its own; up to its Sync the pipeline
+// is a single implicit transaction, so the settings cover that statement
+// alone.
+type tenantPool struct {
+	pool *pgxpool.Pool
+}
+
+func (p *tenantPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, setTenantSQL, tenant.ID(ctx), allTenants(ctx)); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+func (p *tenantPool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	results := p.sendBatch(ctx, sql, args)
+
+	tag, err := results.Exec()
+	if closeErr := results.Close(); err == nil {
+		err = closeErr
+	}
+
+	return tag, err
+}
+
+func (p *tenantPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	results := p.sendBatch(ctx, sql, args)
+
+	rows, err := results.Query()
+	if err != nil {
+		_ = results.Close()
+		return nil, err
+	}
+
+	return &tenantRows{Rows: rows, results: results}, nil
+}
+
+func (p *tenantPool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	rows, err := p.Query(ctx, sql, args...)
+	return &tenantRow{rows: rows, err: err}
+}
+
+func (p *tenantPool) Ping(ctx context.Context) error {
+	return p.pool.Ping(ctx)
+}
+
+func (p *tenantPool) Stat() *pgxpool.Stat {
+	return p.pool.Stat()
+}
+
+func (p *tenantPool) Close() {
+	p.pool.Close()
+}
+
+// sendBatch sends setTenantSQL and the statement together and returns the
+// results of the statement.
+func (p *tenantPool) sendBatch(ctx context.Context, sql string, args []any) pgx.BatchResults {
+	batch := &pgx.Batch{}
+	batch.Queue(setTenantSQL, tenant.ID(ctx), allTenants(ctx))
+	batch.Queue(sql, args...)
+
+	results := p.pool.SendBatch(ctx, batch)
+	if _, err := results.Exec(); err != nil {
+		_ = results.Close()
+		return failedBatch{err: err}
+	}
+
+	return results
+}
+
+// tenantRows releases the connection of the batch along with the rows.
+type tenantRows struct {
+	pgx.Rows
+	results pgx.BatchResults
+}
+
+func (r *tenantRows) Close() {
+	r.Rows.Close()
+	_ = r.results.Close()
+}
+
+// tenantRow is pgx.Row on top of tenantRows.
+type tenantRow struct {
+	rows pgx.Rows
+	err  error
+}
+
+func (r *tenantRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	defer r.rows.Close()
+
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+
+		return pgx.ErrNoRows
+	}
+
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+
+	r.rows.Close()
+
+	return r.rows.Err()
+}
+
+// failedBatch reports the error of setTenantSQL for the statement behind it.
+type failedBatch struct {
+	err error
+}
+
+func (b failedBatch) Exec() (pgconn.CommandTag, error) { return pgconn.CommandTag{}, b.err }
+func (b failedBatch) Query() (pgx.Rows, error)         { return nil, b.err }
+func (b failedBatch) QueryRow() pgx.Row                { return &tenantRow{err: b.err} }
+func (b failedBatch) Close() error                     { return b.err }
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/tenant"
+	"testing"
+)
+
+// TestNewRefusesBypassingRole connects as the login role itself. In the
+// usual setup that is the superuser that owns the tables, which the tenant
+// policies do not apply to.
+func TestNewRefusesBypassingRole(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.PostgresAppRole = ""
+
+	storage, err := New(context.Background(), cfg, testLogger)
+	if err == nil {
+		storage.Close()
+		t.Skipf("login role %s does not bypass row-level security", cfg.PostgresUser)
+	}
+
+	if !strings.Contains(err.Error(), "bypasses row-level security") {
+		t.Errorf("got %v, want a row-level security error", err)
+	}
+}
+
+func TestRowLevelSecurity(t *testing.T) {
+	// The storage runs as POSTGRES_APP_ROLE, which New has checked the
+	// policies apply to.
+	storage, ctx := newTestStorage(t)
+
+	user := createTestUser(t, storage, ctx)
+	sub := newTestSubscription(user.ID)
+	if err := storage.Save(ctx, sub); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	owner := tenant.ID(ctx)
+	other := tenant.WithID(context.Background(), newTestTenant())
+
+	// The queries name no tenant, so only the policies keep tenants apart.
+	const countSQL = `SELECT count(*) FROM subscriptions WHERE id = $1`
+
+	count := func(ctx context.Context) int {
+		t.Helper()
+
+		var n int
+		if err := storage.database.QueryRow(ctx, countSQL, sub.ID).Scan(&n); err != nil {
+			t.Fatalf("count: %v", err)
+		}
+
+		return n
+	}
+
+	countInTx := func(ctx context.Context) int {
+		t.Helper()
+
+		var n int
+		err := pgx.BeginFunc(ctx, storage.database, func(tx pgx.Tx) error {
+			return tx.QueryRow(ctx, countSQL, sub.ID).Scan(&n)
+		})
+		if err != nil {
+			t.Fatalf("count in transaction: %v", err)
+		}
+
+		return n
+	}
+
+	tests := []struct {
+		name  string
+		count func(context.Context) int
+		ctx   context.Context
+		want  int
+	}{
+		{name: "own tenant", count: count, ctx: ctx, want: 1},
+		{name: "other tenant", count: count, ctx: other, want: 0},
+		{name: "all tenants", count: count, ctx: withAllTenants(other), want: 1},
+		{name: "own tenant in a transaction", count: countInTx, ctx: ctx, want: 1},
+		{name: "other tenant in a transaction", count: countInTx, ctx: other, want: 0},
+		{name: "all tenants in a transaction", count: countInTx, ctx: withAllTenants(other), want: 1},
+		// The settings of the transactions above must not stay on the
+		// connection for the next one.
+		{name: "other tenant after the own tenant", count: count, ctx: other, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.count(tt.ctx); got != tt.want {
+			t.Errorf("%s: got %d rows, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := storage.GetByID(other, sub.ID, false); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("get from another tenant: got %v, want %v", err, db.ErrNotFound)
+	}
+
+	if _, err := storage.GetByID(ctx, sub.ID, false); err != nil {
+		t.Errorf("get from the own tenant: %v", err)
+	}
+
+	// Between uses the connection holds no tenant at all.
+	var setting string
+	err := storage.database.pool.QueryRow(context.Background(), "SELECT coalesce(current_setting('app.tenant_id', true), '')").Scan(&setting)
+	if err != nil {
+		t.Fatalf("read setting: %v", err)
+	}
+
+	if setting != "" {
+		t.Errorf("app.tenant_id left on the connection: %q", setting)
+	}
+
+	var n int
+	if err := storage.database.pool.QueryRow(context.Background(), "SELECT count(*) FROM subscriptions").Scan(&n); err != nil {
+		t.Fatalf("count without a tenant: %v", err)
+	}
+
+	if n != 0 {
+		t.Errorf("without a tenant: got %d rows, want 0", n)
+	}
+
+	// A write cannot put rows into another tenant either.
+	_, err = storage.database.Exec(other, `UPDATE subscriptions SET tenant_id = $1 WHERE id = $2`, owner, sub.ID)
+	if err != nil {
+		t.Fatalf("update from another tenant: %v", err)
+	}
+
+	_, err = storage.database.Exec(ctx, `UPDATE subscriptions SET tenant_id = $1 WHERE id = $2`, tenant.ID(other), sub.ID)
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "42501" {
+		t.Errorf("move to another tenant: got %v, want a row-level security violation", err)
+	}
+
+	if got := count(ctx); got != 1 {
+		t.Errorf("after the writes: got %d rows, want 1", got)
+	}
+}
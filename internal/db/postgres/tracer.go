@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+const tracerName = "subscription-aggregator/internal/db/postgres"
+
+// queryTracer opens a client span for every query, batch and COPY a
+// connection runs, as a child of the span in the query's context. Query
+// arguments are left out of the spans, since they carry user data.
+type queryTracer struct {
+	tracer trace.Tracer
+}
+
+func newQueryTracer() *queryTracer {
+	return &queryTracer{tracer: otel.Tracer(tracerName)}
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := sqlOperation(data.SQL)
+
+	ctx, _ = t.tracer.Start(ctx, operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemPostgreSQL,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(data.SQL),
+	))
+
+	return ctx
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	endSpan(ctx, data.CommandTag.RowsAffected(), data.Err)
+}
+
+// TraceBatchStart names the batch that tenantPool sends for a single
+// statement after that statement.
+func (t *queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	if queries := data.Batch.QueuedQueries; len(queries) == 2 && queries[0].SQL == setTenantSQL {
+		operation := sqlOperation(queries[1].SQL)
+
+		ctx, _ = t.tracer.Start(ctx, operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(queries[1].SQL),
+		))
+
+		return ctx
+	}
+
+	ctx, _ = t.tracer.Start(ctx, "BATCH", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemPostgreSQL,
+		semconv.DBOperationName("BATCH"),
+		attribute.Int("db.batch.size", data.Batch.Len()),
+	))
+
+	return ctx
+}
+
+// TraceBatchQuery records the queries of a batch as events of its span: they
+// are sent together, so they have no timing of their own.
+func (t *queryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	attributes := []attribute.KeyValue{semconv.DBQueryText(data.SQL)}
+	if data.Err != nil {
+		attributes = append(attributes, attribute.String("error", data.Err.Error()))
+	}
+
+	trace.SpanFromContext(ctx).AddEvent("query", trace.WithAttributes(attributes...))
+}
+
+func (t *queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	endSpan(ctx, -1, data.Err)
+}
+
+func (t *queryTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	ctx, _ = t.tracer.Start(ctx, "COPY", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemPostgreSQL,
+		semconv.DBOperationName("COPY"),
+		semconv.DBCollectionName(data.TableName.Sanitize()),
+	))
+
+	return ctx
+}
+
+func (t *queryTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	endSpan(ctx, data.CommandTag.RowsAffected(), data.Err)
+}
+
+// endSpan ends the span of ctx; a negative rows leaves the row count out.
+func endSpan(ctx context.Context, rows int64, err error) {
+	span := trace.SpanFromContext(ctx)
+	if rows >= 0 {
+		span.SetAttributes(attribute.Int64("db.rows_affected", rows))
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// sqlOperation returns the first keyword of a statement, such as SELECT.
+func sqlOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+
+	return strings.ToUpper(fields[0])
+}
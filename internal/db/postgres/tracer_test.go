@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+func TestSQLOperation(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{sql: "SELECT 1", want: "SELECT"},
+		{sql: "\n      update subscriptions SET price = 1", want: "UPDATE"},
+		{sql: "", want: "QUERY"},
+	}
+
+	for _, tt := range tests {
+		if got := sqlOperation(tt.sql); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestQueryTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := &queryTracer{tracer: provider.Tracer(tracerName)}
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+
+	tenantBatch := &pgx.Batch{}
+	tenantBatch.Queue(setTenantSQL, "default", "off")
+	tenantBatch.Queue("DELETE FROM users WHERE id = $1", "42")
+
+	batch := &pgx.Batch{}
+	batch.Queue("SELECT 1")
+	batch.Queue("SELECT 2")
+
+	failure := errors.New("boom")
+
+	tests := []struct {
+		name      string
+		trace     func()
+		wantName  string
+		wantQuery string
+		wantError bool
+	}{
+		{
+			name: "query",
+			trace: func() {
+				ctx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT * FROM subscriptions"})
+				tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 3")})
+			},
+			wantName:  "SELECT",
+			wantQuery: "SELECT * FROM subscriptions",
+		},
+		{
+			name: "failed query",
+			trace: func() {
+				ctx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "INSERT INTO users VALUES ($1)"})
+				tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: failure})
+			},
+			wantName:  "INSERT",
+			wantQuery: "INSERT INTO users VALUES ($1)",
+			wantError: true,
+		},
+		{
+			name: "statement of the tenant pool",
+			trace: func() {
+				ctx := tracer.TraceBatchStart(ctx, nil, pgx.TraceBatchStartData{Batch: tenantBatch})
+				tracer.TraceBatchEnd(ctx, nil, pgx.TraceBatchEndData{})
+			},
+			wantName:  "DELETE",
+			wantQuery: "DELETE FROM users WHERE id = $1",
+		},
+		{
+			name: "batch",
+			trace: func() {
+				ctx := tracer.TraceBatchStart(ctx, nil, pgx.TraceBatchStartData{Batch: batch})
+				tracer.TraceBatchQuery(ctx, nil, pgx.TraceBatchQueryData{SQL: "SELECT 1"})
+				tracer.TraceBatchEnd(ctx, nil, pgx.TraceBatchEndData{Err: failure})
+			},
+			wantName:  "BATCH",
+			wantError: true,
+		},
+		{
+			name: "copy",
+			trace: func() {
+				ctx := tracer.TraceCopyFromStart(ctx, nil, pgx.TraceCopyFromStartData{TableName: pgx.Identifier{"subscription_events"}})
+				tracer.TraceCopyFromEnd(ctx, nil, pgx.TraceCopyFromEndData{CommandTag: pgconn.NewCommandTag("COPY 2")})
+			},
+			wantName: "COPY",
+		},
+	}
+
+	for _, tt := range tests {
+		before := len(recorder.Ended())
+		tt.trace()
+
+		spans := recorder.Ended()
+		if len(spans) != before+1 {
+			t.Errorf("%s: got %d spans, want 1", tt.name, len(spans)-before)
+			continue
+		}
+
+		span := spans[before]
+
+		if span.Name() != tt.wantName {
+			t.Errorf("%s: got span %q, want %q", tt.name, span.Name(), tt.wantName)
+		}
+
+		if span.SpanKind() != trace.SpanKindClient {
+			t.Errorf("%s: got kind %v, want client", tt.name, span.SpanKind())
+		}
+
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s: span is not a child of the request span", tt.name)
+		}
+
+		var query string
+		for _, kv := range span.Attributes() {
+			if kv.Key == attribute.Key("db.query.text") {
+				query = kv.Value.AsString()
+			}
+		}
+
+		if query != tt.wantQuery {
+			t.Errorf("%s: got query %q, want %q", tt.name, query, tt.wantQuery)
+		}
+
+		if got := span.Status().Code == codes.Error; got != tt.wantError {
+			t.Errorf("%s: got error status %v, want %v", tt.name, got, tt.wantError)
+		}
+	}
+
+	parent.End()
+}
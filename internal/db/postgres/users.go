@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"subscription-aggregator/internal/audit"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
+)
+
+const userColumns = `id, display_name, COALESCE(email, ''), default_currency, timezone, created_at, updated_at`
+
+func (s *Storage) CreateUser(ctx context.Context, user *models.User) error {
+	sql := `
+      INSERT INTO users (id, display_name, email, default_currency, timezone, tenant_id) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+      RETURNING ` + userColumns
+
+	saved, err := scanUser(s.database.QueryRow(ctx, sql, user.ID, user.DisplayName, user.Email, user.DefaultCurrency, user.Timezone, tenant.ID(ctx)))
+	if err != nil {
+		s.logger.Error("Unable to save user", "error", err, "id", user.ID)
+		return fmt.Errorf("unable to save user: %w", mapError(err))
+	}
+
+	*user = *saved
+
+	s.logger.Info("User saved successfully", "ID", user.ID)
+
+	return nil
+}
+
+func (s *Storage) GetUser(ctx context.Context, id string) (*models.User, error) {
+	sql := `SELECT ` + userColumns + ` FROM users WHERE id = $1 AND tenant_id = $2`
+
+	user, err := scanUser(s.database.QueryRow(ctx, sql, id, tenant.ID(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Error("Failed to find user", "error", err, "id", id)
+			return nil, db.ErrNotFound
+		}
+
+		s.logger.Error("Failed to get user", "error", err)
+		return nil, fmt.Errorf("failed to get user by id: %w", mapError(err))
+	}
+
+	return user, nil
+}
+
+func (s *Storage) ListUsers(ctx context.Context) ([]models.User, error) {
+	sql := `SELECT ` + userColumns + ` FROM users WHERE tenant_id = $1 ORDER BY created_at, id`
+
+	rows, err := s.database.Query(ctx, sql, tenant.ID(ctx))
+	if err != nil {
+		s.logger.Error("Failed to list users", "error", err)
+		return nil, fmt.Errorf("failed to list users: %w", mapError(err))
+	}
+
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			s.logger.Error("Failed to scan user row", "error", err)
+			return nil, err
+		}
+
+		users = append(users, *user)
+	}
+
+	if err := rows.Err(); err != nil {
+		s.logger.Error("Error rows iterations", "error", err)
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (s *Storage) UpdateUser(ctx context.Context, user *models.User) error {
+	sql := `
+      UPDATE users SET display_name = $1, email = NULLIF($2, ''), default_currency = $3, timezone = $4, updated_at = now()
+      WHERE id = $5 AND tenant_id = $6
+      RETURNING ` + userColumns
+
+	updated, err := scanUser(s.database.QueryRow(ctx, sql, user.DisplayName, user.Email, user.DefaultCurrency, user.Timezone, user.ID, tenant.ID(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Error("Failed to find user for update", "id", user.ID)
+			return db.ErrNotFound
+		}
+
+		s.logger.Error("Failed to update user", "error", err)
+		return fmt.Errorf("failed to update user: %w", mapError(err))
+	}
+
+	*user = *updated
+
+	s.logger.Info("User updated successfully", "ID", user.ID)
+
+	return nil
+}
+
+// DeleteUser relies on the foreign key of subscriptions to remove the
+// subscriptions of the user, after recording a deleted event for each live
+// one in the same transaction; soft-deleted ones were recorded when they
+// were deleted.
+func (s *Storage) DeleteUser(ctx context.Context, id string) error {
+	sql := `DELETE FROM users WHERE id = $1 AND tenant_id = $2`
+
+	err := pgx.BeginFunc(ctx, s.database, func(tx pgx.Tx) error {
+		subs, err := lockUserSubscriptions(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(ctx, sql, id, tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return db.ErrNotFound
+		}
+
+		for _, sub := range subs {
+			if err := recordEvent(ctx, tx, sub.ID, audit.ActionDeleted, sub, nil); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			s.logger.Error("Failed to find user", "id", id)
+			return err
+		}
+
+		s.logger.Error("Failed to delete user", "error", err)
+		return fmt.Errorf("failed to delete user: %w", mapError(err))
+	}
+
+	s.logger.Info("User deleted successfully", "ID", id)
+	return nil
+}
+
+// lockUserSubscriptions reads the live subscriptions of a user inside tx and
+// locks their rows until the transaction ends.
+func lockUserSubscriptions(ctx context.Context, tx pgx.Tx, userID string) ([]*models.Subscription, error) {
+	sql := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE user_id = $1 AND tenant_id = $2 AND deleted_at IS NULL FOR UPDATE`
+
+	rows, err := tx.Query(ctx, sql, userID, tenant.ID(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var subs []*models.Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		subs = append(subs, sub)
+	}
+
+	return subs, rows.Err()
+}
+
+// userExists returns ErrNotFound when there is no user with the given ID.
+func (s *Storage) userExists(ctx context.Context, id string) error {
+	sql := `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND tenant_id = $2)`
+
+	var exists bool
+	if err := s.database.QueryRow(ctx, sql, id, tenant.ID(ctx)).Scan(&exists); err != nil {
+		s.logger.Error("Failed to check user", "error", err, "user_id", id)
+		return fmt.Errorf("failed to check user: %w", mapError(err))
+	}
+
+	if !exists {
+		s.logger.Error("Failed to find user", "user_id", id)
+		return db.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanUser(row pgx.Row) (*models.User, error) {
+	var user models.User
+	if err := row.Scan(
+		&user.ID,
+		&user.DisplayName,
+		&user.Email,
+		&user.DefaultCurrency,
+		&user.Timezone,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
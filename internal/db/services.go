@@ -0,0 +1,31 @@
+package db
+
+import (
+	"strings"
+	"subscription-aggregator/internal/models"
+)
+
+// ServiceKey is the form in which service names and aliases are compared:
+// lower case with runs of spaces collapsed, so "Netflix", "netflix " and
+// "NETFLIX" are the same service.
+func ServiceKey(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// ResolveService links sub to the catalog service its name resolved to, or
+// unlinks it when svc is nil. A linked subscription takes the name of the
+// service and, when it has none of its own, its category.
+func ResolveService(sub *models.Subscription, svc *models.Service) {
+	if svc == nil {
+		sub.ServiceID = nil
+		return
+	}
+
+	id := svc.ID
+	sub.ServiceID = &id
+	sub.ServiceName = svc.Name
+
+	if sub.Category == "" {
+		sub.Category = svc.Category
+	}
+}
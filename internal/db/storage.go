@@ -3,20 +3,161 @@ package db
 import (
 	"context"
 	"errors"
+	"fmt"
 	"subscription-aggregator/internal/models"
 	"time"
 )
 
 type SubscriptionStorage interface {
+	// Save stores a new subscription and fills sub with the stored row,
+	// including the fields the backend generates.
 	Save(ctx context.Context, sub *models.Subscription) error
+	// Delete soft-deletes a subscription: it disappears from every read path
+	// until it is restored or purged.
 	Delete(ctx context.Context, id string) error
-	GetByID(ctx context.Context, id string) (*models.Subscription, error)
-	List(ctx context.Context, userID string) ([]*models.Subscription, error)
+	Restore(ctx context.Context, id string) error
+	// Purge permanently removes subscriptions of every tenant soft-deleted
+	// before the given time and returns how many were removed.
+	Purge(ctx context.Context, deletedBefore time.Time) (int, error)
+	GetByID(ctx context.Context, id string, includeDeleted bool) (*models.Subscription, error)
+	// List returns a page of subscriptions, or ErrNotFound when
+	// filter.UserID names a user that does not exist.
+	List(ctx context.Context, filter ListFilter) (*models.SubscriptionPage, error)
+	// Export calls fn for every subscription that matches filter, in its
+	// sort order, in a single pass that neither pages nor counts.
+	// filter.Limit and filter.Cursor are ignored. It fails like List for an
+	// unknown user, before fn is called; an error of fn stops the export and
+	// is returned as is.
+	Export(ctx context.Context, filter ListFilter, fn func(*models.Subscription) error) error
+	// Update overwrites a subscription and bumps its version. A non-zero
+	// sub.Version is the version the caller last saw: the update fails with
+	// ErrPreconditionFailed when the stored one differs. On success sub is
+	// filled with the stored row, including its new version.
 	Update(ctx context.Context, sub *models.Subscription) error
-	SumTotalCost(ctx context.Context, userID string, serviceName string,
-		periodStart time.Time, periodEnd time.Time) (int, error)
+	// ApplyBatch runs the operations in order and reports the outcome of
+	// each. In atomic mode the first failure rolls back the whole batch;
+	// otherwise every operation stands on its own. The error is only set when
+	// the batch could not run at all.
+	ApplyBatch(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, error)
+	// SumTotalCost adds up the charges of the period per combination of
+	// filter.GroupBy, which may include GroupByCurrency.
+	SumTotalCost(ctx context.Context, filter CostFilter) ([]models.CostGroup, error)
+	// MonthlyCharges sums the charges of a user's subscriptions per month,
+	// service, category and currency. filter.GroupBy is ignored.
+	MonthlyCharges(ctx context.Context, filter CostFilter) ([]models.MonthlyCharge, error)
 }
 
+type FXRateStorage interface {
+	SaveRates(ctx context.Context, rates []models.FXRate) error
+	ListRates(ctx context.Context) ([]models.FXRate, error)
+	// GetRate returns the newest rate of the pair that is valid in month.
+	GetRate(ctx context.Context, baseCurrency string, quoteCurrency string, month time.Time) (*models.FXRate, error)
+}
+
+// UserStorage manages the users that own subscriptions. Save and Update of
+// a subscription fail with ErrUserNotFound when its user does not exist.
+type UserStorage interface {
+	// CreateUser stores a new user and fills user with the stored row. It
+	// fails with ErrConflict when the ID or email is already taken.
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUser(ctx context.Context, id string) (*models.User, error)
+	ListUsers(ctx context.Context) ([]models.User, error)
+	// UpdateUser overwrites a user and fills user with the stored row.
+	UpdateUser(ctx context.Context, user *models.User) error
+	// DeleteUser removes a user together with all of their subscriptions.
+	DeleteUser(ctx context.Context, id string) error
+}
+
+// ServiceStorage manages the service catalog. Save and Update of a
+// subscription resolve its service_name against the catalog.
+type ServiceStorage interface {
+	// CreateService stores a new service and fills svc with the stored row.
+	// It fails with ErrConflict when the name or an alias is already taken.
+	CreateService(ctx context.Context, svc *models.Service) error
+	GetService(ctx context.Context, id string) (*models.Service, error)
+	ListServices(ctx context.Context) ([]models.Service, error)
+	// UpdateService overwrites a service and its aliases and fills svc with
+	// the stored row. Subscriptions already linked to it keep their name.
+	UpdateService(ctx context.Context, svc *models.Service) error
+	// DeleteService removes a service; its subscriptions keep their name and
+	// lose the link.
+	DeleteService(ctx context.Context, id string) error
+}
+
+// APIKeyStorage keeps the API keys of services. Only the hash of a key is
+// stored.
+type APIKeyStorage interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey, hash string) error
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	// RevokeAPIKey disables a key for good. Revoking a revoked key is a
+	// no-op.
+	RevokeAPIKey(ctx context.Context, id string) error
+	// RotateAPIKey gives an active key a new secret, which invalidates the
+	// old one at once, and returns the updated key.
+	RotateAPIKey(ctx context.Context, id string, prefix string, hash string) (*models.APIKey, error)
+	// UseAPIKey returns the active key with the given hash, of whichever
+	// tenant, and records that it was used, to the minute. It fails with
+	// ErrNotFound for unknown and revoked keys.
+	UseAPIKey(ctx context.Context, hash string) (*models.APIKey, error)
+}
+
+// AuditStorage reads the audit log that Save, Update and Delete append to in
+// the same transaction as the change itself.
+type AuditStorage interface {
+	// History returns the events of a subscription, oldest first, and
+	// ErrNotFound when the subscription never existed.
+	History(ctx context.Context, subscriptionID string) ([]models.SubscriptionEvent, error)
+}
+
+// IdempotencyStorage remembers the responses of requests sent with an
+// Idempotency-Key so that a retry gets the original response.
+type IdempotencyStorage interface {
+	// ReserveIdempotencyKey claims key for a new request. When the key is
+	// already held by a record created after notBefore, that record is
+	// returned and nothing is claimed; older records are replaced.
+	ReserveIdempotencyKey(ctx context.Context, key string, requestHash string, notBefore time.Time) (*models.IdempotencyRecord, error)
+	// CompleteIdempotencyKey stores the response of a reserved key.
+	CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, location string, response []byte) error
+	// ReleaseIdempotencyKey drops the reservation of a request that failed,
+	// so it can be retried with the same key.
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+	// PurgeIdempotencyKeys removes records of every tenant created before the
+	// given time and returns how many were removed.
+	PurgeIdempotencyKeys(ctx context.Context, createdBefore time.Time) (int, error)
+}
+
+// StatsStorage aggregates subscriptions for the business metrics.
+type StatsStorage interface {
+	// ServiceRevenue counts the subscriptions of all tenants together that
+	// are active at the given time, per service and currency, along with
+	// their monthly recurring revenue.
+	ServiceRevenue(ctx context.Context, at time.Time) ([]models.ServiceRevenue, error)
+}
+
+// Storage is everything a backend provides to the HTTP layer. Every method
+// only sees and changes the data of the tenant of its context (see
+// tenant.ID), except the purges, UseAPIKey and ServiceRevenue, which span all
+// tenants.
+type Storage interface {
+	SubscriptionStorage
+	FXRateStorage
+	UserStorage
+	ServiceStorage
+	AuditStorage
+	IdempotencyStorage
+	APIKeyStorage
+	StatsStorage
+}
+
+// Storage implementations wrap their failures in one of these errors so the
+// HTTP layer can pick a status code without knowing the backend.
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrPreconditionFailed means the stored version is not the expected one.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrUserNotFound means a subscription refers to a user that does not
+	// exist. It is an ErrInvalidInput.
+	ErrUserNotFound = fmt.Errorf("%w: user not found", ErrInvalidInput)
 )
@@ -0,0 +1,170 @@
+// Package fx converts subscription charges between currencies using the
+// locally loaded rate table.
+package fx
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/utils"
+	"time"
+)
+
+// MissingRateError is returned by Convert when the rate table has no rate
+// for a charge. It is a db.ErrInvalidInput, since loading the rate or picking
+// another target currency is up to the caller.
+type MissingRateError struct {
+	Base  string
+	Quote string
+	Month time.Time
+}
+
+func (e *MissingRateError) Error() string {
+	return fmt.Sprintf("no %s/%s rate for %s", e.Base, e.Quote, e.Month.Format("01-2006"))
+}
+
+func (e *MissingRateError) Unwrap() error {
+	return db.ErrInvalidInput
+}
+
+// Convert expresses every charge in target, using the rate valid in the
+// month of the charge. It returns the converted charges and the distinct
+// rates that were applied.
+func Convert(ctx context.Context, rates db.FXRateStorage, charges []models.MonthlyCharge, target string) ([]models.MonthlyCharge, []models.FXRate, error) {
+	type rateKey struct {
+		currency string
+		month    time.Time
+	}
+
+	cache := make(map[rateKey]*models.FXRate)
+	used := []models.FXRate{}
+	seen := make(map[models.FXRate]bool)
+
+	converted := make([]models.MonthlyCharge, 0, len(charges))
+	for _, charge := range charges {
+		if charge.Currency == target {
+			converted = append(converted, charge)
+			continue
+		}
+
+		key := rateKey{currency: charge.Currency, month: charge.Month}
+		rate, ok := cache[key]
+		if !ok {
+			found, err := rates.GetRate(ctx, charge.Currency, target, charge.Month)
+			if err != nil {
+				if errors.Is(err, db.ErrNotFound) {
+					return nil, nil, &MissingRateError{Base: charge.Currency, Quote: target, Month: charge.Month}
+				}
+
+				return nil, nil, err
+			}
+
+			cache[key] = found
+			rate = found
+		}
+
+		if !seen[*rate] {
+			seen[*rate] = true
+			used = append(used, *rate)
+		}
+
+		charge.Amount = int(math.Round(float64(charge.Amount) * rate.Rate))
+		charge.Currency = target
+		converted = append(converted, charge)
+	}
+
+	return converted, used, nil
+}
+
+// ParseCSV reads rates from CSV with the header month,base_currency,
+// quote_currency,rate. Months use the MM-YYYY format of the API.
+func ParseCSV(r io.Reader) ([]models.FXRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	if strings.ToLower(strings.Join(header, ",")) != "month,base_currency,quote_currency,rate" {
+		return nil, fmt.Errorf("unexpected csv header %q", strings.Join(header, ","))
+	}
+
+	var rates []models.FXRate
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+
+		rate, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: rate is not a number", line)
+		}
+
+		parsed, err := MapRequest(models.FXRateRequest{
+			Month:         record[0],
+			BaseCurrency:  record[1],
+			QuoteCurrency: record[2],
+			Rate:          rate,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		rates = append(rates, *parsed)
+	}
+
+	return rates, nil
+}
+
+// MapRequest validates a rate coming from the API or a CSV file.
+func MapRequest(req models.FXRateRequest) (*models.FXRate, error) {
+	validation := &utils.ValidationError{}
+
+	month, err := utils.ParseDate(req.Month)
+	if err != nil {
+		validation.Add("month", "expected date in MM-YYYY format")
+	}
+
+	base, err := utils.ValidateCurrency(req.BaseCurrency)
+	if err != nil {
+		validation.Add("base_currency", err.Error())
+	}
+
+	quote, err := utils.ValidateCurrency(req.QuoteCurrency)
+	if err != nil {
+		validation.Add("quote_currency", err.Error())
+	}
+
+	if base != "" && base == quote {
+		validation.Add("quote_currency", "quote currency must differ from base currency")
+	}
+
+	if req.Rate <= 0 || math.IsInf(req.Rate, 0) || math.IsNaN(req.Rate) {
+		validation.Add("rate", "rate must be a positive number")
+	}
+
+	if validation.HasErrors() {
+		return nil, validation
+	}
+
+	return &models.FXRate{
+		Month:         month,
+		BaseCurrency:  base,
+		QuoteCurrency: quote,
+		Rate:          req.Rate,
+	}, nil
+}
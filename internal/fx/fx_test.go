@@ -0,0 +1,89 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"testing"
+	"time"
+)
+
+// stubRates serves GetRate from a map keyed by base currency and month and
+// fails every lookup with err when it is set.
+type stubRates struct {
+	db.FXRateStorage
+	rates   map[string]float64
+	err     error
+	lookups int
+}
+
+func (s *stubRates) GetRate(_ context.Context, base string, quote string, month time.Time) (*models.FXRate, error) {
+	s.lookups++
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	rate, ok := s.rates[base+month.Format("01-2006")]
+	if !ok {
+		return nil, db.ErrNotFound
+	}
+
+	return &models.FXRate{Month: month, BaseCurrency: base, QuoteCurrency: quote, Rate: rate}, nil
+}
+
+func month(m time.Month) time.Time {
+	return time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestConvert(t *testing.T) {
+	rates := &stubRates{rates: map[string]float64{"USD07-2025": 80, "USD08-2025": 90.5}}
+
+	charges := []models.MonthlyCharge{
+		{Month: month(time.July), ServiceName: "Netflix", Currency: "USD", Amount: 10},
+		{Month: month(time.July), ServiceName: "Spotify", Currency: "USD", Amount: 5},
+		{Month: month(time.July), ServiceName: "Okko", Currency: "RUB", Amount: 300},
+		{Month: month(time.August), ServiceName: "Netflix", Currency: "USD", Amount: 3},
+	}
+
+	converted, used, err := Convert(context.Background(), rates, charges, "RUB")
+	if err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+
+	want := []int{800, 400, 300, 272}
+	for i, charge := range converted {
+		if charge.Currency != "RUB" || charge.Amount != want[i] {
+			t.Errorf("charge %d: got %d %s, want %d RUB", i, charge.Amount, charge.Currency, want[i])
+		}
+	}
+
+	if len(used) != 2 || used[0].Rate != 80 || used[1].Rate != 90.5 {
+		t.Errorf("got rates %+v, want the July and August USD rates once each", used)
+	}
+
+	if rates.lookups != 2 {
+		t.Errorf("got %d rate lookups, want one per currency and month", rates.lookups)
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	charges := []models.MonthlyCharge{{Month: month(time.July), Currency: "USD", Amount: 10}}
+	storageErr := errors.New("connection reset")
+
+	_, _, err := Convert(context.Background(), &stubRates{}, charges, "RUB")
+
+	var missing *MissingRateError
+	if !errors.As(err, &missing) || !errors.Is(err, db.ErrInvalidInput) {
+		t.Fatalf("missing rate: got %v, want a MissingRateError", err)
+	}
+
+	if missing.Base != "USD" || missing.Quote != "RUB" || !missing.Month.Equal(month(time.July)) {
+		t.Errorf("missing rate: got %+v", missing)
+	}
+
+	_, _, err = Convert(context.Background(), &stubRates{err: storageErr}, charges, "RUB")
+	if !errors.Is(err, storageErr) || errors.As(err, &missing) || errors.Is(err, db.ErrInvalidInput) {
+		t.Errorf("storage error: got %v, want %v unchanged", err, storageErr)
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"subscription-aggregator/internal/auth"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/utils"
+)
+
+type APIKeysHandler struct {
+	storage db.APIKeyStorage
+	log     *slog.Logger
+}
+
+func NewAPIKeysHandler(storage db.APIKeyStorage, log *slog.Logger) *APIKeysHandler {
+	return &APIKeysHandler{
+		storage: storage,
+		log:     log,
+	}
+}
+
+// IssueAPIKey issues a new API key.
+// @Summary Issue an API key
+// @Description Issues a key for service-to-service access with the given scopes: subscriptions:read, subscriptions:write, reports:read or admin, which grants everything. The key is only returned in this response; only a hash of it is stored.
+// @Accept json
+// @Produce json
+// @Param key body models.APIKeyRequest true "Key name and scopes"
+// @Success 201 {object} models.IssuedAPIKey "API key issued successfully"
+// @Failure 400 {object} models.Problem "Invalid request body or data"
+// @Failure 403 {object} models.Problem "Caller is not an admin"
+// @Failure 500 {object} models.Problem "Could not issue API key"
+// @Security BearerAuth
+// @Router /api-keys [post]
+func (h *APIKeysHandler) IssueAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req models.APIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	key, err := utils.MapAPIKeyRequest(req)
+	if err != nil {
+		writeError(w, r, err, "invalid request data")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	secret, prefix, hash, err := auth.NewAPIKey()
+	if err == nil {
+		key.Prefix = prefix
+		err = h.storage.CreateAPIKey(r.Context(), key, hash)
+	}
+
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not issue api key", "error", err, "api_key_id", key.ID, "request_id", reqID)
+		writeError(w, r, err, "could not issue api key")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully issued api key", "api_key_id", key.ID, "scopes", key.Scopes, "request_id", reqID)
+
+	if err := writeJSON(w, http.StatusCreated, &models.IssuedAPIKey{APIKey: *key, Key: secret}); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "api_key_id", key.ID, "request_id", reqID)
+	}
+}
+
+// ListAPIKeys lists API keys.
+// @Summary List API keys
+// @Description Get every API key, including revoked ones, oldest first. Keys are shown by their prefix only.
+// @Produce json
+// @Success 200 {array} models.APIKey "API keys retrieved successfully"
+// @Failure 403 {object} models.Problem "Caller is not an admin"
+// @Failure 500 {object} models.Problem "Could not get API keys"
+// @Security BearerAuth
+// @Router /api-keys [get]
+func (h *APIKeysHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetReqID(r.Context())
+
+	keys, err := h.storage.ListAPIKeys(r.Context())
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not get api keys", "error", err, "request_id", reqID)
+		writeError(w, r, err, "could not get api keys")
+		return
+	}
+
+	if err := writeJSON(w, http.StatusOK, keys); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "request_id", reqID)
+	}
+}
+
+// RevokeAPIKey revokes an API key.
+// @Summary Revoke an API key
+// @Description Revokes a key so that it no longer authenticates. Revoking a revoked key does nothing.
+// @Param id path string true "API key ID"
+// @Success 204 "API key revoked successfully"
+// @Failure 400 {object} models.Problem "Invalid API key ID"
+// @Failure 403 {object} models.Problem "Caller is not an admin"
+// @Failure 404 {object} models.Problem "API key not found"
+// @Failure 500 {object} models.Problem "Could not revoke API key"
+// @Security BearerAuth
+// @Router /api-keys/{id} [delete]
+func (h *APIKeysHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := apiKeyID(r)
+	if err != nil {
+		writeError(w, r, err, "invalid api key ID")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	if err := h.storage.RevokeAPIKey(r.Context(), id); err != nil {
+		h.log.ErrorContext(r.Context(), "could not revoke api key", "error", err, "api_key_id", id, "request_id", reqID)
+		writeError(w, r, err, "could not revoke api key")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully revoked api key", "api_key_id", id, "request_id", reqID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RotateAPIKey replaces the secret of an API key.
+// @Summary Rotate an API key
+// @Description Replaces the secret of an active key, keeping its ID, name and scopes. The old secret stops working at once; the new one is only returned in this response.
+// @Produce json
+// @Param id path string true "API key ID"
+// @Success 200 {object} models.IssuedAPIKey "API key rotated successfully"
+// @Failure 400 {object} models.Problem "Invalid API key ID"
+// @Failure 403 {object} models.Problem "Caller is not an admin"
+// @Failure 404 {object} models.Problem "API key not found or revoked"
+// @Failure 500 {object} models.Problem "Could not rotate API key"
+// @Security BearerAuth
+// @Router /api-keys/{id}/rotate [post]
+func (h *APIKeysHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := apiKeyID(r)
+	if err != nil {
+		writeError(w, r, err, "invalid api key ID")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	var key *models.APIKey
+	secret, prefix, hash, err := auth.NewAPIKey()
+	if err == nil {
+		key, err = h.storage.RotateAPIKey(r.Context(), id, prefix, hash)
+	}
+
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not rotate api key", "error", err, "api_key_id", id, "request_id", reqID)
+		writeError(w, r, err, "could not rotate api key")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully rotated api key", "api_key_id", id, "request_id", reqID)
+
+	if err := writeJSON(w, http.StatusOK, &models.IssuedAPIKey{APIKey: *key, Key: secret}); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "api_key_id", id, "request_id", reqID)
+	}
+}
+
+func apiKeyID(r *http.Request) (string, error) {
+	id := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(id); err != nil {
+		return "", utils.NewValidationError("id", err.Error())
+	}
+
+	return id, nil
+}
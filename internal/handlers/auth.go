@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"strings"
+	"subscription-aggregator/internal/audit"
+	"subscription-aggregator/internal/auth"
+	"subscription-aggregator/internal/db"
+	"time"
+)
+
+// Authenticate requires a bearer token, or an API key when apiKeys is set,
+// on every request and puts the principal into the request context. A nil
+// verifier turns bearer tokens off. The principal replaces any X-Actor
+// header as the audit actor, so it must run after audit.Middleware.
+func Authenticate(verifier *auth.Verifier, apiKeys db.APIKeyStorage, log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticate(r, verifier, apiKeys)
+			if err != nil {
+				if !errors.Is(err, auth.ErrUnauthenticated) {
+					log.ErrorContext(r.Context(), "could not authenticate request", "error", err, "request_id", middleware.GetReqID(r.Context()))
+					writeError(w, r, err, "could not authenticate request")
+					return
+				}
+
+				log.WarnContext(r.Context(), "rejected credentials", "error", err, "request_id", middleware.GetReqID(r.Context()))
+				w.Header().Set("WWW-Authenticate", challenge(verifier, apiKeys))
+				writeProblem(w, r, http.StatusUnauthorized, "valid credentials are required")
+				return
+			}
+
+			ctx := auth.WithPrincipal(r.Context(), principal)
+			ctx = audit.WithActor(ctx, principal.Subject)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func authenticate(r *http.Request, verifier *auth.Verifier, apiKeys db.APIKeyStorage) (*auth.Principal, error) {
+	scheme, credentials, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	credentials = strings.TrimSpace(credentials)
+
+	switch {
+	case credentials == "":
+		return nil, fmt.Errorf("%w: no credentials", auth.ErrUnauthenticated)
+	case strings.EqualFold(scheme, "Bearer") && verifier != nil:
+		return verifier.Verify(credentials, time.Now())
+	case strings.EqualFold(scheme, "ApiKey") && apiKeys != nil:
+		if !auth.LooksLikeAPIKey(credentials) {
+			return nil, fmt.Errorf("%w: malformed api key", auth.ErrUnauthenticated)
+		}
+
+		key, err := apiKeys.UseAPIKey(r.Context(), auth.HashAPIKey(credentials))
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, fmt.Errorf("%w: unknown or revoked api key", auth.ErrUnauthenticated)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return auth.APIKeyPrincipal(key), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported authorization scheme %q", auth.ErrUnauthenticated, scheme)
+	}
+}
+
+// challenge is the WWW-Authenticate header listing the accepted schemes.
+func challenge(verifier *auth.Verifier, apiKeys db.APIKeyStorage) string {
+	var schemes []string
+	if verifier != nil {
+		schemes = append(schemes, "Bearer")
+	}
+	if apiKeys != nil {
+		schemes = append(schemes, "ApiKey")
+	}
+
+	return strings.Join(schemes, ", ")
+}
+
+// RequireScope lets through callers whose API key has scope; callers with a
+// user token always pass.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if principal := auth.FromContext(r.Context()); principal != nil && !principal.HasScope(scope) {
+				writeProblem(w, r, http.StatusForbidden, "api key lacks the "+scope+" scope")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAdmin lets only admin callers through.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !auth.IsAdmin(r.Context()) {
+			writeProblem(w, r, http.StatusForbidden, "admin role is required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forbiddenUser is the error for a request about the data of a user the
+// caller may not act for.
+func forbiddenUser(userID string) error {
+	return fmt.Errorf("%w: caller may not act for user %s", auth.ErrForbidden, userID)
+}
+
+// authorizeUser fails with auth.ErrForbidden unless the caller may act for
+// userID; an empty userID stands for every user.
+func authorizeUser(ctx context.Context, userID string) error {
+	if !auth.CanActFor(ctx, userID) {
+		return forbiddenUser(userID)
+	}
+
+	return nil
+}
+
+// authorizeIncludeDeleted limits soft-deleted subscriptions to admins.
+func authorizeIncludeDeleted(ctx context.Context, includeDeleted bool) error {
+	if includeDeleted && !auth.IsAdmin(ctx) {
+		return fmt.Errorf("%w: only admins can see deleted subscriptions", auth.ErrForbidden)
+	}
+
+	return nil
+}
+
+// authorizeSubscription fails with db.ErrNotFound when the subscription
+// belongs to a user the caller may not act for, so that other users'
+// subscription IDs cannot be probed.
+func (h *SubscriptionsHandler) authorizeSubscription(ctx context.Context, id string, includeDeleted bool) error {
+	if auth.ActsForAllUsers(ctx) {
+		return nil
+	}
+
+	sub, err := h.storage.GetByID(ctx, id, includeDeleted)
+	if err != nil {
+		return err
+	}
+
+	if !auth.CanActFor(ctx, sub.UserID) {
+		return db.ErrNotFound
+	}
+
+	return nil
+}
+
+// scopedIdempotencyKey keeps the Idempotency-Keys of different callers
+// apart, so one caller can never replay another caller's response.
+func scopedIdempotencyKey(ctx context.Context, key string) string {
+	principal := auth.FromContext(ctx)
+	if principal == nil || key == "" {
+		return key
+	}
+
+	sum := sha256.Sum256([]byte(principal.Subject + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/utils"
+)
+
+const (
+	batchModeAtomic     = "atomic"
+	batchModeBestEffort = "best_effort"
+	maxBatchOperations  = 1000
+)
+
+// BatchSubscriptions applies many creates, updates and deletes at once.
+// @Summary Apply a batch of subscription changes
+// @Description Runs up to 1000 create, update and delete operations in order and reports the outcome of each with the status code the single-item endpoint would have answered.
+// @Description In atomic mode (the default) a failing operation rolls back the whole batch and the other operations report 424. In best_effort mode every operation is applied or fails on its own.
+// @Accept json
+// @Produce json
+// @Param batch body models.BatchRequest true "Operations to apply"
+// @Success 200 {object} models.BatchResponse "Outcome of every operation"
+// @Failure 400 {object} models.Problem "Invalid request body"
+// @Failure 500 {object} models.Problem "Could not apply batch"
+// @Security BearerAuth
+// @Router /subscriptions:batch [post]
+func (h *SubscriptionsHandler) BatchSubscriptions(w http.ResponseWriter, r *http.Request) {
+	var req models.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	validation := &utils.ValidationError{}
+
+	if req.Mode == "" {
+		req.Mode = batchModeAtomic
+	} else if req.Mode != batchModeAtomic && req.Mode != batchModeBestEffort {
+		validation.Add("mode", "expected atomic or best_effort")
+	}
+
+	if len(req.Operations) == 0 {
+		validation.Add("operations", "at least one operation is required")
+	} else if len(req.Operations) > maxBatchOperations {
+		validation.Add("operations", fmt.Sprintf("at most %d operations are allowed", maxBatchOperations))
+	}
+
+	if validation.HasErrors() {
+		writeError(w, r, validation, "invalid parameters")
+		return
+	}
+
+	atomic := req.Mode == batchModeAtomic
+	reqID := middleware.GetReqID(r.Context())
+
+	results := make([]models.BatchResult, len(req.Operations))
+	ops := make([]db.BatchOperation, 0, len(req.Operations))
+	// indexes maps every operation sent to storage back to its result.
+	indexes := make([]int, 0, len(req.Operations))
+
+	for i, item := range req.Operations {
+		results[i] = models.BatchResult{Op: item.Op, ID: item.ID}
+
+		op, err := h.batchOperation(item)
+		if err == nil {
+			err = h.authorizeBatchOperation(r.Context(), op)
+		}
+
+		if err != nil {
+			results[i].Status, results[i].Error = batchProblem(r, i, err)
+			continue
+		}
+
+		ops = append(ops, op)
+		indexes = append(indexes, i)
+	}
+
+	switch {
+	case atomic && len(ops) < len(req.Operations):
+		// An invalid operation fails an atomic batch before it reaches storage.
+		for i := range results {
+			if results[i].Error == nil {
+				results[i].Status, results[i].Error = batchProblem(r, i, db.ErrBatchAborted)
+			}
+		}
+	case len(ops) > 0:
+		stored, err := h.storage.ApplyBatch(r.Context(), ops, atomic)
+		if err != nil {
+			h.log.ErrorContext(r.Context(), "could not apply batch", "error", err, "request_id", reqID)
+			writeError(w, r, err, "could not apply batch")
+			return
+		}
+
+		for j, outcome := range stored {
+			i := indexes[j]
+
+			if outcome.Err != nil {
+				results[i].Status, results[i].Error = batchProblem(r, i, outcome.Err)
+				continue
+			}
+
+			results[i].Status = batchStatus(ops[j].Op)
+			results[i].ID = ops[j].ID
+			results[i].Subscription = outcome.Subscription
+		}
+	}
+
+	response := models.BatchResponse{Mode: req.Mode, Results: results}
+	for _, result := range results {
+		if result.Error == nil {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+
+	h.log.InfoContext(
+		r.Context(),
+		"Successfully applied batch",
+		"mode", req.Mode,
+		"succeeded", response.Succeeded,
+		"failed", response.Failed,
+		"request_id", reqID,
+	)
+
+	if err := writeJSON(w, http.StatusOK, &response); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "request_id", reqID)
+	}
+}
+
+// batchOperation validates one operation of a batch.
+func (h *SubscriptionsHandler) batchOperation(item models.BatchOperationRequest) (db.BatchOperation, error) {
+	op := db.BatchOperation{Op: item.Op, ID: item.ID}
+
+	if !db.IsBatchOp(item.Op) {
+		return op, utils.NewValidationError("op", "expected one of create, update, delete")
+	}
+
+	if item.Op != db.BatchCreate {
+		if err := utils.ValidateUUID(item.ID); err != nil {
+			return op, utils.NewValidationError("id", err.Error())
+		}
+	}
+
+	if item.Op == db.BatchDelete {
+		return op, nil
+	}
+
+	if item.Subscription == nil {
+		return op, utils.NewValidationError("subscription", "subscription is required")
+	}
+
+	var (
+		sub *models.Subscription
+		err error
+	)
+
+	if item.Op == db.BatchCreate {
+		sub, err = utils.MapRequest(*item.Subscription, h.log)
+	} else {
+		sub, err = utils.MapUpdateRequest(item.ID, *item.Subscription, h.log)
+	}
+
+	if err != nil {
+		return op, err
+	}
+
+	sub.Version = item.Version
+	op.ID = sub.ID
+	op.Subscription = sub
+
+	return op, nil
+}
+
+// authorizeBatchOperation checks that the caller may act for the user of
+// the subscription before and after the operation.
+func (h *SubscriptionsHandler) authorizeBatchOperation(ctx context.Context, op db.BatchOperation) error {
+	if op.Op != db.BatchCreate {
+		if err := h.authorizeSubscription(ctx, op.ID, false); err != nil {
+			return err
+		}
+	}
+
+	if op.Subscription != nil {
+		return authorizeUser(ctx, op.Subscription.UserID)
+	}
+
+	return nil
+}
+
+// batchStatus is the status code of a successful operation.
+func batchStatus(op string) int {
+	switch op {
+	case db.BatchCreate:
+		return http.StatusCreated
+	case db.BatchDelete:
+		return http.StatusNoContent
+	default:
+		return http.StatusOK
+	}
+}
+
+// batchProblem describes a failed operation. The instance points at the
+// operation inside the request body.
+func batchProblem(r *http.Request, index int, err error) (int, *models.Problem) {
+	status := errorStatus(err)
+	instance := fmt.Sprintf("%s#/operations/%d", r.URL.Path, index)
+
+	if validation := fieldErrors(err); validation != nil {
+		return status, newProblem(instance, status, "invalid operation", validation.Fields...)
+	}
+
+	var detail string
+	switch status {
+	case http.StatusNotFound:
+		detail = "subscription not found"
+	case http.StatusForbidden:
+		detail = "subscription belongs to another user"
+	case http.StatusConflict:
+		detail = "subscription already exists"
+	case http.StatusPreconditionFailed:
+		detail = "subscription was modified by someone else"
+	case http.StatusFailedDependency:
+		detail = "not applied because another operation of the atomic batch failed"
+	case http.StatusBadRequest:
+		detail = "invalid subscription data"
+	default:
+		detail = "could not apply operation"
+	}
+
+	return status, newProblem(instance, status, detail)
+}
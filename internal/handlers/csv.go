@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"subscription-aggregator/internal/auth"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/utils"
+)
+
+const (
+	maxImportSize = 10 << 20
+	maxImportRows = 10000
+)
+
+// ImportSubscriptions creates subscriptions from a CSV file.
+// @Summary Import subscriptions from CSV
+// @Description Creates a subscription for every row of a CSV file with a header row. The columns service_name, price, user_id and start_date are required; end_date, billing_period, billing_interval, currency and category are optional and other columns, such as the id of an export, are ignored. Dates must be in "MM-YYYY" format.
+// @Description The import is all or nothing: with any invalid row nothing is created. With dry_run the rows are only validated and the report lists the invalid ones.
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV file, at most 10000 rows"
+// @Param dry_run query bool false "Only validate the rows"
+// @Success 200 {object} models.ImportReport "Rows imported or validated"
+// @Failure 400 {object} models.Problem "Invalid file or rows"
+// @Failure 500 {object} models.Problem "Could not import subscriptions"
+// @Security BearerAuth
+// @Router /subscriptions/import [post]
+func (h *SubscriptionsHandler) ImportSubscriptions(w http.ResponseWriter, r *http.Request) {
+	validation := &utils.ValidationError{}
+	dryRun := boolParam(r.URL.Query(), "dry_run", validation)
+	if validation.HasErrors() {
+		writeError(w, r, validation, "invalid parameters")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "expected a CSV file of at most 10 MB in the file field")
+		return
+	}
+
+	defer file.Close()
+
+	reqID := middleware.GetReqID(r.Context())
+
+	rows, err := utils.ReadSubscriptionsCSV(file, maxImportRows, h.log)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report := models.ImportReport{DryRun: dryRun, Rows: len(rows)}
+	ops := make([]db.BatchOperation, 0, len(rows))
+	for _, row := range rows {
+		if row.Err == nil && !auth.CanActFor(r.Context(), row.Subscription.UserID) {
+			row.Err = utils.NewValidationError("user_id", "cannot import subscriptions of another user")
+		}
+
+		if row.Err != nil {
+			report.Errors = append(report.Errors, importRowError(row))
+			continue
+		}
+
+		ops = append(ops, db.BatchOperation{Op: db.BatchCreate, ID: row.Subscription.ID, Subscription: row.Subscription})
+	}
+
+	if !dryRun && len(report.Errors) > 0 {
+		for _, rowError := range report.Errors {
+			for _, field := range rowError.InvalidParams {
+				validation.Add(fmt.Sprintf("line[%d].%s", rowError.Line, field.Name), field.Reason)
+			}
+		}
+
+		writeError(w, r, validation, "invalid rows, nothing was imported")
+		return
+	}
+
+	if !dryRun && len(ops) > 0 {
+		results, err := h.storage.ApplyBatch(r.Context(), ops, true)
+		if err == nil {
+			err = batchError(results)
+		}
+
+		if err != nil {
+			h.log.ErrorContext(r.Context(), "could not import subscriptions", "error", err, "request_id", reqID)
+			writeError(w, r, err, "could not import subscriptions")
+			return
+		}
+
+		report.Imported = len(ops)
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully imported subscriptions", "rows", report.Rows, "imported", report.Imported, "dry_run", dryRun, "request_id", reqID)
+
+	if err := writeJSON(w, http.StatusOK, &report); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "request_id", reqID)
+	}
+}
+
+// ExportSubscriptions streams subscriptions as CSV.
+// @Summary Export subscriptions to CSV
+// @Description Streams the subscriptions of a user, or of every user when all_users is set, as CSV in the format accepted by the import. Takes the filters and sort order of the listing endpoint. A service_name or category starting with =, +, - or @ is prefixed with an apostrophe so spreadsheets do not run it as a formula.
+// @Produce text/csv
+// @Param user_id query string false "User ID, required unless all_users is set"
+// @Param all_users query bool false "Export subscriptions of every user"
+// @Param include_deleted query bool false "Also export soft-deleted subscriptions; admins only"
+// @Param service_name query string false "Service name"
+// @Param sort query string false "Sort field: start_date, price or service_name, prefixed with - for descending order"
+// @Param format query string false "Export format, only csv is supported"
+// @Success 200 {file} file "CSV file"
+// @Failure 400 {object} models.Problem "Invalid parameters"
+// @Failure 404 {object} models.Problem "User not found"
+// @Failure 500 {object} models.Problem "Could not export subscriptions"
+// @Security BearerAuth
+// @Router /subscriptions/export [get]
+func (h *SubscriptionsHandler) ExportSubscriptions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if format := query.Get("format"); format != "" && format != "csv" {
+		writeError(w, r, utils.NewValidationError("format", "only csv is supported"), "invalid parameters")
+		return
+	}
+
+	filter, err := parseListFilter(query)
+	if err != nil {
+		writeError(w, r, err, "invalid parameters")
+		return
+	}
+
+	if err := authorizeUser(r.Context(), filter.UserID); err != nil {
+		writeError(w, r, err, "cannot export subscriptions of another user")
+		return
+	}
+
+	if err := authorizeIncludeDeleted(r.Context(), filter.IncludeDeleted); err != nil {
+		writeError(w, r, err, "cannot export deleted subscriptions")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	writer := csv.NewWriter(w)
+	exported := 0
+
+	// Nothing is written before the first row arrives, so that a failure to
+	// start the export can still be reported as a problem response.
+	started := false
+	start := func() {
+		started = true
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="subscriptions.csv"`)
+		w.WriteHeader(http.StatusOK)
+
+		_ = writer.Write(utils.SubscriptionCSVHeader)
+	}
+
+	err = h.storage.Export(r.Context(), filter, func(sub *models.Subscription) error {
+		if !started {
+			start()
+		}
+
+		_ = writer.Write(utils.SubscriptionCSVRecord(sub))
+		exported++
+
+		if exported%maxPageLimit == 0 {
+			writer.Flush()
+		}
+
+		return writer.Error()
+	})
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not export subscriptions", "error", err, "user_id", filter.UserID, "request_id", reqID)
+		if !started {
+			writeError(w, r, err, "could not export subscriptions")
+		}
+
+		// Otherwise the status line is gone; the client sees a truncated file.
+		return
+	}
+
+	if !started {
+		start()
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "user_id", filter.UserID, "request_id", reqID)
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully exported subscriptions", "count", exported, "user_id", filter.UserID, "request_id", reqID)
+}
+
+func importRowError(row utils.CSVRow) models.ImportRowError {
+	rowError := models.ImportRowError{Line: row.Line}
+
+	var validation *utils.ValidationError
+	if errors.As(row.Err, &validation) {
+		rowError.InvalidParams = validation.Fields
+	} else {
+		rowError.InvalidParams = []models.InvalidParam{{Name: "row", Reason: row.Err.Error()}}
+	}
+
+	return rowError
+}
+
+// batchError returns the error of the operation that failed a batch.
+func batchError(results []db.BatchResult) error {
+	for _, result := range results {
+		if result.Err != nil && !errors.Is(result.Err, db.ErrBatchAborted) {
+			return result.Err
+		}
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			return result.Err
+		}
+	}
+
+	return nil
+}
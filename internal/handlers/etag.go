@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag renders a subscription version as a strong entity tag.
+func etag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// etagMatches reports whether the If-Match or If-None-Match header value
+// lists tag. Weak tags only match when weak is set, as RFC 9110 asks for
+// If-None-Match; If-Match uses the strong comparison.
+func etagMatches(header string, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if candidate == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ifMatch reports whether the If-Match precondition of the request holds for
+// the given version. A request without If-Match always passes.
+func ifMatch(r *http.Request, version int) bool {
+	match := r.Header.Get("If-Match")
+	return match == "" || etagMatches(match, etag(version), false)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestETagMatches(t *testing.T) {
+	tag := etag(3)
+
+	tests := []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{header: `"3"`, want: true},
+		{header: `"2", "3"`, want: true},
+		{header: `*`, want: true},
+		{header: `"2"`},
+		{header: `3`},
+		{header: `W/"3"`},
+		{header: `W/"3"`, weak: true, want: true},
+		{header: ` W/"2" ,  "3" `, weak: true, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, tag, tt.weak); got != tt.want {
+			t.Errorf("%s weak %v: got %v, want %v", tt.header, tt.weak, got, tt.want)
+		}
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{want: true},
+		{header: `"3"`, want: true},
+		{header: `"4"`},
+		{header: `W/"3"`},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("PUT", "/subscriptions/1", nil)
+		if tt.header != "" {
+			r.Header.Set("If-Match", tt.header)
+		}
+
+		if got := ifMatch(r, 3); got != tt.want {
+			t.Errorf("If-Match %q: got %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/fx"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/utils"
+)
+
+type FXRatesHandler struct {
+	storage db.FXRateStorage
+	log     *slog.Logger
+}
+
+func NewFXRatesHandler(storage db.FXRateStorage, log *slog.Logger) *FXRatesHandler {
+	return &FXRatesHandler{
+		storage: storage,
+		log:     log,
+	}
+}
+
+// SaveRates loads exchange rates.
+// @Summary Load exchange rates
+// @Description Creates or replaces exchange rates. A rate is the price of one unit of base_currency in quote_currency and is valid from its month until a newer rate is loaded. Months must be in "MM-YYYY" format.
+// @Accept json
+// @Produce json
+// @Param rates body []models.FXRateRequest true "Exchange rates"
+// @Success 200 {array} models.FXRate "Rates saved successfully"
+// @Failure 400 {object} models.Problem "Invalid request body or data"
+// @Failure 500 {object} models.Problem "Could not save rates"
+// @Security BearerAuth
+// @Router /fx-rates [put]
+func (h *FXRatesHandler) SaveRates(w http.ResponseWriter, r *http.Request) {
+	var req []models.FXRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	validation := &utils.ValidationError{}
+	rates := make([]models.FXRate, 0, len(req))
+	for i, item := range req {
+		rate, err := fx.MapRequest(item)
+		if err != nil {
+			var itemValidation *utils.ValidationError
+			if errors.As(err, &itemValidation) {
+				for _, field := range itemValidation.Fields {
+					validation.Add(fmt.Sprintf("[%d].%s", i, field.Name), field.Reason)
+				}
+			}
+			continue
+		}
+
+		rates = append(rates, *rate)
+	}
+
+	if validation.HasErrors() {
+		writeError(w, r, validation, "invalid request data")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	if err := h.storage.SaveRates(r.Context(), rates); err != nil {
+		h.log.ErrorContext(r.Context(), "could not save fx rates", "error", err, "request_id", reqID)
+		writeError(w, r, err, "could not save rates")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully saved fx rates", "count", len(rates), "request_id", reqID)
+
+	if err := writeJSON(w, http.StatusOK, &rates); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "request_id", reqID)
+	}
+}
+
+// ListRates lists exchange rates.
+// @Summary List exchange rates
+// @Description Get every loaded exchange rate.
+// @Produce json
+// @Success 200 {array} models.FXRate "Rates retrieved successfully"
+// @Failure 500 {object} models.Problem "Could not get rates"
+// @Security BearerAuth
+// @Router /fx-rates [get]
+func (h *FXRatesHandler) ListRates(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetReqID(r.Context())
+
+	rates, err := h.storage.ListRates(r.Context())
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not list fx rates", "error", err, "request_id", reqID)
+		writeError(w, r, err, "could not get rates")
+		return
+	}
+
+	if err := writeJSON(w, http.StatusOK, &rates); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "request_id", reqID)
+	}
+}
+
+// writeConversionError reports a failed fx.Convert. A missing rate is named
+// on target_currency; the text of any other error stays in the log.
+func writeConversionError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	var missing *fx.MissingRateError
+	if errors.As(err, &missing) {
+		writeProblem(w, r, http.StatusBadRequest, detail, models.InvalidParam{Name: "target_currency", Reason: missing.Error()})
+		return
+	}
+
+	writeError(w, r, err, detail)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"subscription-aggregator/internal/fx"
+	"subscription-aggregator/internal/models"
+	"testing"
+	"time"
+)
+
+func TestWriteConversionError(t *testing.T) {
+	missing := &fx.MissingRateError{Base: "USD", Quote: "RUB", Month: time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		reason string
+	}{
+		{name: "missing rate", err: missing, status: http.StatusBadRequest, reason: "no USD/RUB rate for 07-2025"},
+		{name: "storage error", err: errors.New("dial tcp 10.0.0.5:5432: connection refused"), status: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		writeConversionError(w, httptest.NewRequest(http.MethodGet, "/subscriptions/total-cost", nil), tt.err, "could not convert total cost")
+
+		if w.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, w.Code, tt.status)
+		}
+
+		var problem models.Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("%s: decode problem: %v", tt.name, err)
+		}
+
+		if problem.Detail != "could not convert total cost" {
+			t.Errorf("%s: got detail %q", tt.name, problem.Detail)
+		}
+
+		if tt.reason == "" {
+			if len(problem.InvalidParams) > 0 || strings.Contains(w.Body.String(), "10.0.0.5") {
+				t.Errorf("%s: error text leaked into %s", tt.name, w.Body.String())
+			}
+			continue
+		}
+
+		if len(problem.InvalidParams) != 1 || problem.InvalidParams[0].Name != "target_currency" || problem.InvalidParams[0].Reason != tt.reason {
+			t.Errorf("%s: got invalid params %+v, want target_currency: %s", tt.name, problem.InvalidParams, tt.reason)
+		}
+	}
+}
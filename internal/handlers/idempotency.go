@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"subscription-aggregator/internal/utils"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyKey reads the Idempotency-Key header; an empty key means the
+// request is not idempotent.
+func idempotencyKey(r *http.Request) (string, error) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		return "", utils.NewValidationError(idempotencyKeyHeader, "key must be at most 255 characters")
+	}
+
+	return key, nil
+}
+
+// requestHash fingerprints the decoded request, so that retries that only
+// differ in JSON formatting still count as the same request.
+func requestHash(req any) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// reserveIdempotencyKey claims key for this request. When the key was used
+// before it answers the request itself and returns false: with the stored
+// response for a retry, 422 for a different payload and 409 while the first
+// request is still running.
+func (h *SubscriptionsHandler) reserveIdempotencyKey(w http.ResponseWriter, r *http.Request, key string, hash string) bool {
+	reqID := middleware.GetReqID(r.Context())
+	notBefore := time.Now().Add(-h.idempotencyTTL)
+
+	record, err := h.idempotency.ReserveIdempotencyKey(r.Context(), key, hash, notBefore)
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not reserve idempotency key", "error", err, "request_id", reqID)
+		writeError(w, r, err, "could not reserve idempotency key")
+		return false
+	}
+
+	if record == nil {
+		return true
+	}
+
+	switch {
+	case record.RequestHash != hash:
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+	case record.StatusCode == 0:
+		writeProblem(w, r, http.StatusConflict, "a request with this Idempotency-Key is still in progress")
+	default:
+		h.log.InfoContext(r.Context(), "Replaying idempotent response", "request_id", reqID)
+
+		w.Header().Set("Idempotent-Replayed", "true")
+		if record.Location != "" {
+			w.Header().Set("Location", record.Location)
+		}
+
+		if err := writeRawJSON(w, record.StatusCode, record.Response); err != nil {
+			h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "request_id", reqID)
+		}
+	}
+
+	return false
+}
+
+// completeIdempotencyKey stores the response for later retries. It runs even
+// if the client has gone away, since that client is the one that will retry.
+func (h *SubscriptionsHandler) completeIdempotencyKey(r *http.Request, key string, status int, location string, body []byte) {
+	ctx := context.WithoutCancel(r.Context())
+
+	if err := h.idempotency.CompleteIdempotencyKey(ctx, key, status, location, body); err != nil {
+		h.log.ErrorContext(ctx, "could not store idempotent response", "error", err, "request_id", middleware.GetReqID(ctx))
+	}
+}
+
+// releaseIdempotencyKey frees the key of a failed request so the client can
+// retry it.
+func (h *SubscriptionsHandler) releaseIdempotencyKey(r *http.Request, key string) {
+	ctx := context.WithoutCancel(r.Context())
+
+	if err := h.idempotency.ReleaseIdempotencyKey(ctx, key); err != nil {
+		h.log.ErrorContext(ctx, "could not release idempotency key", "error", err, "request_id", middleware.GetReqID(ctx))
+	}
+}
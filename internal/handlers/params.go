@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/utils"
+	"time"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// monthParam parses an optional MM-YYYY query parameter.
+func monthParam(query url.Values, name string, validation *utils.ValidationError) *time.Time {
+	value := query.Get(name)
+	if value == "" {
+		return nil
+	}
+
+	date, err := utils.ParseDate(value)
+	if err != nil {
+		validation.Add(name, "expected date in MM-YYYY format")
+		return nil
+	}
+
+	return &date
+}
+
+// intParam parses an optional integer query parameter.
+func intParam(query url.Values, name string, validation *utils.ValidationError) *int {
+	value := query.Get(name)
+	if value == "" {
+		return nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		validation.Add(name, "expected an integer")
+		return nil
+	}
+
+	return &number
+}
+
+// boolParam parses an optional boolean query parameter, false when absent.
+func boolParam(query url.Values, name string, validation *utils.ValidationError) bool {
+	value := query.Get(name)
+	if value == "" {
+		return false
+	}
+
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		validation.Add(name, "expected true or false")
+		return false
+	}
+
+	return result
+}
+
+// parseListFilter reads the pagination, filtering and sorting parameters of
+// a subscription listing.
+func parseListFilter(query url.Values) (db.ListFilter, error) {
+	validation := &utils.ValidationError{}
+
+	filter := db.ListFilter{
+		UserID:      query.Get("user_id"),
+		ServiceName: query.Get("service_name"),
+		ActiveAt:    monthParam(query, "active_at", validation),
+		MinPrice:    intParam(query, "min_price", validation),
+		MaxPrice:    intParam(query, "max_price", validation),
+		StartFrom:   monthParam(query, "start_from", validation),
+		StartTo:     monthParam(query, "start_to", validation),
+		EndFrom:     monthParam(query, "end_from", validation),
+		EndTo:       monthParam(query, "end_to", validation),
+		SortBy:      db.SortStartDate,
+		Limit:       defaultPageLimit,
+	}
+
+	filter.IncludeDeleted = boolParam(query, "include_deleted", validation)
+
+	allUsers := boolParam(query, "all_users", validation)
+	if filter.UserID == "" && !allUsers {
+		validation.Add("user_id", "user ID is required unless all_users is set")
+	} else if filter.UserID != "" {
+		if err := utils.ValidateUUID(filter.UserID); err != nil {
+			validation.Add("user_id", err.Error())
+		}
+	}
+
+	if sortBy := query.Get("sort"); sortBy != "" {
+		filter.Desc = strings.HasPrefix(sortBy, "-")
+		filter.SortBy = strings.TrimPrefix(sortBy, "-")
+		if !db.IsSortField(filter.SortBy) {
+			validation.Add("sort", "expected one of start_date, price, service_name, optionally prefixed with -")
+		}
+	}
+
+	if limit := intParam(query, "limit", validation); limit != nil {
+		if *limit < 1 || *limit > maxPageLimit {
+			validation.Add("limit", "limit must be between 1 and "+strconv.Itoa(maxPageLimit))
+		} else {
+			filter.Limit = *limit
+		}
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := db.DecodeCursor(value)
+		if err != nil {
+			validation.Add("cursor", "malformed cursor")
+		} else if cursor.SortBy != filter.SortBy || cursor.Desc != filter.Desc {
+			validation.Add("cursor", "cursor was issued for a different sort order")
+		} else {
+			filter.Cursor = cursor
+		}
+	}
+
+	if validation.HasErrors() {
+		return db.ListFilter{}, validation
+	}
+
+	return filter, nil
+}
+
+// parseCostFilter reads the parameters of a total-cost request.
+func parseCostFilter(query url.Values) (db.CostFilter, string, error) {
+	validation := &utils.ValidationError{}
+
+	filter := db.CostFilter{
+		UserID: query.Get("user_id"),
+	}
+
+	if err := utils.ValidateUUID(filter.UserID); err != nil {
+		validation.Add("user_id", err.Error())
+	}
+
+	for _, serviceName := range query["service_name"] {
+		if serviceName == "" {
+			validation.Add("service_name", "service name must not be empty")
+			continue
+		}
+
+		filter.ServiceNames = append(filter.ServiceNames, serviceName)
+	}
+
+	// The period is [period_start, period_end): without either bound the
+	// charges would be counted over an empty range and add up to 0.
+	periodStart := monthParam(query, "period_start", validation)
+	if periodStart == nil && query.Get("period_start") == "" {
+		validation.Add("period_start", "period_start is required")
+	}
+
+	periodEnd := monthParam(query, "period_end", validation)
+	if periodEnd == nil && query.Get("period_end") == "" {
+		validation.Add("period_end", "period_end is required")
+	}
+
+	if periodStart != nil && periodEnd != nil {
+		if !periodEnd.After(*periodStart) {
+			validation.Add("period_end", "period_end must be after period_start")
+		}
+
+		filter.PeriodStart = *periodStart
+		filter.PeriodEnd = *periodEnd
+	}
+
+	for _, value := range query["group_by"] {
+		for _, dimension := range strings.Split(value, ",") {
+			dimension = strings.TrimSpace(dimension)
+			if !db.IsGroupBy(dimension) {
+				validation.Add("group_by", "expected service, month, year or category")
+				continue
+			}
+
+			if !slices.Contains(filter.GroupBy, dimension) {
+				filter.GroupBy = append(filter.GroupBy, dimension)
+			}
+		}
+	}
+
+	var targetCurrency string
+	if target := query.Get("target_currency"); target != "" {
+		code, err := utils.ValidateCurrency(target)
+		if err != nil {
+			validation.Add("target_currency", err.Error())
+		}
+
+		targetCurrency = code
+	}
+
+	if validation.HasErrors() {
+		return db.CostFilter{}, "", validation
+	}
+
+	return filter, targetCurrency, nil
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/utils"
+	"testing"
+	"time"
+)
+
+func TestParseCostFilter(t *testing.T) {
+	const userID = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+
+	tests := []struct {
+		query   string
+		invalid []string
+		check   func(filter db.CostFilter, target string) bool
+	}{
+		{
+			query: "period_start=01-2025&period_end=07-2025",
+			check: func(filter db.CostFilter, target string) bool {
+				return filter.PeriodStart.Equal(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)) &&
+					filter.PeriodEnd.Equal(time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)) &&
+					filter.ServiceNames == nil && filter.GroupBy == nil && target == ""
+			},
+		},
+		{
+			query: "period_start=01-2025&period_end=02-2025&service_name=Netflix&service_name=Okko&group_by=service,month&group_by=service&target_currency=usd",
+			check: func(filter db.CostFilter, target string) bool {
+				return slices.Equal(filter.ServiceNames, []string{"Netflix", "Okko"}) &&
+					slices.Equal(filter.GroupBy, []string{"service", "month"}) && target == "USD"
+			},
+		},
+		{query: "period_end=07-2025", invalid: []string{"period_start"}},
+		{query: "period_start=01-2025", invalid: []string{"period_end"}},
+		{query: "", invalid: []string{"period_start", "period_end"}},
+		{query: "period_start=2025-01&period_end=07-2025", invalid: []string{"period_start"}},
+		{query: "period_start=07-2025&period_end=07-2025", invalid: []string{"period_end"}},
+		{query: "period_start=07-2025&period_end=01-2025", invalid: []string{"period_end"}},
+		{query: "period_start=01-2025&period_end=07-2025&group_by=user", invalid: []string{"group_by"}},
+		{query: "period_start=01-2025&period_end=07-2025&service_name=", invalid: []string{"service_name"}},
+	}
+
+	for _, tt := range tests {
+		query, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		query.Set("user_id", userID)
+
+		filter, target, err := parseCostFilter(query)
+		if len(tt.invalid) > 0 {
+			var validation *utils.ValidationError
+			if !errors.As(err, &validation) || len(validation.Fields) != len(tt.invalid) {
+				t.Errorf("%q: got error %v, want validation errors for %v", tt.query, err, tt.invalid)
+				continue
+			}
+
+			for i, field := range validation.Fields {
+				if field.Name != tt.invalid[i] {
+					t.Errorf("%q: got invalid field %q, want %q", tt.query, field.Name, tt.invalid[i])
+				}
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.query, err)
+			continue
+		}
+
+		if !tt.check(filter, target) {
+			t.Errorf("%q: unexpected filter %+v and target currency %q", tt.query, filter, target)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"subscription-aggregator/internal/auth"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/utils"
+)
+
+const problemContentType = "application/problem+json"
+
+// writeProblem sends an RFC 7807 problem+json response.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string, params ...models.InvalidParam) {
+	problem := newProblem(r.URL.Path, status, detail, params...)
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+func newProblem(instance string, status int, detail string, params ...models.InvalidParam) *models.Problem {
+	return &models.Problem{
+		Type:          "about:blank",
+		Title:         http.StatusText(status),
+		Status:        status,
+		Detail:        detail,
+		Instance:      instance,
+		InvalidParams: params,
+	}
+}
+
+// writeError maps an error from the validation or storage layer to its
+// status code. Anything outside the taxonomy is reported as an internal error.
+func writeError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	if validation := fieldErrors(err); validation != nil {
+		writeProblem(w, r, http.StatusBadRequest, detail, validation.Fields...)
+		return
+	}
+
+	writeProblem(w, r, errorStatus(err), detail)
+}
+
+// fieldErrors returns the request fields an error is about: those of a
+// ValidationError, or the field a storage error points at. It is nil for
+// every other error.
+func fieldErrors(err error) *utils.ValidationError {
+	var validation *utils.ValidationError
+	if errors.As(err, &validation) {
+		return validation
+	}
+
+	if errors.Is(err, db.ErrUserNotFound) {
+		return utils.NewValidationError("user_id", "user does not exist")
+	}
+
+	return nil
+}
+
+// errorStatus picks the status code of an error in the taxonomy.
+func errorStatus(err error) int {
+	var validation *utils.ValidationError
+
+	switch {
+	case errors.As(err, &validation):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, db.ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, db.ErrBatchAborted):
+		return http.StatusFailedDependency
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, auth.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJSON encodes body as the JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, body any) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(body)
+}
+
+// writeRawJSON sends an already encoded JSON body.
+func writeRawJSON(w http.ResponseWriter, status int, body []byte) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err := w.Write(body)
+	return err
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"subscription-aggregator/internal/auth"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/utils"
+	"testing"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		params []string
+	}{
+		{name: "not found", err: fmt.Errorf("get: %w", db.ErrNotFound), status: http.StatusNotFound},
+		{name: "conflict", err: fmt.Errorf("%w: duplicate key", db.ErrConflict), status: http.StatusConflict},
+		{name: "invalid input", err: db.ErrInvalidInput, status: http.StatusBadRequest},
+		{name: "precondition failed", err: db.ErrPreconditionFailed, status: http.StatusPreconditionFailed},
+		{name: "batch aborted", err: db.ErrBatchAborted, status: http.StatusFailedDependency},
+		{name: "unauthenticated", err: auth.ErrUnauthenticated, status: http.StatusUnauthorized},
+		{name: "forbidden", err: auth.ErrForbidden, status: http.StatusForbidden},
+		{name: "unknown user", err: fmt.Errorf("%w: fk", db.ErrUserNotFound), status: http.StatusBadRequest, params: []string{"user_id"}},
+		{name: "validation", err: utils.NewValidationError("price", "must not be negative"), status: http.StatusBadRequest, params: []string{"price"}},
+		{name: "unknown error", err: errors.New("connection reset"), status: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/subscriptions/1", nil)
+
+		writeError(w, r, tt.err, "could not do it")
+
+		if w.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, w.Code, tt.status)
+		}
+
+		if got := w.Header().Get("Content-Type"); got != problemContentType {
+			t.Errorf("%s: got content type %q, want %q", tt.name, got, problemContentType)
+		}
+
+		var problem models.Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Errorf("%s: decode problem: %v", tt.name, err)
+			continue
+		}
+
+		// The detail is the one of the handler, never the text of err.
+		if problem.Status != tt.status || problem.Detail != "could not do it" || problem.Instance != "/subscriptions/1" {
+			t.Errorf("%s: got problem %+v", tt.name, problem)
+		}
+
+		if len(problem.InvalidParams) != len(tt.params) {
+			t.Errorf("%s: got invalid params %+v, want %v", tt.name, problem.InvalidParams, tt.params)
+			continue
+		}
+
+		for i, param := range problem.InvalidParams {
+			if param.Name != tt.params[i] {
+				t.Errorf("%s: got invalid param %q, want %q", tt.name, param.Name, tt.params[i])
+			}
+		}
+	}
+}
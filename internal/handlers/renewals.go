@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"subscription-aggregator/internal/billing"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/ical"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/utils"
+	"time"
+)
+
+const (
+	defaultRenewalMonths = 12
+	maxRenewalMonths     = 60
+	maxReminderDays      = 30
+)
+
+type RenewalsHandler struct {
+	storage db.SubscriptionStorage
+	log     *slog.Logger
+}
+
+func NewRenewalsHandler(storage db.SubscriptionStorage, log *slog.Logger) *RenewalsHandler {
+	return &RenewalsHandler{
+		storage: storage,
+		log:     log,
+	}
+}
+
+// RenewalsCalendar returns the upcoming charges of a user as an iCalendar feed.
+// @Summary Renewal calendar
+// @Description Returns an iCalendar (RFC 5545) feed with an all-day event for every charge of the user's subscriptions from today until the horizon. Event UIDs are derived from the subscription ID and the charge date, so calendar apps update events in place when the feed is refreshed.
+// @Produce text/calendar
+// @Param user_id path string true "User ID"
+// @Param months query int false "Horizon in months (1-60, default 12)"
+// @Param reminder_days query int false "Add a reminder this many days before each charge (0-30)"
+// @Success 200 {file} file "iCalendar feed"
+// @Failure 400 {object} models.Problem "Invalid parameters"
+// @Failure 404 {object} models.Problem "User not found, or the path does not end in .ics"
+// @Failure 500 {object} models.Problem "Could not build calendar"
+// @Security BearerAuth
+// @Router /users/{user_id}/renewals.ics [get]
+func (h *RenewalsHandler) RenewalsCalendar(w http.ResponseWriter, r *http.Request) {
+	// The URLFormat middleware strips the extension before routing.
+	if format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string); format != "ics" {
+		writeProblem(w, r, http.StatusNotFound, "only the .ics format is available")
+		return
+	}
+
+	userID := chi.URLParam(r, "user_id")
+	query := r.URL.Query()
+	validation := &utils.ValidationError{}
+
+	if err := utils.ValidateUUID(userID); err != nil {
+		validation.Add("user_id", err.Error())
+	}
+
+	months := defaultRenewalMonths
+	if value := intParam(query, "months", validation); value != nil {
+		if *value < 1 || *value > maxRenewalMonths {
+			validation.Add("months", fmt.Sprintf("months must be between 1 and %d", maxRenewalMonths))
+		} else {
+			months = *value
+		}
+	}
+
+	reminderDays := intParam(query, "reminder_days", validation)
+	if reminderDays != nil && (*reminderDays < 0 || *reminderDays > maxReminderDays) {
+		validation.Add("reminder_days", fmt.Sprintf("reminder_days must be between 0 and %d", maxReminderDays))
+	}
+
+	if validation.HasErrors() {
+		writeError(w, r, validation, "invalid parameters")
+		return
+	}
+
+	if err := authorizeUser(r.Context(), userID); err != nil {
+		writeError(w, r, err, "cannot read the calendar of another user")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	subs, err := h.listAll(r, userID)
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not build renewal calendar", "error", err, "user_id", userID, "request_id", reqID)
+		writeError(w, r, err, "could not build calendar")
+		return
+	}
+
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, months, 0)
+
+	calendar := &ical.Calendar{
+		ProdID: "-//subscription-aggregator//renewals//EN",
+		Name:   "Subscription renewals",
+	}
+
+	for _, sub := range subs {
+		for _, date := range billing.ChargeDates(sub, from, to) {
+			calendar.Events = append(calendar.Events, renewalEvent(sub, date, reminderDays))
+		}
+	}
+
+	slices.SortFunc(calendar.Events, func(a, b ical.Event) int {
+		return cmp.Or(a.Date.Compare(b.Date), strings.Compare(a.UID, b.UID))
+	})
+
+	h.log.InfoContext(r.Context(), "Successfully built renewal calendar", "user_id", userID, "events", len(calendar.Events), "request_id", reqID)
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="renewals.ics"`)
+
+	if err := calendar.Write(w); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "user_id", userID, "request_id", reqID)
+	}
+}
+
+// listAll reads every subscription of the user page by page.
+func (h *RenewalsHandler) listAll(r *http.Request, userID string) ([]*models.Subscription, error) {
+	filter := db.ListFilter{UserID: userID, SortBy: db.SortStartDate, Limit: maxPageLimit}
+
+	var subs []*models.Subscription
+	for {
+		page, err := h.storage.List(r.Context(), filter)
+		if err != nil {
+			return nil, err
+		}
+
+		subs = append(subs, page.Items...)
+
+		if page.NextCursor == "" {
+			return subs, nil
+		}
+
+		if filter.Cursor, err = db.DecodeCursor(page.NextCursor); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func renewalEvent(sub *models.Subscription, date time.Time, reminderDays *int) ical.Event {
+	event := ical.Event{
+		UID:         fmt.Sprintf("%s-%s@subscription-aggregator", sub.ID, date.Format("20060102")),
+		Stamp:       sub.UpdatedAt,
+		Date:        date,
+		Summary:     fmt.Sprintf("%s: %d %s", sub.ServiceName, sub.Price, sub.Currency),
+		Description: fmt.Sprintf("%s renews for %d %s (%s)", sub.ServiceName, sub.Price, sub.Currency, sub.BillingPeriod),
+	}
+
+	if reminderDays != nil {
+		event.Alarm = &ical.Alarm{
+			DaysBefore:  *reminderDays,
+			Description: fmt.Sprintf("%s renews soon", sub.ServiceName),
+		}
+	}
+
+	return event
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/fx"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/utils"
+	"time"
+)
+
+const maxReportMonths = 120
+
+type ReportsHandler struct {
+	storage db.SubscriptionStorage
+	rates   db.FXRateStorage
+	log     *slog.Logger
+}
+
+func NewReportsHandler(storage db.SubscriptionStorage, rates db.FXRateStorage, log *slog.Logger) *ReportsHandler {
+	return &ReportsHandler{
+		storage: storage,
+		rates:   rates,
+		log:     log,
+	}
+}
+
+// MonthlyReport returns a user's spending per calendar month.
+// @Summary Monthly cost breakdown
+// @Description Returns one row per calendar month between from and to (both inclusive) with the cost of every service charged in that month. Dates must be in "MM-YYYY" format.
+// @Produce json
+// @Param user_id query string true "User ID"
+// @Param from query string true "First month of the report (MM-YYYY)"
+// @Param to query string true "Last month of the report (MM-YYYY)"
+// @Param target_currency query string false "ISO 4217 currency to convert every charge to"
+// @Success 200 {object} models.MonthlyReport "Report built successfully"
+// @Failure 400 {object} models.Problem "Invalid parameters"
+// @Failure 500 {object} models.Problem "Could not build report"
+// @Security BearerAuth
+// @Router /reports/monthly [get]
+func (h *ReportsHandler) MonthlyReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	validation := &utils.ValidationError{}
+
+	userID := query.Get("user_id")
+	if err := utils.ValidateUUID(userID); err != nil {
+		validation.Add("user_id", err.Error())
+	}
+
+	from := monthParam(query, "from", validation)
+	if from == nil && query.Get("from") == "" {
+		validation.Add("from", "from is required")
+	}
+
+	to := monthParam(query, "to", validation)
+	if to == nil && query.Get("to") == "" {
+		validation.Add("to", "to is required")
+	}
+
+	if from != nil && to != nil {
+		if to.Before(*from) {
+			validation.Add("to", "to must not be before from")
+		} else if monthsBetween(*from, *to) > maxReportMonths {
+			validation.Add("to", "report may cover at most 120 months")
+		}
+	}
+
+	var targetCurrency string
+	if target := query.Get("target_currency"); target != "" {
+		code, err := utils.ValidateCurrency(target)
+		if err != nil {
+			validation.Add("target_currency", err.Error())
+		}
+
+		targetCurrency = code
+	}
+
+	if validation.HasErrors() {
+		writeError(w, r, validation, "invalid parameters")
+		return
+	}
+
+	if err := authorizeUser(r.Context(), userID); err != nil {
+		writeError(w, r, err, "cannot read the report of another user")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	charges, err := h.storage.MonthlyCharges(r.Context(), db.CostFilter{
+		UserID:      userID,
+		PeriodStart: *from,
+		PeriodEnd:   to.AddDate(0, 1, 0),
+	})
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not get monthly charges", "error", err, "user_id", userID, "request_id", reqID)
+		writeError(w, r, err, "could not build report")
+		return
+	}
+
+	report := models.MonthlyReport{UserID: userID, Currency: targetCurrency}
+
+	if targetCurrency != "" {
+		converted, rates, err := fx.Convert(r.Context(), h.rates, charges, targetCurrency)
+		if err != nil {
+			h.log.WarnContext(r.Context(), "could not convert charges", "error", err, "user_id", userID, "request_id", reqID)
+			writeConversionError(w, r, err, "could not convert report")
+			return
+		}
+
+		charges = converted
+		report.Rates = rates
+	}
+
+	report.Months = buildReportMonths(*from, *to, charges)
+
+	h.log.InfoContext(r.Context(), "Successfully built monthly report", "user_id", userID, "request_id", reqID)
+
+	if err := writeJSON(w, http.StatusOK, &report); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "user_id", userID, "request_id", reqID)
+	}
+}
+
+// buildReportMonths lays charges out over every month from first to last,
+// including months without any charge. Charges must be sorted by month.
+func buildReportMonths(first time.Time, last time.Time, charges []models.MonthlyCharge) []models.ReportMonth {
+	var months []models.ReportMonth
+
+	next := 0
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		row := models.ReportMonth{
+			Month:    month,
+			Totals:   []models.CurrencyTotal{},
+			Services: []models.ServiceCost{},
+		}
+
+		for ; next < len(charges) && charges[next].Month.Equal(month); next++ {
+			charge := charges[next]
+			row.Services = appendServiceCost(row.Services, charge)
+			row.Totals = appendCurrencyTotal(row.Totals, charge.Currency, charge.Amount)
+		}
+
+		months = append(months, row)
+	}
+
+	return months
+}
+
+// appendServiceCost merges a charge into the service list; after currency
+// conversion one service can arrive in several rows for the same month.
+func appendServiceCost(services []models.ServiceCost, charge models.MonthlyCharge) []models.ServiceCost {
+	for i := range services {
+		if services[i].ServiceName == charge.ServiceName && services[i].Currency == charge.Currency {
+			services[i].Amount += charge.Amount
+			return services
+		}
+	}
+
+	return append(services, models.ServiceCost{
+		ServiceName: charge.ServiceName,
+		Currency:    charge.Currency,
+		Amount:      charge.Amount,
+	})
+}
+
+func appendCurrencyTotal(totals []models.CurrencyTotal, currency string, amount int) []models.CurrencyTotal {
+	for i := range totals {
+		if totals[i].Currency == currency {
+			totals[i].Amount += amount
+			return totals
+		}
+	}
+
+	return append(totals, models.CurrencyTotal{Currency: currency, Amount: amount})
+}
+
+func monthsBetween(from time.Time, to time.Time) int {
+	return (to.Year()*12 + int(to.Month())) - (from.Year()*12 + int(from.Month())) + 1
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"subscription-aggregator/internal/db/memory"
+	"subscription-aggregator/internal/models"
+	"testing"
+	"time"
+)
+
+func TestMonthlyReport(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	storage := memory.New(log)
+	ctx := context.Background()
+
+	user := &models.User{ID: uuid.NewString(), DisplayName: "Test", DefaultCurrency: models.DefaultCurrency, Timezone: "UTC"}
+	if err := storage.CreateUser(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	july := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+	for _, sub := range []*models.Subscription{
+		{ID: uuid.NewString(), ServiceName: "Netflix", Price: 400, Currency: "RUB", StartDate: july},
+		{ID: uuid.NewString(), ServiceName: "Spotify", Price: 10, Currency: "USD", StartDate: july.AddDate(0, 1, 0)},
+	} {
+		sub.UserID = user.ID
+		sub.BillingPeriod = models.BillingMonthly
+		sub.BillingInterval = 1
+		if err := storage.Save(ctx, sub); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	handler := NewReportsHandler(storage, storage, log)
+
+	report := func(query string) (int, []byte) {
+		w := httptest.NewRecorder()
+		handler.MonthlyReport(w, httptest.NewRequest(http.MethodGet, "/reports/monthly?user_id="+user.ID+query, nil))
+		return w.Code, w.Body.Bytes()
+	}
+
+	status, body := report("&from=06-2025&to=09-2025")
+	if status != http.StatusOK {
+		t.Fatalf("report: got status %d: %s", status, body)
+	}
+
+	var got models.MonthlyReport
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+
+	// June has no charges but still gets a row; from August on there are two
+	// currencies.
+	wantTotals := []map[string]int{{}, {"RUB": 400}, {"RUB": 400, "USD": 10}, {"RUB": 400, "USD": 10}}
+	if len(got.Months) != len(wantTotals) {
+		t.Fatalf("got %d months, want %d", len(got.Months), len(wantTotals))
+	}
+
+	for i, month := range got.Months {
+		if want := july.AddDate(0, i-1, 0); !month.Month.Equal(want) {
+			t.Errorf("row %d: got month %v, want %v", i, month.Month, want)
+		}
+
+		totals := map[string]int{}
+		for _, total := range month.Totals {
+			totals[total.Currency] = total.Amount
+		}
+
+		if len(totals) != len(wantTotals[i]) || len(month.Services) != len(wantTotals[i]) {
+			t.Errorf("row %d: got totals %+v and services %+v, want %v", i, month.Totals, month.Services, wantTotals[i])
+			continue
+		}
+
+		for currency, amount := range wantTotals[i] {
+			if totals[currency] != amount {
+				t.Errorf("row %d: got %d %s, want %d", i, totals[currency], currency, amount)
+			}
+		}
+	}
+
+	status, body = report("&from=06-2025&to=09-2025&target_currency=RUB")
+	if status != http.StatusBadRequest {
+		t.Fatalf("report without a rate: got status %d: %s", status, body)
+	}
+
+	var problem models.Problem
+	if err := json.Unmarshal(body, &problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+
+	if problem.Detail != "could not convert report" || len(problem.InvalidParams) != 1 || problem.InvalidParams[0].Name != "target_currency" {
+		t.Errorf("report without a rate: got problem %+v", problem)
+	}
+
+	if err := storage.SaveRates(ctx, []models.FXRate{{Month: july, BaseCurrency: "USD", QuoteCurrency: "RUB", Rate: 90}}); err != nil {
+		t.Fatalf("save rates: %v", err)
+	}
+
+	status, body = report("&from=09-2025&to=09-2025&target_currency=RUB")
+	if status != http.StatusOK {
+		t.Fatalf("converted report: got status %d: %s", status, body)
+	}
+
+	got = models.MonthlyReport{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+
+	if len(got.Months) != 1 || len(got.Months[0].Totals) != 1 || got.Months[0].Totals[0].Amount != 1300 || len(got.Rates) != 1 {
+		t.Errorf("converted report: got %s, want 1300 RUB for September and the rate used", body)
+	}
+
+	for _, query := range []string{"", "&from=06-2025", "&from=09-2025&to=06-2025", "&from=01-2015&to=02-2025", "&from=06-2025&to=09-2025&target_currency=rub1"} {
+		if status, body := report(query); status != http.StatusBadRequest {
+			t.Errorf("%q: got status %d, want %d: %s", query, status, http.StatusBadRequest, body)
+		}
+	}
+}
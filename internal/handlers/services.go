@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/utils"
+)
+
+type ServicesHandler struct {
+	storage db.ServiceStorage
+	log     *slog.Logger
+}
+
+func NewServicesHandler(storage db.ServiceStorage, log *slog.Logger) *ServicesHandler {
+	return &ServicesHandler{
+		storage: storage,
+		log:     log,
+	}
+}
+
+// CreateService adds a service to the catalog.
+// @Summary Create a service
+// @Description Adds a service to the catalog. Subscriptions created or updated later whose service_name matches the name or an alias, ignoring case and extra spaces, are linked to it and stored under its name.
+// @Accept json
+// @Produce json
+// @Param service body models.ServiceRequest true "Service data"
+// @Success 201 {object} models.Service "Service created successfully"
+// @Header 201 {string} Location "URL of the new service"
+// @Failure 400 {object} models.Problem "Invalid request body or data"
+// @Failure 409 {object} models.Problem "Name or alias is already used by another service"
+// @Failure 500 {object} models.Problem "Could not save service"
+// @Security BearerAuth
+// @Router /services [post]
+func (h *ServicesHandler) CreateService(w http.ResponseWriter, r *http.Request) {
+	var req models.ServiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	svc, err := utils.MapServiceRequest(req)
+	if err != nil {
+		writeError(w, r, err, "invalid request data")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	if err := h.storage.CreateService(r.Context(), svc); err != nil {
+		h.log.ErrorContext(r.Context(), "could not save service", "error", err, "service_id", svc.ID, "request_id", reqID)
+		writeError(w, r, err, "could not save service")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully saved service", "service_id", svc.ID, "request_id", reqID)
+
+	w.Header().Set("Location", "/services/"+svc.ID)
+
+	if err := writeJSON(w, http.StatusCreated, svc); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "service_id", svc.ID, "request_id", reqID)
+	}
+}
+
+// GetService gets a service by ID.
+// @Summary Get a service by ID
+// @Description Get a catalog service with its aliases.
+// @Produce json
+// @Param id path string true "Service ID"
+// @Success 200 {object} models.Service "Service found successfully"
+// @Failure 400 {object} models.Problem "Invalid service ID"
+// @Failure 404 {object} models.Problem "Service not found"
+// @Failure 500 {object} models.Problem "Could not get service"
+// @Security BearerAuth
+// @Router /services/{id} [get]
+func (h *ServicesHandler) GetService(w http.ResponseWriter, r *http.Request) {
+	id, err := serviceID(r)
+	if err != nil {
+		writeError(w, r, err, "invalid service ID")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	svc, err := h.storage.GetService(r.Context(), id)
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not get service", "error", err, "service_id", id, "request_id", reqID)
+		writeError(w, r, err, "could not get service")
+		return
+	}
+
+	if err := writeJSON(w, http.StatusOK, svc); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "service_id", id, "request_id", reqID)
+	}
+}
+
+// ListServices lists the service catalog.
+// @Summary List services
+// @Description Get every catalog service, ordered by name.
+// @Produce json
+// @Success 200 {array} models.Service "Services retrieved successfully"
+// @Failure 500 {object} models.Problem "Could not get services"
+// @Security BearerAuth
+// @Router /services [get]
+func (h *ServicesHandler) ListServices(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetReqID(r.Context())
+
+	services, err := h.storage.ListServices(r.Context())
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not list services", "error", err, "request_id", reqID)
+		writeError(w, r, err, "could not get services")
+		return
+	}
+
+	if err := writeJSON(w, http.StatusOK, &services); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "request_id", reqID)
+	}
+}
+
+// UpdateService updates a catalog service.
+// @Summary Update a service
+// @Description Replaces a catalog service and its aliases. Subscriptions already linked to it keep the name they were stored under until they are updated.
+// @Accept json
+// @Produce json
+// @Param id path string true "Service ID"
+// @Param service body models.ServiceRequest true "Updated service data"
+// @Success 200 {object} models.Service "Service updated successfully"
+// @Failure 400 {object} models.Problem "Invalid request body or data"
+// @Failure 404 {object} models.Problem "Service not found"
+// @Failure 409 {object} models.Problem "Name or alias is already used by another service"
+// @Failure 500 {object} models.Problem "Could not update service"
+// @Security BearerAuth
+// @Router /services/{id} [put]
+func (h *ServicesHandler) UpdateService(w http.ResponseWriter, r *http.Request) {
+	id, err := serviceID(r)
+	if err != nil {
+		writeError(w, r, err, "invalid service ID")
+		return
+	}
+
+	var req models.ServiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	svc, err := utils.MapServiceUpdateRequest(id, req)
+	if err != nil {
+		writeError(w, r, err, "invalid request data")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	if err := h.storage.UpdateService(r.Context(), svc); err != nil {
+		h.log.ErrorContext(r.Context(), "could not update service", "error", err, "service_id", id, "request_id", reqID)
+		writeError(w, r, err, "could not update service")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully updated service", "service_id", id, "request_id", reqID)
+
+	if err := writeJSON(w, http.StatusOK, svc); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "service_id", id, "request_id", reqID)
+	}
+}
+
+// DeleteService removes a service from the catalog.
+// @Summary Delete a service
+// @Description Removes a catalog service. Its subscriptions keep their service name and lose the link to the service.
+// @Produce json
+// @Param id path string true "Service ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.Problem "Invalid service ID"
+// @Failure 404 {object} models.Problem "Service not found"
+// @Failure 500 {object} models.Problem "Could not delete service"
+// @Security BearerAuth
+// @Router /services/{id} [delete]
+func (h *ServicesHandler) DeleteService(w http.ResponseWriter, r *http.Request) {
+	id, err := serviceID(r)
+	if err != nil {
+		writeError(w, r, err, "invalid service ID")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	if err := h.storage.DeleteService(r.Context(), id); err != nil {
+		h.log.ErrorContext(r.Context(), "could not delete service", "error", err, "service_id", id, "request_id", reqID)
+		writeError(w, r, err, "could not delete service")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully deleted service", "service_id", id, "request_id", reqID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func serviceID(r *http.Request) (string, error) {
+	id := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(id); err != nil {
+		return "", utils.NewValidationError("id", err.Error())
+	}
+
+	return id, nil
+}
@@ -2,94 +2,208 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"log/slog"
+	"mime"
 	"net/http"
-	"subscription-aggregator/internal/db/postgres"
+	"slices"
+	"strings"
+	"subscription-aggregator/internal/auth"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/fx"
 	"subscription-aggregator/internal/models"
 	"subscription-aggregator/internal/utils"
 	"time"
 )
 
+const mergePatchContentType = "application/merge-patch+json"
+
 type SubscriptionsHandler struct {
-	storage *postgres.Storage
-	log     *slog.Logger
+	storage        db.SubscriptionStorage
+	rates          db.FXRateStorage
+	audit          db.AuditStorage
+	idempotency    db.IdempotencyStorage
+	idempotencyTTL time.Duration
+	log            *slog.Logger
 }
 
-func NewSubscriptionsHandler(storage *postgres.Storage, log *slog.Logger) *SubscriptionsHandler {
+func NewSubscriptionsHandler(
+	storage db.SubscriptionStorage,
+	rates db.FXRateStorage,
+	audit db.AuditStorage,
+	idempotency db.IdempotencyStorage,
+	idempotencyTTL time.Duration,
+	log *slog.Logger,
+) *SubscriptionsHandler {
 	return &SubscriptionsHandler{
-		storage: storage,
-		log:     log,
+		storage:        storage,
+		rates:          rates,
+		audit:          audit,
+		idempotency:    idempotency,
+		idempotencyTTL: idempotencyTTL,
+		log:            log,
 	}
 }
 
 // CreateSubscription creates a new subscription.
 // @Summary Create a new subscription
 // @Description Creates a new user subscription. Dates must be in "MM-YYYY" format.
+// @Description With an Idempotency-Key header a retry of the same request within the key's TTL returns the original response instead of creating another subscription.
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Client-generated key, at most 255 characters"
 // @Param subscription body models.SubscriptionRequest true "Subscription data"
-// @Success 201 {object} models.SubscriptionRequest "Subscription created successfully"
-// @Failure 400 {string} string "Invalid request body or data"
-// @Failure 500 {string} string "Could not save subscription"
+// @Success 201 {object} models.Subscription "Subscription created successfully"
+// @Header 201 {string} Location "URL of the new subscription"
+// @Failure 400 {object} models.Problem "Invalid request body or data"
+// @Failure 409 {object} models.Problem "Subscription already exists or a request with the same Idempotency-Key is in progress"
+// @Failure 422 {object} models.Problem "Idempotency-Key was used with a different request"
+// @Failure 500 {object} models.Problem "Could not save subscription"
+// @Security BearerAuth
 // @Router /subscriptions [post]
 func (h *SubscriptionsHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
 	var req models.SubscriptionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	key, err := idempotencyKey(r)
+	if err != nil {
+		writeError(w, r, err, "invalid Idempotency-Key")
 		return
 	}
 
-	updateRequest, err := utils.MapRequest(req, h.log)
+	key = scopedIdempotencyKey(r.Context(), key)
+
+	sub, err := utils.MapRequest(req, h.log)
 	if err != nil {
-		http.Error(w, "invalid request data", http.StatusBadRequest)
+		writeError(w, r, err, "invalid request data")
+		return
+	}
+
+	if err := authorizeUser(r.Context(), sub.UserID); err != nil {
+		writeError(w, r, err, "cannot create subscriptions for another user")
 		return
 	}
 
 	reqID := middleware.GetReqID(r.Context())
 
-	if err := h.storage.Save(r.Context(), updateRequest); err != nil {
-		h.log.Error("could not save subscription", "error", err, "subscription_id", updateRequest.ID, "request_id", reqID)
-		http.Error(w, "could not save subscription", http.StatusInternalServerError)
+	if key != "" {
+		hash, err := requestHash(&req)
+		if err != nil {
+			writeError(w, r, err, "could not save subscription")
+			return
+		}
+
+		if !h.reserveIdempotencyKey(w, r, key, hash) {
+			return
+		}
+	}
+
+	if err := h.storage.Save(r.Context(), sub); err != nil {
+		h.log.ErrorContext(r.Context(), "could not save subscription", "error", err, "subscription_id", sub.ID, "request_id", reqID)
+		if key != "" {
+			h.releaseIdempotencyKey(r, key)
+		}
+
+		writeError(w, r, err, "could not save subscription")
 		return
 	}
 
-	h.log.Info("Successfully saved subscription", "subscription_id", updateRequest.ID, "request_id", reqID)
+	h.log.InfoContext(r.Context(), "Successfully saved subscription", "subscription_id", sub.ID, "request_id", reqID)
 
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(&req); err != nil {
-		h.log.Error("failed to write response", "error", err, "subscription_id", updateRequest.ID, "request_id", reqID)
-		http.Error(w, "failed to write response", http.StatusInternalServerError)
+	body, err := json.Marshal(sub)
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "failed to encode response", "error", err, "subscription_id", sub.ID, "request_id", reqID)
+		writeError(w, r, err, "could not encode response")
 		return
 	}
+
+	location := "/subscriptions/" + sub.ID
+	if key != "" {
+		h.completeIdempotencyKey(r, key, http.StatusCreated, location, body)
+	}
+
+	w.Header().Set("Location", location)
+	w.Header().Set("ETag", etag(sub.Version))
+
+	if err := writeRawJSON(w, http.StatusCreated, body); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "subscription_id", sub.ID, "request_id", reqID)
+	}
 }
 
 // DeleteSubscription deletes a subscription by ID.
 // @Summary Delete a subscription
-// @Description Deletes a user's subscription record by its unique ID.
+// @Description Soft-deletes a user's subscription record by its unique ID. The subscription can be restored until the retention window passes and it is purged.
 // @Produce json
 // @Param id path string true "Subscription ID"
 // @Success 204 "No Content"
-// @Failure 400 {string} string "Invalid subscription ID"
-// @Failure 500 {string} string "Could not delete subscription"
+// @Failure 400 {object} models.Problem "Invalid subscription ID"
+// @Failure 404 {object} models.Problem "Subscription not found"
+// @Failure 500 {object} models.Problem "Could not delete subscription"
+// @Security BearerAuth
 // @Router /subscriptions/{id} [delete]
 func (h *SubscriptionsHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
-	subID := chi.URLParam(r, "id")
-	if subID == "" {
-		http.Error(w, "no subscription ID", http.StatusBadRequest)
+	subID, err := subscriptionID(r)
+	if err != nil {
+		writeError(w, r, err, "invalid subscription ID")
 		return
 	}
 
 	reqID := middleware.GetReqID(r.Context())
 
+	if err := h.authorizeSubscription(r.Context(), subID, false); err != nil {
+		writeError(w, r, err, "could not delete subscription")
+		return
+	}
+
 	if err := h.storage.Delete(r.Context(), subID); err != nil {
-		h.log.Error("could not delete subscription", "error", err, "subscription_id", subID, "request_id", reqID)
-		http.Error(w, "could not delete subscription", http.StatusInternalServerError)
+		h.log.ErrorContext(r.Context(), "could not delete subscription", "error", err, "subscription_id", subID, "request_id", reqID)
+		writeError(w, r, err, "could not delete subscription")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully deleted subscription", "subscription_id", subID, "request_id", reqID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreSubscription restores a soft-deleted subscription.
+// @Summary Restore a deleted subscription
+// @Description Restores a soft-deleted subscription that has not been purged yet.
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.Problem "Invalid subscription ID"
+// @Failure 404 {object} models.Problem "Subscription not found"
+// @Failure 409 {object} models.Problem "Subscription is not deleted"
+// @Failure 500 {object} models.Problem "Could not restore subscription"
+// @Security BearerAuth
+// @Router /subscriptions/{id}/restore [post]
+func (h *SubscriptionsHandler) RestoreSubscription(w http.ResponseWriter, r *http.Request) {
+	subID, err := subscriptionID(r)
+	if err != nil {
+		writeError(w, r, err, "invalid subscription ID")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	if err := h.authorizeSubscription(r.Context(), subID, true); err != nil {
+		writeError(w, r, err, "could not restore subscription")
 		return
 	}
 
-	h.log.Info("Successfully deleted subscription", "subscription_id", subID, "request_id", reqID)
+	if err := h.storage.Restore(r.Context(), subID); err != nil {
+		h.log.ErrorContext(r.Context(), "could not restore subscription", "error", err, "subscription_id", subID, "request_id", reqID)
+		writeError(w, r, err, "could not restore subscription")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully restored subscription", "subscription_id", subID, "request_id", reqID)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -99,179 +213,532 @@ func (h *SubscriptionsHandler) DeleteSubscription(w http.ResponseWriter, r *http
 // @Description Get a user's subscription record by its unique ID.
 // @Produce json
 // @Param id path string true "Subscription ID"
+// @Param include_deleted query bool false "Also return a soft-deleted subscription; admins only"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} models.Subscription "Subscription found successfully"
-// @Failure 400 {string} string "Invalid subscription ID"
-// @Failure 500 {string} string "Could not get subscription"
+// @Header 200 {string} ETag "Version of the subscription"
+// @Success 304 "Subscription has not changed"
+// @Failure 400 {object} models.Problem "Invalid subscription ID"
+// @Failure 404 {object} models.Problem "Subscription not found"
+// @Failure 500 {object} models.Problem "Could not get subscription"
+// @Security BearerAuth
 // @Router /subscriptions/{id} [get]
 func (h *SubscriptionsHandler) GetSubscriptionByID(w http.ResponseWriter, r *http.Request) {
-	subID := chi.URLParam(r, "id")
-	if subID == "" {
-		http.Error(w, "no subscription ID", http.StatusBadRequest)
+	subID, err := subscriptionID(r)
+	if err != nil {
+		writeError(w, r, err, "invalid subscription ID")
 		return
 	}
 
 	reqID := middleware.GetReqID(r.Context())
 
-	result, err := h.storage.GetByID(r.Context(), subID)
+	validation := &utils.ValidationError{}
+	includeDeleted := boolParam(r.URL.Query(), "include_deleted", validation)
+	if validation.HasErrors() {
+		writeError(w, r, validation, "invalid parameters")
+		return
+	}
+
+	if err := authorizeIncludeDeleted(r.Context(), includeDeleted); err != nil {
+		writeError(w, r, err, "cannot get deleted subscriptions")
+		return
+	}
+
+	result, err := h.storage.GetByID(r.Context(), subID, includeDeleted)
 	if err != nil {
-		h.log.Error("could not get subscription", "error", err, "subscription_id", subID, "request_id", reqID)
-		http.Error(w, "could not get subscription", http.StatusInternalServerError)
+		h.log.ErrorContext(r.Context(), "could not get subscription", "error", err, "subscription_id", subID, "request_id", reqID)
+		writeError(w, r, err, "could not get subscription")
 		return
 	}
 
-	h.log.Info("Successfully get subscription", "subscription_id", subID, "request_id", reqID)
+	if !auth.CanActFor(r.Context(), result.UserID) {
+		writeError(w, r, db.ErrNotFound, "could not get subscription")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully get subscription", "subscription_id", subID, "request_id", reqID)
+
+	tag := etag(result.Version)
+	w.Header().Set("ETag", tag)
 
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(&result); err != nil {
-		h.log.Error("failed to write response", "error", err, "subscription_id", subID, "request_id", reqID)
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, tag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if err := writeJSON(w, http.StatusOK, &result); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "subscription_id", subID, "request_id", reqID)
 	}
 }
 
 // ListSubscriptionsByUserID list of subscriptions for specific user.
-// @Summary Get subscriptions by user ID
-// @Description Get a list of all subscription records for a specific user.
+// @Summary List subscriptions
+// @Description Get a page of subscription records for a specific user, or for every user when all_users is set. Dates must be in "MM-YYYY" format.
 // @Produce json
-// @Param user_id query string true "User ID"
-// @Success 200 {array} models.Subscription "Subscriptions retrieved successfully"
-// @Failure 400 {string} string "No user ID"
-// @Failure 500 {string} string "Could not get list subscriptions"
+// @Param user_id query string false "User ID, required unless all_users is set"
+// @Param all_users query bool false "List subscriptions of every user"
+// @Param include_deleted query bool false "Also list soft-deleted subscriptions; admins only"
+// @Param service_name query string false "Service name"
+// @Param active_at query string false "Only subscriptions active in this month (MM-YYYY)"
+// @Param min_price query int false "Minimum price"
+// @Param max_price query int false "Maximum price"
+// @Param start_from query string false "Earliest start date (MM-YYYY)"
+// @Param start_to query string false "Latest start date (MM-YYYY)"
+// @Param end_from query string false "Earliest end date (MM-YYYY)"
+// @Param end_to query string false "Latest end date (MM-YYYY)"
+// @Param sort query string false "Sort field: start_date, price or service_name, prefixed with - for descending order"
+// @Param limit query int false "Page size (1-500, default 50)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Success 200 {object} models.SubscriptionPage "Subscriptions retrieved successfully"
+// @Failure 400 {object} models.Problem "Invalid parameters"
+// @Failure 404 {object} models.Problem "User not found"
+// @Failure 500 {object} models.Problem "Could not get list subscriptions"
+// @Security BearerAuth
 // @Router /subscriptions [get]
 func (h *SubscriptionsHandler) ListSubscriptionsByUserID(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		http.Error(w, "no user ID", http.StatusBadRequest)
+	filter, err := parseListFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err, "invalid parameters")
+		return
+	}
+
+	if err := authorizeUser(r.Context(), filter.UserID); err != nil {
+		writeError(w, r, err, "cannot list subscriptions of another user")
+		return
+	}
+
+	if err := authorizeIncludeDeleted(r.Context(), filter.IncludeDeleted); err != nil {
+		writeError(w, r, err, "cannot list deleted subscriptions")
 		return
 	}
 
 	reqID := middleware.GetReqID(r.Context())
 
-	result, err := h.storage.List(r.Context(), userID)
+	result, err := h.storage.List(r.Context(), filter)
 	if err != nil {
-		h.log.Error("could not get list subscriptions", "error", err, "user_id", userID, "request_id", reqID)
-		http.Error(w, "could not get list subscriptions", http.StatusInternalServerError)
+		h.log.ErrorContext(r.Context(), "could not get list subscriptions", "error", err, "user_id", filter.UserID, "request_id", reqID)
+		writeError(w, r, err, "could not get list subscriptions")
 		return
 	}
 
-	h.log.Info("Successfully get list subscriptions", "user_id", userID, "request_id", reqID)
+	h.log.InfoContext(r.Context(), "Successfully get list subscriptions", "user_id", filter.UserID, "request_id", reqID)
 
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(&result); err != nil {
-		h.log.Error("failed to write response", "error", err, "user_id", userID, "request_id", reqID)
+	if err := writeJSON(w, http.StatusOK, &result); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "user_id", filter.UserID, "request_id", reqID)
+	}
+}
+
+// GetSubscriptionHistory returns the audit log of a subscription.
+// @Summary Get subscription history
+// @Description Get every recorded change of a subscription, oldest first, including changes made before it was deleted.
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 200 {array} models.SubscriptionEvent "History retrieved successfully"
+// @Failure 400 {object} models.Problem "Invalid subscription ID"
+// @Failure 404 {object} models.Problem "Subscription has no history"
+// @Failure 500 {object} models.Problem "Could not get subscription history"
+// @Security BearerAuth
+// @Router /subscriptions/{id}/history [get]
+func (h *SubscriptionsHandler) GetSubscriptionHistory(w http.ResponseWriter, r *http.Request) {
+	subID, err := subscriptionID(r)
+	if err != nil {
+		writeError(w, r, err, "invalid subscription ID")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	if err := h.authorizeSubscription(r.Context(), subID, true); err != nil {
+		writeError(w, r, err, "could not get subscription history")
+		return
+	}
+
+	events, err := h.audit.History(r.Context(), subID)
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not get subscription history", "error", err, "subscription_id", subID, "request_id", reqID)
+		writeError(w, r, err, "could not get subscription history")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully get subscription history", "subscription_id", subID, "request_id", reqID)
+
+	if err := writeJSON(w, http.StatusOK, &events); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "subscription_id", subID, "request_id", reqID)
 	}
 }
 
 // UpdateSubscription updates an existing subscription.
 // @Summary Update an existing subscription
-// @Description Update an existing subscription record by its unique ID.
+// @Description Update an existing subscription record by its unique ID. With If-Match the update is only applied when the subscription still has that ETag.
 // @Accept json
 // @Produce json
 // @Param id path string true "Subscription ID"
+// @Param If-Match header string false "ETag returned by the last read"
 // @Param subscription body models.SubscriptionRequest true "Updated subscription data"
-// @Success 200 {object} models.SubscriptionRequest "Subscription updated successfully"
-// @Failure 400 {string} string "Invalid request body"
-// @Failure 500 {string} string "Could not update subscription"
+// @Success 200 {object} models.Subscription "Subscription updated successfully"
+// @Header 200 {string} ETag "New version of the subscription"
+// @Failure 400 {object} models.Problem "Invalid request body"
+// @Failure 404 {object} models.Problem "Subscription not found"
+// @Failure 412 {object} models.Problem "Subscription was modified by someone else"
+// @Failure 500 {object} models.Problem "Could not update subscription"
+// @Security BearerAuth
 // @Router /subscriptions/{id} [put]
 func (h *SubscriptionsHandler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
-	subID := chi.URLParam(r, "id")
-	if subID == "" {
-		http.Error(w, "no subscription ID", http.StatusBadRequest)
+	subID, err := subscriptionID(r)
+	if err != nil {
+		writeError(w, r, err, "invalid subscription ID")
 		return
 	}
 
 	var req models.SubscriptionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
-	updateRequest, err := utils.MapRequest(req, h.log)
+	updateRequest, err := utils.MapUpdateRequest(subID, req, h.log)
 	if err != nil {
-		http.Error(w, "invalid request data", http.StatusBadRequest)
+		writeError(w, r, err, "invalid request data")
 		return
 	}
 
-	updateRequest.ID = subID
+	if err := authorizeUser(r.Context(), updateRequest.UserID); err != nil {
+		writeError(w, r, err, "cannot move a subscription to another user")
+		return
+	}
 
 	reqID := middleware.GetReqID(r.Context())
 
+	if err := h.authorizeSubscription(r.Context(), subID, false); err != nil {
+		writeError(w, r, err, "could not update subscription")
+		return
+	}
+
+	if r.Header.Get("If-Match") != "" {
+		current, err := h.storage.GetByID(r.Context(), subID, false)
+		if err != nil {
+			h.log.ErrorContext(r.Context(), "could not get subscription", "error", err, "subscription_id", subID, "request_id", reqID)
+			writeError(w, r, err, "could not update subscription")
+			return
+		}
+
+		if !ifMatch(r, current.Version) {
+			writeProblem(w, r, http.StatusPreconditionFailed, "subscription was modified by someone else")
+			return
+		}
+
+		updateRequest.Version = current.Version
+	}
+
 	if err := h.storage.Update(r.Context(), updateRequest); err != nil {
-		h.log.Error("could not update subscription", "error", err, "subscription_id", subID, "request_id", reqID)
-		http.Error(w, "could not update subscription", http.StatusInternalServerError)
+		h.log.ErrorContext(r.Context(), "could not update subscription", "error", err, "subscription_id", subID, "request_id", reqID)
+		writeError(w, r, err, "could not update subscription")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully update subscription", "subscription_id", subID, "request_id", reqID)
+
+	w.Header().Set("ETag", etag(updateRequest.Version))
+
+	if err := writeJSON(w, http.StatusOK, updateRequest); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "subscription_id", subID, "request_id", reqID)
+	}
+}
+
+// PatchSubscription applies a JSON merge patch to a subscription.
+// @Summary Patch a subscription
+// @Description Changes only the fields present in an RFC 7396 merge patch; null removes a field, so "end_date": null makes the subscription open-ended; null for service_name, price, user_id or start_date is rejected. The merged subscription is validated like a full update. With If-Match the patch is only applied when the subscription still has that ETag.
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Param If-Match header string false "ETag returned by the last read"
+// @Param patch body models.SubscriptionRequest true "Merge patch with the fields to change"
+// @Success 200 {object} models.Subscription "Subscription patched successfully"
+// @Header 200 {string} ETag "New version of the subscription"
+// @Failure 400 {object} models.Problem "Invalid patch or merged data"
+// @Failure 404 {object} models.Problem "Subscription not found"
+// @Failure 412 {object} models.Problem "Subscription was modified by someone else"
+// @Failure 415 {object} models.Problem "Patch is not JSON"
+// @Failure 500 {object} models.Problem "Could not patch subscription"
+// @Security BearerAuth
+// @Router /subscriptions/{id} [patch]
+func (h *SubscriptionsHandler) PatchSubscription(w http.ResponseWriter, r *http.Request) {
+	subID, err := subscriptionID(r)
+	if err != nil {
+		writeError(w, r, err, "invalid subscription ID")
 		return
 	}
 
-	h.log.Info("Successfully update subscription", "subscription_id", subID, "request_id", reqID)
+	if !isMergePatch(r.Header.Get("Content-Type")) {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "expected "+mergePatchContentType)
+		return
+	}
 
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(&req); err != nil {
-		h.log.Error("failed to write response", "error", err, "subscription_id", subID, "request_id", reqID)
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		writeProblem(w, r, http.StatusBadRequest, "merge patch must be a JSON object")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	current, err := h.storage.GetByID(r.Context(), subID, false)
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not get subscription", "error", err, "subscription_id", subID, "request_id", reqID)
+		writeError(w, r, err, "could not patch subscription")
+		return
+	}
+
+	if !auth.CanActFor(r.Context(), current.UserID) {
+		writeError(w, r, db.ErrNotFound, "could not patch subscription")
+		return
+	}
+
+	if !ifMatch(r, current.Version) {
+		writeProblem(w, r, http.StatusPreconditionFailed, "subscription was modified by someone else")
+		return
+	}
+
+	req, err := mergeSubscription(current, patch)
+	var validation *utils.ValidationError
+	if errors.As(err, &validation) {
+		writeError(w, r, err, "invalid merge patch")
+		return
+	}
+
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid merge patch: "+err.Error())
+		return
+	}
+
+	patched, err := utils.MapUpdateRequest(subID, req, h.log)
+	if err != nil {
+		writeError(w, r, err, "invalid merged data")
+		return
+	}
+
+	if err := authorizeUser(r.Context(), patched.UserID); err != nil {
+		writeError(w, r, err, "cannot move a subscription to another user")
+		return
+	}
+
+	// The patch was merged into the version read above, so a concurrent
+	// change in between must not be overwritten.
+	patched.Version = current.Version
+
+	if err := h.storage.Update(r.Context(), patched); err != nil {
+		h.log.ErrorContext(r.Context(), "could not patch subscription", "error", err, "subscription_id", subID, "request_id", reqID)
+		writeError(w, r, err, "could not patch subscription")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully patch subscription", "subscription_id", subID, "request_id", reqID)
+
+	w.Header().Set("ETag", etag(patched.Version))
+
+	if err := writeJSON(w, http.StatusOK, patched); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "subscription_id", subID, "request_id", reqID)
 	}
 }
 
-// SumTotalCostSubscriptions calculate total cost of a user's subscriptions for a given period and service.
+// SumTotalCostSubscriptions calculate total cost of a user's subscriptions for a given period.
 // @Summary Calculate total subscription cost
-// @Description Calculates the total cost of a user's subscriptions for a given period, filtered by user ID and service name. The period dates must be in "MM-YYYY" format.
+// @Description Calculates the total cost of a user's subscriptions for a given period, optionally filtered by one or more service names. The period dates must be in "MM-YYYY" format.
+// @Description Without target_currency and group_by the prices are summed as stored and a bare number is returned; that fails with 400 when the charges are in more than one currency. With target_currency every month's charges are converted at that month's rate and the rates used are reported. With group_by the response also lists the total of every group.
 // @Produce json
 // @Param user_id query string true "User ID"
-// @Param service_name query string true "Service name"
+// @Param service_name query []string false "Service name or catalog alias, case-insensitive; may be repeated" collectionFormat(multi)
 // @Param period_start query string true "Start date of the period (MM-YYYY)"
-// @Param period_end query string false "End date of the period (MM-YYYY)"
-// @Success 200 {object} map[string]int "Total cost calculated successfully"
-// @Failure 400 {string} string "Invalid parameters"
-// @Failure 500 {string} string "Could not calculate total cost"
+// @Param period_end query string true "End of the period (MM-YYYY), exclusive"
+// @Param target_currency query string false "ISO 4217 currency to convert the total to"
+// @Param group_by query []string false "Group totals by service, month, year or category" collectionFormat(csv)
+// @Success 200 {object} models.TotalCost "Total cost calculated successfully"
+// @Failure 400 {object} models.Problem "Invalid parameters"
+// @Failure 500 {object} models.Problem "Could not calculate total cost"
+// @Security BearerAuth
 // @Router /subscriptions/total-cost [get]
 func (h *SubscriptionsHandler) SumTotalCostSubscriptions(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		http.Error(w, "no user ID", http.StatusBadRequest)
+	filter, targetCurrency, err := parseCostFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err, "invalid parameters")
 		return
 	}
 
-	serviceName := r.URL.Query().Get("service_name")
-	if serviceName == "" {
-		http.Error(w, "no service name", http.StatusBadRequest)
+	if err := authorizeUser(r.Context(), filter.UserID); err != nil {
+		writeError(w, r, err, "cannot read the costs of another user")
 		return
 	}
 
-	var periodStart time.Time
+	reqID := middleware.GetReqID(r.Context())
 
-	perStart := r.URL.Query().Get("period_start")
-	if perStart != "" {
-		parseStartDate, err := utils.ParseDate(perStart)
-		if err != nil {
-			h.log.Error("failed to parse end date", "error", err)
-			return
-		}
+	if targetCurrency != "" {
+		h.convertedTotalCost(w, r, filter, targetCurrency)
+		return
+	}
+
+	// Prices are only added up within a currency; the split is dropped again
+	// once the charges turn out to share one.
+	byCurrency := filter
+	byCurrency.GroupBy = append(slices.Clone(filter.GroupBy), db.GroupByCurrency)
 
-		periodStart = parseStartDate
+	groups, err := h.storage.SumTotalCost(r.Context(), byCurrency)
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not get sum subscriptions", "error", err, "user_id", filter.UserID, "request_id", reqID)
+		writeError(w, r, err, "could not get sum subscriptions")
+		return
 	}
 
-	var periodEnd time.Time
+	currency, err := singleCurrency(groups)
+	if err != nil {
+		writeError(w, r, err, "charges are in several currencies")
+		return
+	}
 
-	perEnd := r.URL.Query().Get("period_end")
-	if perEnd != "" {
-		parseEndDate, err := utils.ParseDate(perEnd)
-		if err != nil {
-			h.log.Error("failed to parse end date", "error", err)
-			return
-		}
+	h.log.InfoContext(r.Context(), "Successfully get sum subscriptions", "user_id", filter.UserID, "request_id", reqID)
+
+	total := newTotalCost(groups, filter.GroupBy)
+
+	var result any
+	if len(filter.GroupBy) == 0 {
+		result = total.TotalCost
+	} else {
+		total.Currency = currency
+		result = total
+	}
 
-		periodEnd = parseEndDate
+	if err := writeJSON(w, http.StatusOK, &result); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "user_id", filter.UserID, "request_id", reqID)
 	}
+}
 
+// convertedTotalCost answers a total-cost request that asks for a single
+// target currency.
+func (h *SubscriptionsHandler) convertedTotalCost(w http.ResponseWriter, r *http.Request, filter db.CostFilter, targetCurrency string) {
 	reqID := middleware.GetReqID(r.Context())
 
-	result, err := h.storage.SumTotalCost(r.Context(), userID, serviceName, periodStart, periodEnd)
+	charges, err := h.storage.MonthlyCharges(r.Context(), filter)
 	if err != nil {
-		h.log.Error("could not get sum subscriptions", "error", err, "user_id", userID, "request_id", reqID)
-		http.Error(w, "could not get sum subscriptions", http.StatusInternalServerError)
+		h.log.ErrorContext(r.Context(), "could not get monthly charges", "error", err, "user_id", filter.UserID, "request_id", reqID)
+		writeError(w, r, err, "could not get sum subscriptions")
 		return
 	}
 
-	h.log.Info("Successfully get sum subscriptions", "user_id", userID, "request_id", reqID)
+	converted, rates, err := fx.Convert(r.Context(), h.rates, charges, targetCurrency)
+	if err != nil {
+		h.log.WarnContext(r.Context(), "could not convert charges", "error", err, "user_id", filter.UserID, "request_id", reqID)
+		writeConversionError(w, r, err, "could not convert total cost")
+		return
+	}
+
+	result := newTotalCost(db.GroupCharges(converted, filter.GroupBy), filter.GroupBy)
+	result.Currency = targetCurrency
+	result.Rates = rates
+
+	h.log.InfoContext(r.Context(), "Successfully get sum subscriptions", "user_id", filter.UserID, "currency", targetCurrency, "request_id", reqID)
+
+	if err := writeJSON(w, http.StatusOK, &result); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "user_id", filter.UserID, "request_id", reqID)
+	}
+}
+
+// singleCurrency returns the currency of groups split by currency and clears
+// it from them. It fails with a validation error on target_currency when there
+// is more than one.
+func singleCurrency(groups []models.CostGroup) (string, error) {
+	var currencies []string
+	for i := range groups {
+		if !slices.Contains(currencies, groups[i].Currency) {
+			currencies = append(currencies, groups[i].Currency)
+		}
 
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(&result); err != nil {
-		h.log.Error("failed to write response", "error", err, "user_id", userID, "request_id", reqID)
+		groups[i].Currency = ""
 	}
+
+	if len(currencies) > 1 {
+		slices.Sort(currencies)
+		return "", utils.NewValidationError("target_currency",
+			"charges are in "+strings.Join(currencies, ", ")+"; set target_currency to add them up")
+	}
+
+	if len(currencies) == 0 {
+		return "", nil
+	}
+
+	return currencies[0], nil
+}
+
+func newTotalCost(groups []models.CostGroup, groupBy []string) *models.TotalCost {
+	result := &models.TotalCost{}
+	for _, group := range groups {
+		result.TotalCost += group.TotalCost
+	}
+
+	if len(groupBy) > 0 {
+		result.Groups = groups
+	}
+
+	return result
+}
+
+// requiredPatchFields are the fields a merge patch may change but not remove.
+// Removing one would leave its zero value, e.g. a price of 0.
+var requiredPatchFields = []string{"service_name", "price", "user_id", "start_date"}
+
+// mergeSubscription applies the merge patch to the request form of the
+// subscription. A null for one of requiredPatchFields is a validation error.
+func mergeSubscription(sub *models.Subscription, patch map[string]json.RawMessage) (models.SubscriptionRequest, error) {
+	var req models.SubscriptionRequest
+
+	validation := &utils.ValidationError{}
+	for _, field := range requiredPatchFields {
+		if value, ok := patch[field]; ok && string(value) == "null" {
+			validation.Add(field, "is required and cannot be null")
+		}
+	}
+
+	if validation.HasErrors() {
+		return req, validation
+	}
+
+	target, err := json.Marshal(utils.ToRequest(sub))
+	if err != nil {
+		return req, err
+	}
+
+	patchBody, err := json.Marshal(patch)
+	if err != nil {
+		return req, err
+	}
+
+	merged, err := utils.MergePatch(target, patchBody)
+	if err != nil {
+		return req, err
+	}
+
+	if err := json.Unmarshal(merged, &req); err != nil {
+		return req, err
+	}
+
+	return req, nil
+}
+
+// isMergePatch accepts application/merge-patch+json and, for clients that
+// cannot set it, plain application/json.
+func isMergePatch(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == mergePatchContentType || mediaType == "application/json"
+}
+
+// subscriptionID reads and validates the {id} path parameter.
+func subscriptionID(r *http.Request) (string, error) {
+	subID := chi.URLParam(r, "id")
+	if err := utils.ValidateUUID(subID); err != nil {
+		return "", utils.NewValidationError("id", err.Error())
+	}
+
+	return subID, nil
 }
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/utils"
+	"testing"
+	"time"
+)
+
+func TestMergeSubscription(t *testing.T) {
+	end := time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)
+	sub := &models.Subscription{
+		ServiceName:     "Netflix",
+		Price:           400,
+		UserID:          "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		StartDate:       time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:         &end,
+		BillingPeriod:   "monthly",
+		BillingInterval: 1,
+		Currency:        "RUB",
+	}
+
+	tests := []struct {
+		patch   string
+		invalid []string
+		check   func(models.SubscriptionRequest) bool
+	}{
+		{patch: `{"price": 500}`, check: func(req models.SubscriptionRequest) bool { return req.Price == 500 && req.EndDate == "12-2025" }},
+		{patch: `{"end_date": null}`, check: func(req models.SubscriptionRequest) bool { return req.EndDate == "" && req.Price == 400 }},
+		{patch: `{"category": null}`, check: func(req models.SubscriptionRequest) bool { return req.Category == "" }},
+		{patch: `{"price": null}`, invalid: []string{"price"}},
+		{patch: `{"service_name": null, "user_id": null}`, invalid: []string{"service_name", "user_id"}},
+		{patch: `{"start_date": null, "end_date": null}`, invalid: []string{"start_date"}},
+	}
+
+	for _, tt := range tests {
+		var patch map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+			t.Fatalf("%s: %v", tt.patch, err)
+		}
+
+		req, err := mergeSubscription(sub, patch)
+		if len(tt.invalid) > 0 {
+			var validation *utils.ValidationError
+			if !errors.As(err, &validation) || len(validation.Fields) != len(tt.invalid) {
+				t.Errorf("%s: got error %v, want validation errors for %v", tt.patch, err, tt.invalid)
+				continue
+			}
+
+			for i, field := range validation.Fields {
+				if field.Name != tt.invalid[i] {
+					t.Errorf("%s: got invalid field %q, want %q", tt.patch, field.Name, tt.invalid[i])
+				}
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.patch, err)
+			continue
+		}
+
+		if !tt.check(req) {
+			t.Errorf("%s: unexpected merge result %+v", tt.patch, req)
+		}
+	}
+}
+
+func TestSingleCurrency(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups []models.CostGroup
+		want   string
+		valid  bool
+	}{
+		{name: "no charges", valid: true},
+		{
+			name:   "one currency",
+			groups: []models.CostGroup{{ServiceName: "Netflix", Currency: "RUB", TotalCost: 200}, {ServiceName: "Okko", Currency: "RUB", TotalCost: 100}},
+			want:   "RUB",
+			valid:  true,
+		},
+		{
+			name:   "several currencies",
+			groups: []models.CostGroup{{Currency: "USD", TotalCost: 10}, {Currency: "RUB", TotalCost: 200}},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := singleCurrency(tt.groups)
+		if !tt.valid {
+			var validation *utils.ValidationError
+			if !errors.As(err, &validation) || validation.Fields[0].Name != "target_currency" {
+				t.Errorf("%s: got error %v, want a target_currency validation error", tt.name, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("%s: got currency %q, want %q", tt.name, got, tt.want)
+		}
+
+		for _, group := range tt.groups {
+			if group.Currency != "" {
+				t.Errorf("%s: currency %q left on a group", tt.name, group.Currency)
+			}
+		}
+	}
+}
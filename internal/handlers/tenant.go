@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"subscription-aggregator/internal/auth"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
+)
+
+// ResolveTenant puts the tenant of the request into its context. An
+// authenticated caller belongs to the tenant of its token or API key, and an
+// X-Tenant-ID header naming another tenant is refused; without
+// authentication the header picks the tenant. It must run after
+// Authenticate.
+func ResolveTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested := strings.TrimSpace(r.Header.Get(tenant.Header))
+		if requested != "" {
+			if err := tenant.Validate(requested); err != nil {
+				writeProblem(w, r, http.StatusBadRequest, "invalid tenant", models.InvalidParam{Name: tenant.Header, Reason: err.Error()})
+				return
+			}
+		}
+
+		id := requested
+		if principal := auth.FromContext(r.Context()); principal != nil {
+			id = principal.Tenant
+			if requested != "" && requested != id {
+				writeProblem(w, r, http.StatusForbidden, "credentials are not valid for tenant "+requested)
+				return
+			}
+		}
+
+		if id == "" {
+			id = tenant.Default
+		}
+
+		next.ServeHTTP(w, r.WithContext(tenant.WithID(r.Context(), id)))
+	})
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/utils"
+)
+
+type UsersHandler struct {
+	storage db.UserStorage
+	log     *slog.Logger
+}
+
+func NewUsersHandler(storage db.UserStorage, log *slog.Logger) *UsersHandler {
+	return &UsersHandler{
+		storage: storage,
+		log:     log,
+	}
+}
+
+// CreateUser creates a new user.
+// @Summary Create a user
+// @Description Creates a user that subscriptions can belong to. The ID is generated unless the request carries one, for users known to another system.
+// @Accept json
+// @Produce json
+// @Param user body models.UserRequest true "User data"
+// @Success 201 {object} models.User "User created successfully"
+// @Header 201 {string} Location "URL of the new user"
+// @Failure 400 {object} models.Problem "Invalid request body or data"
+// @Failure 409 {object} models.Problem "ID or email is already used by another user"
+// @Failure 500 {object} models.Problem "Could not save user"
+// @Security BearerAuth
+// @Router /users [post]
+func (h *UsersHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req models.UserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	user, err := utils.MapUserRequest(req)
+	if err != nil {
+		writeError(w, r, err, "invalid request data")
+		return
+	}
+
+	if err := authorizeUser(r.Context(), user.ID); err != nil {
+		writeError(w, r, err, "only admins can create other users")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	if err := h.storage.CreateUser(r.Context(), user); err != nil {
+		h.log.ErrorContext(r.Context(), "could not save user", "error", err, "user_id", user.ID, "request_id", reqID)
+		writeError(w, r, err, "could not save user")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully saved user", "user_id", user.ID, "request_id", reqID)
+
+	w.Header().Set("Location", "/users/"+user.ID)
+
+	if err := writeJSON(w, http.StatusCreated, user); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "user_id", user.ID, "request_id", reqID)
+	}
+}
+
+// GetUser gets a user by ID.
+// @Summary Get a user by ID
+// @Description Get a user by its unique ID.
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 200 {object} models.User "User found successfully"
+// @Failure 400 {object} models.Problem "Invalid user ID"
+// @Failure 404 {object} models.Problem "User not found"
+// @Failure 500 {object} models.Problem "Could not get user"
+// @Security BearerAuth
+// @Router /users/{user_id} [get]
+func (h *UsersHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	id, err := userID(r)
+	if err != nil {
+		writeError(w, r, err, "invalid user ID")
+		return
+	}
+
+	if err := authorizeUser(r.Context(), id); err != nil {
+		writeError(w, r, err, "cannot access another user")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	user, err := h.storage.GetUser(r.Context(), id)
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not get user", "error", err, "user_id", id, "request_id", reqID)
+		writeError(w, r, err, "could not get user")
+		return
+	}
+
+	if err := writeJSON(w, http.StatusOK, user); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "user_id", id, "request_id", reqID)
+	}
+}
+
+// ListUsers lists users.
+// @Summary List users
+// @Description Get every user, oldest first.
+// @Produce json
+// @Success 200 {array} models.User "Users retrieved successfully"
+// @Failure 500 {object} models.Problem "Could not get users"
+// @Security BearerAuth
+// @Router /users [get]
+func (h *UsersHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetReqID(r.Context())
+
+	users, err := h.storage.ListUsers(r.Context())
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "could not list users", "error", err, "request_id", reqID)
+		writeError(w, r, err, "could not get users")
+		return
+	}
+
+	if err := writeJSON(w, http.StatusOK, &users); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "request_id", reqID)
+	}
+}
+
+// UpdateUser updates a user.
+// @Summary Update a user
+// @Description Replaces the profile of a user.
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Param user body models.UserRequest true "Updated user data"
+// @Success 200 {object} models.User "User updated successfully"
+// @Failure 400 {object} models.Problem "Invalid request body or data"
+// @Failure 404 {object} models.Problem "User not found"
+// @Failure 409 {object} models.Problem "Email is already used by another user"
+// @Failure 500 {object} models.Problem "Could not update user"
+// @Security BearerAuth
+// @Router /users/{user_id} [put]
+func (h *UsersHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	id, err := userID(r)
+	if err != nil {
+		writeError(w, r, err, "invalid user ID")
+		return
+	}
+
+	if err := authorizeUser(r.Context(), id); err != nil {
+		writeError(w, r, err, "cannot access another user")
+		return
+	}
+
+	var req models.UserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	user, err := utils.MapUserUpdateRequest(id, req)
+	if err != nil {
+		writeError(w, r, err, "invalid request data")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	if err := h.storage.UpdateUser(r.Context(), user); err != nil {
+		h.log.ErrorContext(r.Context(), "could not update user", "error", err, "user_id", id, "request_id", reqID)
+		writeError(w, r, err, "could not update user")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully updated user", "user_id", id, "request_id", reqID)
+
+	if err := writeJSON(w, http.StatusOK, user); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write response", "error", err, "user_id", id, "request_id", reqID)
+	}
+}
+
+// DeleteUser deletes a user and their subscriptions.
+// @Summary Delete a user
+// @Description Permanently deletes a user together with all of their subscriptions, including soft-deleted ones. The audit history of the subscriptions is kept and gets a deleted event for every subscription that was not deleted yet.
+// @Produce json
+// @Param user_id path string true "User ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.Problem "Invalid user ID"
+// @Failure 404 {object} models.Problem "User not found"
+// @Failure 500 {object} models.Problem "Could not delete user"
+// @Security BearerAuth
+// @Router /users/{user_id} [delete]
+func (h *UsersHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, err := userID(r)
+	if err != nil {
+		writeError(w, r, err, "invalid user ID")
+		return
+	}
+
+	if err := authorizeUser(r.Context(), id); err != nil {
+		writeError(w, r, err, "cannot access another user")
+		return
+	}
+
+	reqID := middleware.GetReqID(r.Context())
+
+	if err := h.storage.DeleteUser(r.Context(), id); err != nil {
+		h.log.ErrorContext(r.Context(), "could not delete user", "error", err, "user_id", id, "request_id", reqID)
+		writeError(w, r, err, "could not delete user")
+		return
+	}
+
+	h.log.InfoContext(r.Context(), "Successfully deleted user", "user_id", id, "request_id", reqID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func userID(r *http.Request) (string, error) {
+	id := chi.URLParam(r, "user_id")
+	if err := utils.ValidateUUID(id); err != nil {
+		return "", utils.NewValidationError("user_id", err.Error())
+	}
+
+	return id, nil
+}
@@ -0,0 +1,120 @@
+// Package ical writes iCalendar feeds as described in RFC 5545.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineLength is the longest content line in octets, not counting CRLF.
+const maxLineLength = 75
+
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Event is an all-day event on Date.
+type Event struct {
+	UID         string
+	Stamp       time.Time
+	Date        time.Time
+	Summary     string
+	Description string
+	Alarm       *Alarm
+}
+
+// Alarm shows a reminder DaysBefore days ahead of its event.
+type Alarm struct {
+	DaysBefore  int
+	Description string
+}
+
+// Write encodes the calendar with CRLF line endings and folded long lines.
+func (c *Calendar) Write(w io.Writer) error {
+	out := &writer{buf: bufio.NewWriter(w)}
+
+	out.line("BEGIN:VCALENDAR")
+	out.line("VERSION:2.0")
+	out.line("PRODID:" + escape(c.ProdID))
+	out.line("CALSCALE:GREGORIAN")
+	out.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		out.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+
+	for _, event := range c.Events {
+		out.line("BEGIN:VEVENT")
+		out.line("UID:" + escape(event.UID))
+		out.line("DTSTAMP:" + event.Stamp.UTC().Format("20060102T150405Z"))
+		out.line("DTSTART;VALUE=DATE:" + event.Date.Format("20060102"))
+		out.line("DTEND;VALUE=DATE:" + event.Date.AddDate(0, 0, 1).Format("20060102"))
+		out.line("SUMMARY:" + escape(event.Summary))
+		if event.Description != "" {
+			out.line("DESCRIPTION:" + escape(event.Description))
+		}
+		out.line("TRANSP:TRANSPARENT")
+
+		if event.Alarm != nil {
+			out.line("BEGIN:VALARM")
+			out.line("ACTION:DISPLAY")
+			out.line(fmt.Sprintf("TRIGGER:-P%dD", event.Alarm.DaysBefore))
+			out.line("DESCRIPTION:" + escape(event.Alarm.Description))
+			out.line("END:VALARM")
+		}
+
+		out.line("END:VEVENT")
+	}
+
+	out.line("END:VCALENDAR")
+
+	if out.err != nil {
+		return out.err
+	}
+
+	return out.buf.Flush()
+}
+
+// writer keeps the first error so Write can emit lines without checking each.
+type writer struct {
+	buf *bufio.Writer
+	err error
+}
+
+// line writes a content line, folding it into continuation lines that start
+// with a space. Folds never split a UTF-8 sequence.
+func (w *writer) line(text string) {
+	if w.err != nil {
+		return
+	}
+
+	limit := maxLineLength
+	for len(text) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+
+		if _, w.err = w.buf.WriteString(text[:cut] + "\r\n "); w.err != nil {
+			return
+		}
+
+		text = text[cut:]
+		// The leading space of a continuation line counts towards its length.
+		limit = maxLineLength - 1
+	}
+
+	_, w.err = w.buf.WriteString(text + "\r\n")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escape encodes a TEXT value.
+func escape(text string) string {
+	return escaper.Replace(text)
+}
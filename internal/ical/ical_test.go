@@ -0,0 +1,139 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Netflix", want: "Netflix"},
+		{text: "a,b;c", want: `a\,b\;c`},
+		{text: `C:\path`, want: `C:\\path`},
+		{text: "line one\nline two", want: `line one\nline two`},
+		{text: "line one\r\nline two", want: `line one\nline two`},
+		{text: `\n`, want: `\\n`},
+	}
+
+	for _, tt := range tests {
+		if got := escape(tt.text); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWriteFoldsLongLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary string
+	}{
+		{name: "short", summary: "Netflix"},
+		{name: "exactly one line", summary: strings.Repeat("a", maxLineLength-len("SUMMARY:"))},
+		{name: "one octet over", summary: strings.Repeat("a", maxLineLength-len("SUMMARY:")+1)},
+		{name: "ascii", summary: strings.Repeat("Netflix Premium ", 20)},
+		// Two octets per rune, so an odd limit falls inside a rune.
+		{name: "cyrillic", summary: strings.Repeat("Подписка Кинопоиск ", 10)},
+		{name: "emoji", summary: strings.Repeat("🎬", 60)},
+		{name: "escaped", summary: strings.Repeat("a,b;c\\d\n", 20)},
+	}
+
+	for _, tt := range tests {
+		calendar := &Calendar{ProdID: "-//test//EN", Events: []Event{{UID: "1", Summary: tt.summary}}}
+
+		var buf bytes.Buffer
+		if err := calendar.Write(&buf); err != nil {
+			t.Fatalf("%s: write: %v", tt.name, err)
+		}
+
+		out := buf.String()
+		if !strings.HasSuffix(out, "\r\n") {
+			t.Errorf("%s: output does not end in CRLF", tt.name)
+		}
+
+		if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+			t.Errorf("%s: got a bare LF", tt.name)
+		}
+
+		for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+			if len(line) > maxLineLength {
+				t.Errorf("%s: line of %d octets: %q", tt.name, len(line), line)
+			}
+
+			if !utf8.ValidString(line) {
+				t.Errorf("%s: fold splits a rune: %q", tt.name, line)
+			}
+		}
+
+		unfolded := strings.ReplaceAll(out, "\r\n ", "")
+		if want := "\r\nSUMMARY:" + escape(tt.summary) + "\r\n"; !strings.Contains(unfolded, want) {
+			t.Errorf("%s: unfolded output lacks %q", tt.name, want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	stamp := time.Date(2025, time.June, 15, 10, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+	date := time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+	calendar := &Calendar{
+		ProdID: "-//test//EN",
+		Name:   "Renewals",
+		Events: []Event{
+			{UID: "a@test", Stamp: stamp, Date: date, Summary: "Netflix: 400 RUB", Alarm: &Alarm{DaysBefore: 3, Description: "Netflix renews soon"}},
+			{UID: "b@test", Stamp: stamp, Date: date.AddDate(0, 0, 30), Summary: "Okko", Description: "Okko, monthly", Alarm: &Alarm{Description: "Okko renews today"}},
+		},
+	}
+
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//test//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:Renewals",
+		"BEGIN:VEVENT",
+		"UID:a@test",
+		"DTSTAMP:20250615T073000Z",
+		"DTSTART;VALUE=DATE:20250701",
+		"DTEND;VALUE=DATE:20250702",
+		"SUMMARY:Netflix: 400 RUB",
+		"TRANSP:TRANSPARENT",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"TRIGGER:-P3D",
+		"DESCRIPTION:Netflix renews soon",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:b@test",
+		"DTSTAMP:20250615T073000Z",
+		"DTSTART;VALUE=DATE:20250731",
+		"DTEND;VALUE=DATE:20250801",
+		"SUMMARY:Okko",
+		`DESCRIPTION:Okko\, monthly`,
+		"TRANSP:TRANSPARENT",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"TRIGGER:-P0D",
+		"DESCRIPTION:Okko renews today",
+		"END:VALARM",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+
+	var buf bytes.Buffer
+	if err := calendar.Write(&buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
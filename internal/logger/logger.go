@@ -1,11 +1,39 @@
 package logger
 
 import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
 	"log/slog"
 	"os"
 )
 
 func NewLogger() *slog.Logger {
 	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})
-	return slog.New(handler)
+	return slog.New(traceHandler{handler})
+}
+
+// traceHandler adds the trace_id and span_id of the record's context, so
+// that the lines logged with a request's context (and its request_id) lead
+// to its trace.
+type traceHandler struct {
+	slog.Handler
+}
+
+func (h traceHandler) Handle(ctx context.Context, record slog.Record) error {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceHandler) WithGroup(name string) slog.Handler {
+	return traceHandler{h.Handler.WithGroup(name)}
 }
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"testing"
+)
+
+func TestTraceHandler(t *testing.T) {
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		wantTrace string
+		wantSpan  string
+	}{
+		{name: "in a span", ctx: trace.ContextWithSpanContext(context.Background(), spanContext), wantTrace: "4bf92f3577b34da6a3ce929d0e0e4736", wantSpan: "00f067aa0ba902b7"},
+		{name: "outside a span", ctx: context.Background()},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		log := slog.New(traceHandler{slog.NewJSONHandler(&out, nil)}).With("request_id", "req-1")
+
+		log.InfoContext(tt.ctx, "Request handled")
+
+		var line map[string]any
+		if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+			t.Fatalf("%s: decode %q: %v", tt.name, out.String(), err)
+		}
+
+		if line["request_id"] != "req-1" {
+			t.Errorf("%s: got request_id %v, want req-1", tt.name, line["request_id"])
+		}
+
+		if got, _ := line["trace_id"].(string); got != tt.wantTrace {
+			t.Errorf("%s: got trace_id %q, want %q", tt.name, got, tt.wantTrace)
+		}
+
+		if got, _ := line["span_id"].(string); got != tt.wantSpan {
+			t.Errorf("%s: got span_id %q, want %q", tt.name, got, tt.wantSpan)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Package metrics exposes the Prometheus metrics of the service: HTTP
+// traffic, storage timings, the connection pool and subscription revenue.
+package metrics
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// unmatchedRoute labels requests that no route matched, so that probing
+// random paths cannot blow up the number of series.
+const unmatchedRoute = "unmatched"
+
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	storageDuration *prometheus.HistogramVec
+	log             *slog.Logger
+}
+
+// New creates the HTTP and storage metrics on a registry of their own, next to
+// the Go runtime and process metrics.
+func New(log *slog.Logger) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, chi route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time to serve HTTP requests by method, chi route pattern and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "storage_operation_duration_seconds",
+			Help:    "Time spent in storage methods by method and outcome.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "outcome"}),
+		log: log,
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.storageDuration,
+	)
+
+	return m
+}
+
+// MustRegister adds collectors to the registry and panics when one clashes
+// with a registered collector.
+func (m *Metrics) MustRegister(collectors ...prometheus.Collector) {
+	m.registry.MustRegister(collectors...)
+}
+
+// Handler serves the metrics in the Prometheus text format. A collector that
+// fails is logged and left out rather than failing the whole scrape.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		ErrorLog:      slog.NewLogLogger(m.log.Handler(), slog.LevelError),
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// Middleware counts and times requests. The route pattern is only known once
+// chi has routed the request, so it is read after the handler returns.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		labels := prometheus.Labels{"method": r.Method, "route": route, "status": strconv.Itoa(status)}
+		m.requests.With(labels).Inc()
+		m.requestDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// observeStorage records how long a storage method took since start.
+func (m *Metrics) observeStorage(method string, start time.Time, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+
+	m.storageDuration.WithLabelValues(method, outcome).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testLog = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestMiddleware(t *testing.T) {
+	m := New(testLog)
+
+	router := chi.NewRouter()
+	router.Use(m.Middleware)
+	router.Get("/subscriptions/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	router.Post("/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodGet, path: "/subscriptions/1"},
+		{method: http.MethodGet, path: "/subscriptions/2"},
+		{method: http.MethodPost, path: "/subscriptions"},
+		{method: http.MethodGet, path: "/random/1"},
+		{method: http.MethodGet, path: "/random/2"},
+	}
+
+	for _, req := range requests {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	// Paths that share a route share a series, and so do unmatched paths.
+	expected := `
+# HELP http_requests_total HTTP requests by method, chi route pattern and status code.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="/subscriptions/{id}",status="200"} 2
+http_requests_total{method="GET",route="unmatched",status="404"} 2
+http_requests_total{method="POST",route="/subscriptions",status="201"} 1
+`
+
+	if err := testutil.CollectAndCompare(m.requests, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+
+	if got := testutil.CollectAndCount(m.requestDuration); got != 3 {
+		t.Errorf("got %d duration series, want 3", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m := New(testLog)
+	m.requests.WithLabelValues(http.MethodGet, "/subscriptions", "200").Inc()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/subscriptions",status="200"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %s", want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reads the statistics of a pgx pool at scrape time.
+type poolCollector struct {
+	stat func() *pgxpool.Stat
+
+	acquiredConns       *prometheus.Desc
+	idleConns           *prometheus.Desc
+	constructingConns   *prometheus.Desc
+	totalConns          *prometheus.Desc
+	maxConns            *prometheus.Desc
+	acquires            *prometheus.Desc
+	acquireDuration     *prometheus.Desc
+	canceledAcquires    *prometheus.Desc
+	emptyAcquires       *prometheus.Desc
+	newConns            *prometheus.Desc
+	maxLifetimeDestroys *prometheus.Desc
+	maxIdleTimeDestroys *prometheus.Desc
+}
+
+// NewPoolCollector reports the connection pool whose statistics stat returns,
+// such as postgres.Storage.Stat.
+func NewPoolCollector(stat func() *pgxpool.Stat) prometheus.Collector {
+	desc := func(name string, help string) *prometheus.Desc {
+		return prometheus.NewDesc("pgxpool_"+name, help, nil, nil)
+	}
+
+	return &poolCollector{
+		stat:                stat,
+		acquiredConns:       desc("acquired_conns", "Connections currently in use."),
+		idleConns:           desc("idle_conns", "Connections currently idle."),
+		constructingConns:   desc("constructing_conns", "Connections being opened."),
+		totalConns:          desc("total_conns", "Connections currently open or being opened."),
+		maxConns:            desc("max_conns", "Largest size the pool may grow to."),
+		acquires:            desc("acquires_total", "Successful connection acquires."),
+		acquireDuration:     desc("acquire_duration_seconds_total", "Time spent in successful connection acquires."),
+		canceledAcquires:    desc("canceled_acquires_total", "Acquires canceled by their context."),
+		emptyAcquires:       desc("empty_acquires_total", "Acquires that had to wait for a connection."),
+		newConns:            desc("new_conns_total", "Connections opened."),
+		maxLifetimeDestroys: desc("max_lifetime_destroys_total", "Connections closed for exceeding the maximum lifetime."),
+		maxIdleTimeDestroys: desc("max_idle_time_destroys_total", "Connections closed for exceeding the maximum idle time."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.stat()
+
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	gauge(c.acquiredConns, float64(stat.AcquiredConns()))
+	gauge(c.idleConns, float64(stat.IdleConns()))
+	gauge(c.constructingConns, float64(stat.ConstructingConns()))
+	gauge(c.totalConns, float64(stat.TotalConns()))
+	gauge(c.maxConns, float64(stat.MaxConns()))
+	counter(c.acquires, float64(stat.AcquireCount()))
+	counter(c.acquireDuration, stat.AcquireDuration().Seconds())
+	counter(c.canceledAcquires, float64(stat.CanceledAcquireCount()))
+	counter(c.emptyAcquires, float64(stat.EmptyAcquireCount()))
+	counter(c.newConns, float64(stat.NewConnsCount()))
+	counter(c.maxLifetimeDestroys, float64(stat.MaxLifetimeDestroyCount()))
+	counter(c.maxIdleTimeDestroys, float64(stat.MaxIdleDestroyCount()))
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"strings"
+	"testing"
+)
+
+func TestPoolCollector(t *testing.T) {
+	// The pool opens no connection before the first acquire.
+	pool, err := pgxpool.New(context.Background(), "postgres://user@localhost:5432/db?pool_max_conns=7")
+	if err != nil {
+		t.Fatalf("create pool: %v", err)
+	}
+
+	defer pool.Close()
+
+	expected := `
+# HELP pgxpool_max_conns Largest size the pool may grow to.
+# TYPE pgxpool_max_conns gauge
+pgxpool_max_conns 7
+# HELP pgxpool_total_conns Connections currently open or being opened.
+# TYPE pgxpool_total_conns gauge
+pgxpool_total_conns 0
+`
+
+	collector := NewPoolCollector(pool.Stat)
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "pgxpool_max_conns", "pgxpool_total_conns"); err != nil {
+		t.Error(err)
+	}
+
+	if got := testutil.CollectAndCount(collector); got != 12 {
+		t.Errorf("got %d pool metrics, want 12", got)
+	}
+}
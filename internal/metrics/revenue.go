@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"subscription-aggregator/internal/db"
+	"time"
+)
+
+// revenueTimeout bounds the query behind a scrape, which has no context of
+// its own.
+const revenueTimeout = 5 * time.Second
+
+// revenueCollector reads the active subscriptions and their monthly recurring
+// revenue from storage at scrape time.
+type revenueCollector struct {
+	storage db.StatsStorage
+
+	active  *prometheus.Desc
+	revenue *prometheus.Desc
+}
+
+// NewRevenueCollector reports the active subscriptions of all tenants together
+// per service, and their monthly recurring revenue per service and currency.
+// There is no tenant label, so a scrape does not reveal the business of any
+// single tenant. Revenue in different currencies is never added up. A failed query is
+// reported to the scrape, which logs it and serves the other metrics.
+func NewRevenueCollector(storage db.StatsStorage) prometheus.Collector {
+	return &revenueCollector{
+		storage: storage,
+		active: prometheus.NewDesc(
+			"subscriptions_active",
+			"Subscriptions that are active now, by service.",
+			[]string{"service"}, nil,
+		),
+		revenue: prometheus.NewDesc(
+			"subscriptions_monthly_recurring_revenue",
+			"Monthly recurring revenue of the active subscriptions, by service and currency.",
+			[]string{"service", "currency"}, nil,
+		),
+	}
+}
+
+func (c *revenueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.active
+	ch <- c.revenue
+}
+
+func (c *revenueCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), revenueTimeout)
+	defer cancel()
+
+	revenues, err := c.storage.ServiceRevenue(ctx, time.Now().UTC())
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.revenue, err)
+		return
+	}
+
+	active := make(map[string]int)
+	for _, revenue := range revenues {
+		active[revenue.ServiceName] += revenue.Active
+
+		ch <- prometheus.MustNewConstMetric(c.revenue, prometheus.GaugeValue, revenue.MonthlyRevenue,
+			revenue.ServiceName, revenue.Currency)
+	}
+
+	for serviceName, count := range active {
+		ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, float64(count), serviceName)
+	}
+}
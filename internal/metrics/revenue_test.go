@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"strings"
+	"subscription-aggregator/internal/models"
+	"testing"
+	"time"
+)
+
+type stubStats []models.ServiceRevenue
+
+func (s stubStats) ServiceRevenue(context.Context, time.Time) ([]models.ServiceRevenue, error) {
+	return s, nil
+}
+
+func TestRevenueCollectorHasNoTenantLabel(t *testing.T) {
+	collector := NewRevenueCollector(stubStats{
+		{ServiceName: "Netflix", Currency: "RUB", Active: 3, MonthlyRevenue: 1200},
+		{ServiceName: "Netflix", Currency: "USD", Active: 1, MonthlyRevenue: 10},
+		{ServiceName: "Okko", Currency: "RUB", Active: 2, MonthlyRevenue: 600},
+	})
+
+	expected := `
+# HELP subscriptions_active Subscriptions that are active now, by service.
+# TYPE subscriptions_active gauge
+subscriptions_active{service="Netflix"} 4
+subscriptions_active{service="Okko"} 2
+# HELP subscriptions_monthly_recurring_revenue Monthly recurring revenue of the active subscriptions, by service and currency.
+# TYPE subscriptions_monthly_recurring_revenue gauge
+subscriptions_monthly_recurring_revenue{currency="RUB",service="Netflix"} 1200
+subscriptions_monthly_recurring_revenue{currency="USD",service="Netflix"} 10
+subscriptions_monthly_recurring_revenue{currency="RUB",service="Okko"} 600
+`
+
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,202 @@
+package metrics
+
+import (
+	"context"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+	"time"
+)
+
+// Storage times every method of the storage it wraps.
+type Storage struct {
+	next    db.Storage
+	metrics *Metrics
+}
+
+// InstrumentStorage wraps storage so that each call is recorded in the
+// storage_operation_duration_seconds histogram under its method name.
+func InstrumentStorage(storage db.Storage, m *Metrics) *Storage {
+	return &Storage{
+		next:    storage,
+		metrics: m,
+	}
+}
+
+func (s *Storage) observe(method string, start time.Time, err *error) {
+	s.metrics.observeStorage(method, start, *err)
+}
+
+func (s *Storage) Save(ctx context.Context, sub *models.Subscription) (err error) {
+	defer s.observe("Save", time.Now(), &err)
+	return s.next.Save(ctx, sub)
+}
+
+func (s *Storage) Delete(ctx context.Context, id string) (err error) {
+	defer s.observe("Delete", time.Now(), &err)
+	return s.next.Delete(ctx, id)
+}
+
+func (s *Storage) Restore(ctx context.Context, id string) (err error) {
+	defer s.observe("Restore", time.Now(), &err)
+	return s.next.Restore(ctx, id)
+}
+
+func (s *Storage) Purge(ctx context.Context, deletedBefore time.Time) (result int, err error) {
+	defer s.observe("Purge", time.Now(), &err)
+	return s.next.Purge(ctx, deletedBefore)
+}
+
+func (s *Storage) GetByID(ctx context.Context, id string, includeDeleted bool) (result *models.Subscription, err error) {
+	defer s.observe("GetByID", time.Now(), &err)
+	return s.next.GetByID(ctx, id, includeDeleted)
+}
+
+func (s *Storage) List(ctx context.Context, filter db.ListFilter) (result *models.SubscriptionPage, err error) {
+	defer s.observe("List", time.Now(), &err)
+	return s.next.List(ctx, filter)
+}
+
+func (s *Storage) Export(ctx context.Context, filter db.ListFilter, fn func(*models.Subscription) error) (err error) {
+	defer s.observe("Export", time.Now(), &err)
+	return s.next.Export(ctx, filter, fn)
+}
+
+func (s *Storage) Update(ctx context.Context, sub *models.Subscription) (err error) {
+	defer s.observe("Update", time.Now(), &err)
+	return s.next.Update(ctx, sub)
+}
+
+func (s *Storage) ApplyBatch(ctx context.Context, ops []db.BatchOperation, atomic bool) (result []db.BatchResult, err error) {
+	defer s.observe("ApplyBatch", time.Now(), &err)
+	return s.next.ApplyBatch(ctx, ops, atomic)
+}
+
+func (s *Storage) SumTotalCost(ctx context.Context, filter db.CostFilter) (result []models.CostGroup, err error) {
+	defer s.observe("SumTotalCost", time.Now(), &err)
+	return s.next.SumTotalCost(ctx, filter)
+}
+
+func (s *Storage) MonthlyCharges(ctx context.Context, filter db.CostFilter) (result []models.MonthlyCharge, err error) {
+	defer s.observe("MonthlyCharges", time.Now(), &err)
+	return s.next.MonthlyCharges(ctx, filter)
+}
+
+func (s *Storage) SaveRates(ctx context.Context, rates []models.FXRate) (err error) {
+	defer s.observe("SaveRates", time.Now(), &err)
+	return s.next.SaveRates(ctx, rates)
+}
+
+func (s *Storage) ListRates(ctx context.Context) (result []models.FXRate, err error) {
+	defer s.observe("ListRates", time.Now(), &err)
+	return s.next.ListRates(ctx)
+}
+
+func (s *Storage) GetRate(ctx context.Context, baseCurrency string, quoteCurrency string, month time.Time) (result *models.FXRate, err error) {
+	defer s.observe("GetRate", time.Now(), &err)
+	return s.next.GetRate(ctx, baseCurrency, quoteCurrency, month)
+}
+
+func (s *Storage) CreateUser(ctx context.Context, user *models.User) (err error) {
+	defer s.observe("CreateUser", time.Now(), &err)
+	return s.next.CreateUser(ctx, user)
+}
+
+func (s *Storage) GetUser(ctx context.Context, id string) (result *models.User, err error) {
+	defer s.observe("GetUser", time.Now(), &err)
+	return s.next.GetUser(ctx, id)
+}
+
+func (s *Storage) ListUsers(ctx context.Context) (result []models.User, err error) {
+	defer s.observe("ListUsers", time.Now(), &err)
+	return s.next.ListUsers(ctx)
+}
+
+func (s *Storage) UpdateUser(ctx context.Context, user *models.User) (err error) {
+	defer s.observe("UpdateUser", time.Now(), &err)
+	return s.next.UpdateUser(ctx, user)
+}
+
+func (s *Storage) DeleteUser(ctx context.Context, id string) (err error) {
+	defer s.observe("DeleteUser", time.Now(), &err)
+	return s.next.DeleteUser(ctx, id)
+}
+
+func (s *Storage) CreateService(ctx context.Context, svc *models.Service) (err error) {
+	defer s.observe("CreateService", time.Now(), &err)
+	return s.next.CreateService(ctx, svc)
+}
+
+func (s *Storage) GetService(ctx context.Context, id string) (result *models.Service, err error) {
+	defer s.observe("GetService", time.Now(), &err)
+	return s.next.GetService(ctx, id)
+}
+
+func (s *Storage) ListServices(ctx context.Context) (result []models.Service, err error) {
+	defer s.observe("ListServices", time.Now(), &err)
+	return s.next.ListServices(ctx)
+}
+
+func (s *Storage) UpdateService(ctx context.Context, svc *models.Service) (err error) {
+	defer s.observe("UpdateService", time.Now(), &err)
+	return s.next.UpdateService(ctx, svc)
+}
+
+func (s *Storage) DeleteService(ctx context.Context, id string) (err error) {
+	defer s.observe("DeleteService", time.Now(), &err)
+	return s.next.DeleteService(ctx, id)
+}
+
+func (s *Storage) CreateAPIKey(ctx context.Context, key *models.APIKey, hash string) (err error) {
+	defer s.observe("CreateAPIKey", time.Now(), &err)
+	return s.next.CreateAPIKey(ctx, key, hash)
+}
+
+func (s *Storage) ListAPIKeys(ctx context.Context) (result []models.APIKey, err error) {
+	defer s.observe("ListAPIKeys", time.Now(), &err)
+	return s.next.ListAPIKeys(ctx)
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, id string) (err error) {
+	defer s.observe("RevokeAPIKey", time.Now(), &err)
+	return s.next.RevokeAPIKey(ctx, id)
+}
+
+func (s *Storage) RotateAPIKey(ctx context.Context, id string, prefix string, hash string) (result *models.APIKey, err error) {
+	defer s.observe("RotateAPIKey", time.Now(), &err)
+	return s.next.RotateAPIKey(ctx, id, prefix, hash)
+}
+
+func (s *Storage) UseAPIKey(ctx context.Context, hash string) (result *models.APIKey, err error) {
+	defer s.observe("UseAPIKey", time.Now(), &err)
+	return s.next.UseAPIKey(ctx, hash)
+}
+
+func (s *Storage) History(ctx context.Context, subscriptionID string) (result []models.SubscriptionEvent, err error) {
+	defer s.observe("History", time.Now(), &err)
+	return s.next.History(ctx, subscriptionID)
+}
+
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, key string, requestHash string, notBefore time.Time) (result *models.IdempotencyRecord, err error) {
+	defer s.observe("ReserveIdempotencyKey", time.Now(), &err)
+	return s.next.ReserveIdempotencyKey(ctx, key, requestHash, notBefore)
+}
+
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, location string, response []byte) (err error) {
+	defer s.observe("CompleteIdempotencyKey", time.Now(), &err)
+	return s.next.CompleteIdempotencyKey(ctx, key, statusCode, location, response)
+}
+
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, key string) (err error) {
+	defer s.observe("ReleaseIdempotencyKey", time.Now(), &err)
+	return s.next.ReleaseIdempotencyKey(ctx, key)
+}
+
+func (s *Storage) PurgeIdempotencyKeys(ctx context.Context, createdBefore time.Time) (result int, err error) {
+	defer s.observe("PurgeIdempotencyKeys", time.Now(), &err)
+	return s.next.PurgeIdempotencyKeys(ctx, createdBefore)
+}
+
+func (s *Storage) ServiceRevenue(ctx context.Context, at time.Time) (result []models.ServiceRevenue, err error) {
+	defer s.observe("ServiceRevenue", time.Now(), &err)
+	return s.next.ServiceRevenue(ctx, at)
+}
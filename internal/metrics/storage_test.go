@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/db/memory"
+	"subscription-aggregator/internal/models"
+	"testing"
+)
+
+func TestInstrumentStorage(t *testing.T) {
+	m := New(testLog)
+	storage := InstrumentStorage(memory.New(testLog), m)
+	ctx := context.Background()
+
+	user := &models.User{ID: "4f2b1c3e-8d6a-4b5f-9c7e-1a2b3c4d5e6f", DisplayName: "Test", DefaultCurrency: models.DefaultCurrency, Timezone: "UTC"}
+	if err := storage.CreateUser(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	if _, err := storage.GetUser(ctx, user.ID); err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+
+	if _, err := storage.GetUser(ctx, "0d0b7a0e-7f3c-4b8e-a1f5-9e6d5c4b3a21"); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("get missing user: got %v, want %v", err, db.ErrNotFound)
+	}
+
+	tests := []struct {
+		method  string
+		outcome string
+		want    uint64
+	}{
+		{method: "CreateUser", outcome: "ok", want: 1},
+		{method: "GetUser", outcome: "ok", want: 1},
+		{method: "GetUser", outcome: "error", want: 1},
+		{method: "CreateUser", outcome: "error", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := sampleCount(t, m, tt.method, tt.outcome); got != tt.want {
+			t.Errorf("%s %s: got %d observations, want %d", tt.method, tt.outcome, got, tt.want)
+		}
+	}
+
+	if got := testutil.CollectAndCount(m.storageDuration); got != 3 {
+		t.Errorf("got %d storage series, want 3", got)
+	}
+}
+
+func sampleCount(t *testing.T, m *Metrics, method string, outcome string) uint64 {
+	t.Helper()
+
+	families, err := m.registry.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != "storage_operation_duration_seconds" {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+
+			if labels["method"] == method && labels["outcome"] == outcome {
+				return metric.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+
+	return 0
+}
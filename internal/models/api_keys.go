@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// APIKey describes a key for service-to-service access. The key itself is
+// only known to its holder; Prefix is its first characters.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	// TenantID is only read when the key authenticates a request; listings
+	// never cross tenants.
+	TenantID string `json:"-"`
+}
+
+// IssuedAPIKey is the answer to issuing or rotating a key, the only time the
+// key is shown.
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
@@ -0,0 +1,35 @@
+package models
+
+type BatchRequest struct {
+	// Mode is "atomic" (the default), where either every operation is applied
+	// or none is, or "best_effort", where each operation stands on its own.
+	Mode       string                  `json:"mode,omitempty" enums:"atomic,best_effort"`
+	Operations []BatchOperationRequest `json:"operations"`
+}
+
+// BatchOperationRequest is one operation of a batch. Create needs
+// Subscription, update needs ID and Subscription and delete needs ID.
+// A non-zero Version makes an update conditional, like If-Match.
+type BatchOperationRequest struct {
+	Op           string               `json:"op" enums:"create,update,delete"`
+	ID           string               `json:"id,omitempty"`
+	Version      int                  `json:"version,omitempty"`
+	Subscription *SubscriptionRequest `json:"subscription,omitempty"`
+}
+
+type BatchResponse struct {
+	Mode      string        `json:"mode"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// BatchResult is the outcome of the operation at the same index, with the
+// status code the single-item endpoint would have answered.
+type BatchResult struct {
+	Op           string        `json:"op"`
+	ID           string        `json:"id,omitempty"`
+	Status       int           `json:"status"`
+	Subscription *Subscription `json:"subscription,omitempty"`
+	Error        *Problem      `json:"error,omitempty"`
+}
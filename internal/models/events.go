@@ -0,0 +1,19 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SubscriptionEvent is one entry of a subscription's audit log. Before is
+// empty for creations and After is empty for deletions.
+type SubscriptionEvent struct {
+	ID             int64           `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	Action         string          `json:"action"`
+	Actor          string          `json:"actor"`
+	RequestID      string          `json:"request_id,omitempty"`
+	Before         json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After          json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
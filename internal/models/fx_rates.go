@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// FXRate is the price of one unit of BaseCurrency in QuoteCurrency, valid
+// from Month until a newer rate for the same pair is loaded.
+type FXRate struct {
+	Month         time.Time `json:"month"`
+	BaseCurrency  string    `json:"base_currency"`
+	QuoteCurrency string    `json:"quote_currency"`
+	Rate          float64   `json:"rate"`
+}
+
+type FXRateRequest struct {
+	Month         string  `json:"month"`
+	BaseCurrency  string  `json:"base_currency"`
+	QuoteCurrency string  `json:"quote_currency"`
+	Rate          float64 `json:"rate"`
+}
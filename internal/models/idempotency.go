@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// IdempotencyRecord is the stored outcome of a request sent with an
+// Idempotency-Key. StatusCode stays zero while the request is in progress.
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	StatusCode  int
+	Location    string
+	Response    []byte
+	CreatedAt   time.Time
+}
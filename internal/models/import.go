@@ -0,0 +1,17 @@
+package models
+
+// ImportReport is the outcome of a CSV import. A dry run only validates the
+// rows and lists the invalid ones.
+type ImportReport struct {
+	DryRun   bool             `json:"dry_run"`
+	Rows     int              `json:"rows"`
+	Imported int              `json:"imported"`
+	Errors   []ImportRowError `json:"errors,omitempty"`
+}
+
+// ImportRowError lists the problems of one CSV row; Line is the line number
+// in the file, the header being line 1.
+type ImportRowError struct {
+	Line          int            `json:"line"`
+	InvalidParams []InvalidParam `json:"invalid_params"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type MonthlyReport struct {
+	UserID   string        `json:"user_id"`
+	Currency string        `json:"currency,omitempty"`
+	Months   []ReportMonth `json:"months"`
+	Rates    []FXRate      `json:"rates,omitempty"`
+}
+
+type ReportMonth struct {
+	Month    time.Time       `json:"month"`
+	Totals   []CurrencyTotal `json:"totals"`
+	Services []ServiceCost   `json:"services"`
+}
+
+type CurrencyTotal struct {
+	Currency string `json:"currency"`
+	Amount   int    `json:"amount"`
+}
+
+type ServiceCost struct {
+	ServiceName string `json:"service_name"`
+	Currency    string `json:"currency"`
+	Amount      int    `json:"amount"`
+}
+
+// ServiceRevenue is what the active subscriptions of one service bring in per
+// month in one currency, across all tenants. It feeds the business metrics,
+// not the API.
+type ServiceRevenue struct {
+	ServiceName    string  `json:"service_name"`
+	Currency       string  `json:"currency"`
+	Active         int     `json:"active"`
+	MonthlyRevenue float64 `json:"monthly_revenue"`
+}
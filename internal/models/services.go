@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Service is an entry of the service catalog. Subscriptions whose
+// service_name matches its name or one of its aliases, ignoring case and
+// extra spaces, are linked to it and stored under its name.
+type Service struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Aliases      []string  `json:"aliases"`
+	Category     string    `json:"category,omitempty"`
+	DefaultPrice *int      `json:"default_price,omitempty"`
+	Website      string    `json:"website,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type ServiceRequest struct {
+	Name         string   `json:"name"`
+	Aliases      []string `json:"aliases,omitempty"`
+	Category     string   `json:"category,omitempty"`
+	DefaultPrice *int     `json:"default_price,omitempty"`
+	Website      string   `json:"website,omitempty"`
+}
@@ -2,19 +2,75 @@ package models
 
 import "time"
 
+const (
+	BillingWeekly    = "weekly"
+	BillingMonthly   = "monthly"
+	BillingQuarterly = "quarterly"
+	BillingYearly    = "yearly"
+)
+
+const DefaultCurrency = "RUB"
+
 type Subscription struct {
-	ID          string     `json:"id"`
-	ServiceName string     `json:"service_name"`
-	Price       int        `json:"price"`
-	UserID      string     `json:"user_id"`
-	StartDate   time.Time  `json:"start_date"`
-	EndDate     *time.Time `json:"end_date,omitempty"`
+	ID              string     `json:"id"`
+	ServiceName     string     `json:"service_name"`
+	ServiceID       *string    `json:"service_id,omitempty"`
+	Price           int        `json:"price"`
+	UserID          string     `json:"user_id"`
+	StartDate       time.Time  `json:"start_date"`
+	EndDate         *time.Time `json:"end_date,omitempty"`
+	BillingPeriod   string     `json:"billing_period"`
+	BillingInterval int        `json:"billing_interval"`
+	Currency        string     `json:"currency"`
+	Category        string     `json:"category,omitempty"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+	Version         int        `json:"version"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 type SubscriptionRequest struct {
-	ServiceName string `json:"service_name"`
-	Price       int    `json:"price"`
-	UserID      string `json:"user_id"`
-	StartDate   string `json:"start_date"`
-	EndDate     string `json:"end_date,omitempty"`
+	ServiceName     string `json:"service_name"`
+	Price           int    `json:"price"`
+	UserID          string `json:"user_id"`
+	StartDate       string `json:"start_date"`
+	EndDate         string `json:"end_date,omitempty"`
+	BillingPeriod   string `json:"billing_period,omitempty"`
+	BillingInterval int    `json:"billing_interval,omitempty"`
+	Currency        string `json:"currency,omitempty"`
+	Category        string `json:"category,omitempty"`
+}
+
+type SubscriptionPage struct {
+	Items      []*Subscription `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	Total      int             `json:"total"`
+}
+
+// MonthlyCharge is the sum of all charges of one service in one currency
+// during a calendar month.
+type MonthlyCharge struct {
+	Month       time.Time `json:"month"`
+	ServiceName string    `json:"service_name"`
+	Category    string    `json:"category,omitempty"`
+	Currency    string    `json:"currency"`
+	Amount      int       `json:"amount"`
+}
+
+type TotalCost struct {
+	TotalCost int         `json:"total_cost"`
+	Currency  string      `json:"currency,omitempty"`
+	Groups    []CostGroup `json:"groups,omitempty"`
+	Rates     []FXRate    `json:"rates,omitempty"`
+}
+
+// CostGroup is the total of one combination of group_by dimensions; the
+// dimensions that were not requested stay empty.
+type CostGroup struct {
+	ServiceName string `json:"service_name,omitempty"`
+	Category    string `json:"category,omitempty"`
+	Month       string `json:"month,omitempty"`
+	Year        int    `json:"year,omitempty"`
+	Currency    string `json:"currency,omitempty"`
+	TotalCost   int    `json:"total_cost"`
 }
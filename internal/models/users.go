@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// User owns subscriptions. DefaultCurrency and Timezone are the user's
+// preferences for reports and calendars.
+type User struct {
+	ID              string    `json:"id"`
+	DisplayName     string    `json:"display_name"`
+	Email           string    `json:"email,omitempty"`
+	DefaultCurrency string    `json:"default_currency"`
+	Timezone        string    `json:"timezone"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type UserRequest struct {
+	// ID is optional on create, for users whose ID comes from another system.
+	ID              string `json:"id,omitempty"`
+	DisplayName     string `json:"display_name"`
+	Email           string `json:"email,omitempty"`
+	DefaultCurrency string `json:"default_currency,omitempty"`
+	Timezone        string `json:"timezone,omitempty"`
+}
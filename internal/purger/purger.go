@@ -0,0 +1,92 @@
+// Package purger permanently removes soft-deleted subscriptions once their
+// retention window has passed, along with expired idempotency keys.
+package purger
+
+import (
+	"context"
+	"log/slog"
+	"subscription-aggregator/internal/db"
+	"time"
+)
+
+type Purger struct {
+	storage        db.Storage
+	retention      time.Duration
+	idempotencyTTL time.Duration
+	interval       time.Duration
+	log            *slog.Logger
+}
+
+// New creates a purger; a zero retention or idempotencyTTL turns off that
+// part of the purge.
+func New(storage db.Storage, retention time.Duration, idempotencyTTL time.Duration, interval time.Duration, log *slog.Logger) *Purger {
+	return &Purger{
+		storage:        storage,
+		retention:      retention,
+		idempotencyTTL: idempotencyTTL,
+		interval:       interval,
+		log:            log,
+	}
+}
+
+// Run purges once immediately and then every interval until ctx is done.
+func (p *Purger) Run(ctx context.Context) {
+	p.log.Info(
+		"Purger started",
+		"retention", p.retention.String(),
+		"idempotency_ttl", p.idempotencyTTL.String(),
+		"interval", p.interval.String(),
+	)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			p.log.Info("Purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	if p.retention > 0 {
+		p.purgeSubscriptions(ctx)
+	}
+
+	if p.idempotencyTTL > 0 {
+		p.purgeIdempotencyKeys(ctx)
+	}
+}
+
+func (p *Purger) purgeSubscriptions(ctx context.Context) {
+	deletedBefore := time.Now().Add(-p.retention)
+
+	purged, err := p.storage.Purge(ctx, deletedBefore)
+	if err != nil {
+		p.log.Error("Failed to purge deleted subscriptions", "error", err)
+		return
+	}
+
+	if purged > 0 {
+		p.log.Info("Purged deleted subscriptions", "count", purged, "deleted_before", deletedBefore)
+	}
+}
+
+func (p *Purger) purgeIdempotencyKeys(ctx context.Context) {
+	createdBefore := time.Now().Add(-p.idempotencyTTL)
+
+	purged, err := p.storage.PurgeIdempotencyKeys(ctx, createdBefore)
+	if err != nil {
+		p.log.Error("Failed to purge idempotency keys", "error", err)
+		return
+	}
+
+	if purged > 0 {
+		p.log.Info("Purged expired idempotency keys", "count", purged, "created_before", createdBefore)
+	}
+}
@@ -0,0 +1,82 @@
+package purger
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"subscription-aggregator/internal/db/memory"
+	"subscription-aggregator/internal/models"
+	"subscription-aggregator/internal/tenant"
+	"testing"
+	"time"
+)
+
+func TestPurge(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	storage := memory.New(log)
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	user := &models.User{ID: uuid.NewString(), DisplayName: "Test", DefaultCurrency: models.DefaultCurrency, Timezone: "UTC"}
+	if err := storage.CreateUser(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	sub := &models.Subscription{
+		ID:          uuid.NewString(),
+		ServiceName: "Netflix",
+		Price:       400,
+		UserID:      user.ID,
+		StartDate:   time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+		Currency:    models.DefaultCurrency,
+	}
+	if err := storage.Save(ctx, sub); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if err := storage.Delete(ctx, sub.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	// Within the retention window the row stays restorable.
+	New(storage, time.Hour, 0, time.Hour, log).purge(ctx)
+
+	if _, err := storage.GetByID(ctx, sub.ID, true); err != nil {
+		t.Fatalf("within retention: %v", err)
+	}
+
+	// A zero retention turns the subscription purge off.
+	New(storage, 0, 0, time.Hour, log).purge(ctx)
+
+	if _, err := storage.GetByID(ctx, sub.ID, true); err != nil {
+		t.Fatalf("with purging off: %v", err)
+	}
+
+	time.Sleep(time.Millisecond)
+	New(storage, time.Nanosecond, 0, time.Hour, log).purge(ctx)
+
+	if _, err := storage.GetByID(ctx, sub.ID, true); err == nil {
+		t.Errorf("after retention: subscription %s is still stored", sub.ID)
+	}
+}
+
+func TestRunStopsWithContext(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		New(memory.New(log), time.Hour, time.Hour, time.Millisecond, log).Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the context was canceled")
+	}
+}
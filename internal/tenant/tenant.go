@@ -0,0 +1,43 @@
+// Package tenant carries the organization a request acts for down to the
+// storage layer, which keeps the data of every tenant apart.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// Default is the tenant of requests that name none. Data stored before
+// tenants existed belongs to it.
+const Default = "default"
+
+// Header names the tenant of a request when authentication is off.
+const Header = "X-Tenant-ID"
+
+var idPattern = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?$`)
+
+type contextKey struct{}
+
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// ID returns the tenant of ctx, or Default when none is set.
+func ID(ctx context.Context) string {
+	if id, ok := ctx.Value(contextKey{}).(string); ok && id != "" {
+		return id
+	}
+
+	return Default
+}
+
+// Validate accepts lowercase letters, digits and inner hyphens, at most 63
+// characters, so an ID fits a DNS label.
+func Validate(id string) error {
+	if !idPattern.MatchString(id) {
+		return errors.New("expected at most 63 lowercase letters, digits and inner hyphens")
+	}
+
+	return nil
+}
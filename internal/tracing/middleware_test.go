@@ -0,0 +1,128 @@
+package tracing
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newRecorder installs a tracer provider that keeps every span in memory
+// for the duration of the test.
+func newRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return recorder
+}
+
+func attributeValue(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := newRecorder(t)
+
+	var spanInHandler trace.SpanContext
+
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID, Middleware)
+	router.Get("/subscriptions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		spanInHandler = trace.SpanContextFromContext(r.Context())
+	})
+	router.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	tests := []struct {
+		path        string
+		traceparent string
+		wantName    string
+		wantStatus  int64
+		wantError   bool
+	}{
+		{path: "/subscriptions/42", wantName: "GET /subscriptions/{id}", wantStatus: http.StatusOK},
+		{path: "/subscriptions/42", traceparent: "00-" + traceID + "-00f067aa0ba902b7-01", wantName: "GET /subscriptions/{id}", wantStatus: http.StatusOK},
+		{path: "/fail", wantName: "GET /fail", wantStatus: http.StatusInternalServerError, wantError: true},
+		{path: "/missing", wantName: "GET", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set(middleware.RequestIDHeader, "req-1")
+		if tt.traceparent != "" {
+			req.Header.Set("traceparent", tt.traceparent)
+		}
+
+		spanInHandler = trace.SpanContext{}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		if len(spans) == 0 {
+			t.Fatalf("%s: no span recorded", tt.path)
+		}
+
+		span := spans[len(spans)-1]
+
+		if span.Name() != tt.wantName {
+			t.Errorf("%s: got span %q, want %q", tt.path, span.Name(), tt.wantName)
+		}
+
+		if span.SpanKind() != trace.SpanKindServer {
+			t.Errorf("%s: got kind %v, want server", tt.path, span.SpanKind())
+		}
+
+		if got := attributeValue(span, "http.response.status_code").AsInt64(); got != tt.wantStatus {
+			t.Errorf("%s: got status %d, want %d", tt.path, got, tt.wantStatus)
+		}
+
+		if got := attributeValue(span, "request_id").AsString(); got != "req-1" {
+			t.Errorf("%s: got request ID %q, want req-1", tt.path, got)
+		}
+
+		if got := span.Status().Code == codes.Error; got != tt.wantError {
+			t.Errorf("%s: got error status %v, want %v", tt.path, got, tt.wantError)
+		}
+
+		if spanInHandler.IsValid() && !spanInHandler.Equal(span.SpanContext()) {
+			t.Errorf("%s: the handler does not run in the request span", tt.path)
+		}
+
+		if tt.traceparent != "" {
+			if got := span.SpanContext().TraceID().String(); got != traceID {
+				t.Errorf("%s: got trace %s, want the incoming %s", tt.path, got, traceID)
+			}
+
+			if !span.Parent().IsRemote() {
+				t.Errorf("%s: span does not continue the incoming trace", tt.path)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+	"subscription-aggregator/internal/config"
+	"testing"
+)
+
+func TestNewExporter(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantNone bool
+		wantErr  bool
+	}{
+		{name: config.TracingNone, wantNone: true},
+		{name: config.TracingStdout},
+		{name: config.TracingOTLP},
+		{name: "jaeger", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		exporter, err := newExporter(context.Background(), tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: got no error", tt.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+
+		if got := exporter == nil; got != tt.wantNone {
+			t.Errorf("%s: got no exporter %v, want %v", tt.name, got, tt.wantNone)
+		}
+
+		if exporter != nil {
+			_ = exporter.Shutdown(context.Background())
+		}
+	}
+}
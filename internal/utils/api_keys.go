@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"slices"
+	"strings"
+	"subscription-aggregator/internal/auth"
+	"subscription-aggregator/internal/models"
+)
+
+// MapAPIKeyRequest validates a request to issue a key and builds the key
+// with a new ID. The secret and its prefix are set when the key is issued.
+func MapAPIKeyRequest(req models.APIKeyRequest) (*models.APIKey, error) {
+	validation := &ValidationError{}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		validation.Add("name", "name is required")
+	} else if len(name) > 255 {
+		validation.Add("name", "name must be at most 255 characters")
+	}
+
+	scopes := []string{}
+	if len(req.Scopes) == 0 {
+		validation.Add("scopes", "at least one scope is required")
+	}
+
+	for i, scope := range req.Scopes {
+		if !auth.IsScope(scope) {
+			validation.Add(fmt.Sprintf("scopes[%d]", i), "expected one of subscriptions:read, subscriptions:write, reports:read, admin")
+			continue
+		}
+
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if validation.HasErrors() {
+		return nil, validation
+	}
+
+	return &models.APIKey{
+		ID:     uuid.New().String(),
+		Name:   name,
+		Scopes: scopes,
+	}, nil
+}
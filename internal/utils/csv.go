@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"subscription-aggregator/internal/models"
+)
+
+// SubscriptionCSVHeader is the header of an export. An import needs at least
+// service_name, price, user_id and start_date and ignores unknown columns,
+// so an export can be imported again.
+var SubscriptionCSVHeader = []string{
+	"id",
+	"service_name",
+	"price",
+	"user_id",
+	"start_date",
+	"end_date",
+	"billing_period",
+	"billing_interval",
+	"currency",
+	"category",
+}
+
+var requiredCSVColumns = []string{"service_name", "price", "user_id", "start_date"}
+
+// CSVRow is one data row of an import: either the subscription it describes
+// or the validation error of the row.
+type CSVRow struct {
+	Line         int
+	Subscription *models.Subscription
+	Err          error
+}
+
+// ReadSubscriptionsCSV validates every row of a CSV import through
+// MapRequest. The error is only set when the file itself is unreadable or
+// has more than maxRows data rows.
+func ReadSubscriptionsCSV(r io.Reader, maxRows int, log *slog.Logger) ([]CSVRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range requiredCSVColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("csv header has no %s column", name)
+		}
+	}
+
+	var rows []CSVRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+
+		if len(rows) == maxRows {
+			return nil, fmt.Errorf("csv has more than %d rows", maxRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, readCSVRow(line, record, columns, log))
+	}
+
+	return rows, nil
+}
+
+func readCSVRow(line int, record []string, columns map[string]int, log *slog.Logger) CSVRow {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	validation := &ValidationError{}
+
+	req := models.SubscriptionRequest{
+		ServiceName:   unescapeCSVText(field("service_name")),
+		UserID:        field("user_id"),
+		StartDate:     field("start_date"),
+		EndDate:       field("end_date"),
+		BillingPeriod: field("billing_period"),
+		Currency:      field("currency"),
+		Category:      unescapeCSVText(field("category")),
+	}
+
+	if price, err := strconv.Atoi(field("price")); err != nil {
+		validation.Add("price", "expected an integer")
+	} else {
+		req.Price = price
+	}
+
+	if value := field("billing_interval"); value != "" {
+		if interval, err := strconv.Atoi(value); err != nil {
+			validation.Add("billing_interval", "expected an integer")
+		} else {
+			req.BillingInterval = interval
+		}
+	}
+
+	sub, err := MapRequest(req, log)
+	if err != nil {
+		var requestValidation *ValidationError
+		if !errors.As(err, &requestValidation) {
+			return CSVRow{Line: line, Err: err}
+		}
+
+		validation.Fields = append(validation.Fields, requestValidation.Fields...)
+	}
+
+	if validation.HasErrors() {
+		return CSVRow{Line: line, Err: validation}
+	}
+
+	return CSVRow{Line: line, Subscription: sub}
+}
+
+// SubscriptionCSVRecord renders a subscription in the columns of
+// SubscriptionCSVHeader.
+func SubscriptionCSVRecord(sub *models.Subscription) []string {
+	req := ToRequest(sub)
+
+	return []string{
+		sub.ID,
+		escapeCSVText(req.ServiceName),
+		strconv.Itoa(req.Price),
+		req.UserID,
+		req.StartDate,
+		req.EndDate,
+		req.BillingPeriod,
+		strconv.Itoa(req.BillingInterval),
+		req.Currency,
+		escapeCSVText(req.Category),
+	}
+}
+
+// formulaPrefixes are the first characters that make a spreadsheet read a
+// cell as a formula.
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeCSVText prefixes free text that a spreadsheet would run as a formula
+// with an apostrophe, which makes it plain text again.
+func escapeCSVText(value string) string {
+	if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+// unescapeCSVText removes the apostrophe added by escapeCSVText, so an export
+// imports unchanged.
+func unescapeCSVText(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+
+	return value
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"log/slog"
+	"subscription-aggregator/internal/models"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionCSVFormulaRoundTrip(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		value    string
+		exported string
+	}{
+		{value: "Netflix", exported: "Netflix"},
+		{value: "=HYPERLINK(\"http://example.com\")", exported: "'=HYPERLINK(\"http://example.com\")"},
+		{value: "+1 Music", exported: "'+1 Music"},
+		{value: "-Minus", exported: "'-Minus"},
+		{value: "@SUM(A1)", exported: "'@SUM(A1)"},
+		{value: "Don't", exported: "Don't"},
+	}
+
+	for _, tt := range tests {
+		sub := &models.Subscription{
+			ID:              "2b1c4f57-6a0f-4d5e-9d64-0f0a6b3a9c11",
+			ServiceName:     tt.value,
+			Price:           400,
+			UserID:          "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+			StartDate:       time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+			BillingPeriod:   "monthly",
+			BillingInterval: 1,
+			Currency:        "RUB",
+			Category:        tt.value,
+		}
+
+		record := SubscriptionCSVRecord(sub)
+		if record[1] != tt.exported || record[9] != tt.exported {
+			t.Errorf("%q: exported service_name %q and category %q, want %q", tt.value, record[1], record[9], tt.exported)
+		}
+
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		_ = writer.Write(SubscriptionCSVHeader)
+		_ = writer.Write(record)
+		writer.Flush()
+
+		rows, err := ReadSubscriptionsCSV(&buf, 1, log)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.value, err)
+		}
+
+		if rows[0].Err != nil {
+			t.Errorf("%q: import failed: %v", tt.value, rows[0].Err)
+			continue
+		}
+
+		if got := rows[0].Subscription; got.ServiceName != tt.value || got.Category != tt.value {
+			t.Errorf("%q: imported service_name %q and category %q", tt.value, got.ServiceName, got.Category)
+		}
+	}
+}
@@ -4,15 +4,79 @@ import (
 	"github.com/google/uuid"
 	"github.com/jinzhu/copier"
 	"log/slog"
+	"strconv"
+	"strings"
+	"subscription-aggregator/internal/billing"
 	"subscription-aggregator/internal/models"
 	"time"
 )
 
+// MapRequest validates a create request and builds a subscription with a
+// new ID.
 func MapRequest(req models.SubscriptionRequest, log *slog.Logger) (*models.Subscription, error) {
+	sub, err := mapRequest(req, log)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.ID = uuid.New().String()
+
+	return sub, nil
+}
+
+// MapUpdateRequest validates the new state of the subscription id.
+func MapUpdateRequest(id string, req models.SubscriptionRequest, log *slog.Logger) (*models.Subscription, error) {
+	sub, err := mapRequest(req, log)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.ID = id
+
+	return sub, nil
+}
+
+// ToRequest renders a subscription in the request format, the document a
+// merge patch is applied to.
+func ToRequest(sub *models.Subscription) models.SubscriptionRequest {
+	req := models.SubscriptionRequest{
+		ServiceName:     sub.ServiceName,
+		Price:           sub.Price,
+		UserID:          sub.UserID,
+		StartDate:       sub.StartDate.Format(dateLayout),
+		BillingPeriod:   sub.BillingPeriod,
+		BillingInterval: sub.BillingInterval,
+		Currency:        sub.Currency,
+		Category:        sub.Category,
+	}
+
+	if sub.EndDate != nil {
+		req.EndDate = sub.EndDate.Format(dateLayout)
+	}
+
+	return req
+}
+
+func mapRequest(req models.SubscriptionRequest, log *slog.Logger) (*models.Subscription, error) {
+	validation := &ValidationError{}
+
+	req.ServiceName = strings.TrimSpace(req.ServiceName)
+	if req.ServiceName == "" {
+		validation.Add("service_name", "service name is required")
+	}
+
+	if req.Price < 0 {
+		validation.Add("price", "price must not be negative")
+	}
+
+	if err := ValidateUUID(req.UserID); err != nil {
+		validation.Add("user_id", err.Error())
+	}
+
 	startDate, err := ParseDate(req.StartDate)
 	if err != nil {
 		log.Warn("failed to parse start date", "error", err)
-		return nil, err
+		validation.Add("start_date", "expected date in MM-YYYY format")
 	}
 
 	var endDate *time.Time
@@ -21,10 +85,42 @@ func MapRequest(req models.SubscriptionRequest, log *slog.Logger) (*models.Subsc
 		parseEndDate, err := ParseDate(req.EndDate)
 		if err != nil {
 			log.Warn("failed to parse end date", "error", err)
-			return nil, err
+			validation.Add("end_date", "expected date in MM-YYYY format")
+		} else {
+			endDate = &parseEndDate
 		}
+	}
 
-		endDate = &parseEndDate
+	if endDate != nil && !startDate.IsZero() && endDate.Before(startDate) {
+		validation.Add("end_date", "end date must not be before start date")
+	}
+
+	if req.BillingPeriod == "" {
+		req.BillingPeriod = models.BillingMonthly
+	} else if !billing.IsValidPeriod(req.BillingPeriod) {
+		validation.Add("billing_period", "expected one of weekly, monthly, quarterly, yearly")
+	}
+
+	if req.BillingInterval == 0 {
+		req.BillingInterval = 1
+	} else if req.BillingInterval < 0 || req.BillingInterval > billing.MaxInterval {
+		validation.Add("billing_interval", "billing interval must be between 1 and "+strconv.Itoa(billing.MaxInterval))
+	}
+
+	if req.Currency == "" {
+		req.Currency = models.DefaultCurrency
+	} else if code, err := ValidateCurrency(req.Currency); err != nil {
+		validation.Add("currency", err.Error())
+	} else {
+		req.Currency = code
+	}
+
+	if len(req.Category) > 64 {
+		validation.Add("category", "category must be at most 64 characters")
+	}
+
+	if validation.HasErrors() {
+		return nil, validation
 	}
 
 	sub := &models.Subscription{}
@@ -33,7 +129,6 @@ func MapRequest(req models.SubscriptionRequest, log *slog.Logger) (*models.Subsc
 		return nil, err
 	}
 
-	sub.ID = uuid.New().String()
 	sub.StartDate = startDate
 	sub.EndDate = endDate
 
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"subscription-aggregator/internal/models"
+	"testing"
+)
+
+func TestMapRequestBillingInterval(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		interval int
+		want     int
+		valid    bool
+	}{
+		{interval: 0, want: 1, valid: true},
+		{interval: 1, want: 1, valid: true},
+		{interval: 120, want: 120, valid: true},
+		{interval: 121, valid: false},
+		{interval: -1, valid: false},
+		{interval: 1 << 30, valid: false},
+	}
+
+	for _, tt := range tests {
+		req := models.SubscriptionRequest{
+			ServiceName:     "Netflix",
+			Price:           400,
+			UserID:          "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+			StartDate:       "07-2025",
+			BillingInterval: tt.interval,
+		}
+
+		sub, err := MapRequest(req, log)
+		if !tt.valid {
+			var validation *ValidationError
+			if !errors.As(err, &validation) || validation.Fields[0].Name != "billing_interval" {
+				t.Errorf("interval %d: got error %v, want a billing_interval validation error", tt.interval, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("interval %d: unexpected error %v", tt.interval, err)
+		} else if sub.BillingInterval != tt.want {
+			t.Errorf("interval %d: got %d, want %d", tt.interval, sub.BillingInterval, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MergePatch applies an RFC 7396 JSON merge patch to the target document.
+// Members of the patch replace those of the target, objects are merged
+// recursively and a null member removes the key.
+func MergePatch(target []byte, patch []byte) ([]byte, error) {
+	var targetValue any
+	if err := json.Unmarshal(target, &targetValue); err != nil {
+		return nil, fmt.Errorf("invalid merge patch target: %w", err)
+	}
+
+	var patchValue any
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+
+	return json.Marshal(mergeValue(targetValue, patchValue))
+}
+
+func mergeValue(target any, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+
+		targetObject[key] = mergeValue(targetObject[key], value)
+	}
+
+	return targetObject
+}
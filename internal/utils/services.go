@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"net/url"
+	"strings"
+	"subscription-aggregator/internal/db"
+	"subscription-aggregator/internal/models"
+)
+
+// MapServiceRequest validates a create request and builds a service with a
+// new ID.
+func MapServiceRequest(req models.ServiceRequest) (*models.Service, error) {
+	svc, err := mapServiceRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.ID = uuid.New().String()
+
+	return svc, nil
+}
+
+// MapServiceUpdateRequest validates the new state of the service id.
+func MapServiceUpdateRequest(id string, req models.ServiceRequest) (*models.Service, error) {
+	svc, err := mapServiceRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.ID = id
+
+	return svc, nil
+}
+
+func mapServiceRequest(req models.ServiceRequest) (*models.Service, error) {
+	validation := &ValidationError{}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		validation.Add("name", "name is required")
+	} else if len(name) > 255 {
+		validation.Add("name", "name must be at most 255 characters")
+	}
+
+	// Aliases that normalize to the name or to an earlier alias add nothing
+	// and are dropped.
+	seen := map[string]bool{db.ServiceKey(name): true}
+	aliases := []string{}
+	for i, alias := range req.Aliases {
+		alias = strings.TrimSpace(alias)
+		if alias == "" {
+			validation.Add(fmt.Sprintf("aliases[%d]", i), "alias must not be empty")
+			continue
+		}
+
+		if len(alias) > 255 {
+			validation.Add(fmt.Sprintf("aliases[%d]", i), "alias must be at most 255 characters")
+			continue
+		}
+
+		if key := db.ServiceKey(alias); !seen[key] {
+			seen[key] = true
+			aliases = append(aliases, alias)
+		}
+	}
+
+	if len(req.Category) > 64 {
+		validation.Add("category", "category must be at most 64 characters")
+	}
+
+	if req.DefaultPrice != nil && *req.DefaultPrice < 0 {
+		validation.Add("default_price", "default price must not be negative")
+	}
+
+	if req.Website != "" {
+		if err := validateWebsite(req.Website); err != nil {
+			validation.Add("website", err.Error())
+		}
+	}
+
+	if validation.HasErrors() {
+		return nil, validation
+	}
+
+	return &models.Service{
+		Name:         name,
+		Aliases:      aliases,
+		Category:     req.Category,
+		DefaultPrice: req.DefaultPrice,
+		Website:      req.Website,
+	}, nil
+}
+
+func validateWebsite(value string) error {
+	if len(value) > 2048 {
+		return fmt.Errorf("website must be at most 2048 characters")
+	}
+
+	website, err := url.Parse(value)
+	if err != nil || (website.Scheme != "http" && website.Scheme != "https") || website.Host == "" {
+		return fmt.Errorf("expected an absolute http or https URL")
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"net/mail"
+	"strings"
+	"subscription-aggregator/internal/models"
+	"time"
+	// The runtime image has no zoneinfo, so time zones are embedded.
+	_ "time/tzdata"
+)
+
+// MapUserRequest validates a create request and builds a user with the
+// requested ID, or a new one when none is given.
+func MapUserRequest(req models.UserRequest) (*models.User, error) {
+	validation := &ValidationError{}
+
+	id := req.ID
+	if id == "" {
+		id = uuid.New().String()
+	} else if err := ValidateUUID(id); err != nil {
+		validation.Add("id", err.Error())
+	}
+
+	user := mapUserRequest(req, validation)
+	if validation.HasErrors() {
+		return nil, validation
+	}
+
+	user.ID = id
+
+	return user, nil
+}
+
+// MapUserUpdateRequest validates the new state of the user id. The ID in
+// the body, if any, is ignored.
+func MapUserUpdateRequest(id string, req models.UserRequest) (*models.User, error) {
+	validation := &ValidationError{}
+
+	user := mapUserRequest(req, validation)
+	if validation.HasErrors() {
+		return nil, validation
+	}
+
+	user.ID = id
+
+	return user, nil
+}
+
+func mapUserRequest(req models.UserRequest, validation *ValidationError) *models.User {
+	user := &models.User{
+		DisplayName:     strings.TrimSpace(req.DisplayName),
+		Email:           strings.TrimSpace(req.Email),
+		DefaultCurrency: models.DefaultCurrency,
+		Timezone:        "UTC",
+	}
+
+	if user.DisplayName == "" {
+		validation.Add("display_name", "display name is required")
+	} else if len(user.DisplayName) > 255 {
+		validation.Add("display_name", "display name must be at most 255 characters")
+	}
+
+	if user.Email != "" {
+		if err := validateEmail(user.Email); err != nil {
+			validation.Add("email", err.Error())
+		}
+	}
+
+	if req.DefaultCurrency != "" {
+		code, err := ValidateCurrency(req.DefaultCurrency)
+		if err != nil {
+			validation.Add("default_currency", err.Error())
+		}
+		user.DefaultCurrency = code
+	}
+
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil || req.Timezone == "Local" {
+			validation.Add("timezone", "expected an IANA time zone such as Europe/Moscow")
+		}
+		user.Timezone = req.Timezone
+	}
+
+	return user
+}
+
+func validateEmail(value string) error {
+	if len(value) > 320 {
+		return fmt.Errorf("email must be at most 320 characters")
+	}
+
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Name != "" || address.Address != value {
+		return fmt.Errorf("expected an email address")
+	}
+
+	return nil
+}
@@ -2,18 +2,75 @@ package utils
 
 import (
 	"fmt"
+	"github.com/google/uuid"
+	"golang.org/x/text/currency"
+	"strings"
+	"subscription-aggregator/internal/models"
 	"time"
 )
 
+// ValidationError collects every field-level problem found in a request so
+// the client can show them all at once.
+type ValidationError struct {
+	Fields []models.InvalidParam
+}
+
+func NewValidationError(field string, message string) *ValidationError {
+	return &ValidationError{Fields: []models.InvalidParam{{Name: field, Reason: message}}}
+}
+
+func (e *ValidationError) Add(field string, message string) {
+	e.Fields = append(e.Fields, models.InvalidParam{Name: field, Reason: message})
+}
+
+func (e *ValidationError) HasErrors() bool {
+	return len(e.Fields) > 0
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		parts = append(parts, field.Name+": "+field.Reason)
+	}
+
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// dateLayout is the MM-YYYY format of every date in the API.
+const dateLayout = "01-2006"
+
 func ParseDate(date string) (time.Time, error) {
 	if date == "" {
 		return time.Time{}, fmt.Errorf("date is empty")
 	}
 
-	resultTime, err := time.Parse("01-2006", date)
+	resultTime, err := time.Parse(dateLayout, date)
 	if err != nil {
 		return time.Time{}, fmt.Errorf("date is invalid: %w", err)
 	}
 
 	return resultTime, nil
 }
+
+func ValidateUUID(value string) error {
+	if value == "" {
+		return fmt.Errorf("value is empty")
+	}
+
+	if _, err := uuid.Parse(value); err != nil {
+		return fmt.Errorf("value is not a valid UUID")
+	}
+
+	return nil
+}
+
+// ValidateCurrency checks an ISO 4217 currency code and returns it in its
+// canonical upper-case form.
+func ValidateCurrency(code string) (string, error) {
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return "", fmt.Errorf("expected an ISO 4217 currency code")
+	}
+
+	return unit.String(), nil
+}